@@ -0,0 +1,34 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// The hot paths below must not allocate. If one of these starts failing,
+// something in the attempt-key construction has begun escaping to the heap.
+func TestPermittedDoesNotAllocate(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "finished"},
+	)
+	compiled := rules.Compile()
+
+	pending := &Thing{State: "pending"}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing))
+
+	examples := []func(){
+		func() { rules.Permitted(pending, "finished") },
+		func() { rules.Permitted(pending, "started") },
+		func() { compiled.Permitted(pending, "finished") },
+		func() { compiled.Permitted(pending, "started") },
+		func() { the_machine.Transition("finished") },
+	}
+
+	for i, ex := range examples {
+		st.Expect(t, testing.AllocsPerRun(100, ex), float64(0), i)
+	}
+}
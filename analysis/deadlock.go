@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"slices"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// Deadlocks returns the states from which none of finals can be reached,
+// whatever the guards decide. When no finals are given, the states without
+// exits are taken to be final.
+func Deadlocks(r fsm.Ruleset, finals ...fsm.State) []fsm.State {
+	g := newGraph(r)
+	reaching := g.reaching(g.finals(finals))
+
+	var stuck []int
+	for i, ok := range reaching {
+		if !ok {
+			stuck = append(stuck, i)
+		}
+	}
+	return g.names(stuck)
+}
+
+// Livelock is a cycle of states a machine can enter but never leave: no
+// exit from it can ever be taken, so it keeps going round.
+type Livelock struct {
+	States []fsm.State // the strongly connected states, sorted
+	Exits  []fsm.T     // transitions out of them, all of which are blocked
+}
+
+// Livelocks returns the cycles, outside finals, whose every exit is
+// blocked. Guards are opaque, so blocked declares which transitions have
+// guards known never to pass; it may be nil. When no finals are given, the
+// states without exits are taken to be final.
+func Livelocks(r fsm.Ruleset, blocked func(fsm.T) bool, finals ...fsm.State) []Livelock {
+	g := newGraph(r)
+	final := g.finals(finals)
+
+	var locks []Livelock
+	for _, c := range g.sccs() {
+		if !g.cyclic(c) || slices.ContainsFunc(c, func(s int) bool { return final[s] }) {
+			continue
+		}
+
+		lock := Livelock{States: g.names(c)}
+		escapes := false
+		for _, o := range c {
+			for _, e := range g.out[o] {
+				if slices.Contains(c, e) {
+					continue
+				}
+				t := fsm.T{O: g.states[o], E: g.states[e]}
+				if blocked == nil || !blocked(t) {
+					escapes = true
+				}
+				lock.Exits = append(lock.Exits, t)
+			}
+		}
+		if !escapes {
+			locks = append(locks, lock)
+		}
+	}
+
+	slices.SortFunc(locks, func(a, b Livelock) int { return slices.Compare(a.States, b.States) })
+	return locks
+}
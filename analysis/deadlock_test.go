@@ -0,0 +1,57 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/analysis"
+)
+
+var rules = fsm.CreateRuleset(
+	fsm.T{O: "new", E: "review"},
+	fsm.T{O: "review", E: "rework"},
+	fsm.T{O: "rework", E: "review"},
+	fsm.T{O: "review", E: "approved"},
+	fsm.T{O: "new", E: "limbo"},
+	fsm.T{O: "limbo", E: "purgatory"},
+	fsm.T{O: "purgatory", E: "limbo"},
+	fsm.T{O: "approved", E: "done"},
+)
+
+func TestDeadlocks(t *testing.T) {
+	st.Expect(t, analysis.Deadlocks(rules), []fsm.State{"limbo", "purgatory"})
+	st.Expect(t, analysis.Deadlocks(rules, "approved"), []fsm.State{"done", "limbo", "purgatory"})
+}
+
+func TestLivelocks(t *testing.T) {
+	st.Expect(t, analysis.Livelocks(rules, nil), []analysis.Livelock{
+		{States: []fsm.State{"limbo", "purgatory"}},
+	})
+
+	blocked := func(t fsm.T) bool { return t == fsm.T{O: "review", E: "approved"} }
+	st.Expect(t, analysis.Livelocks(rules, blocked), []analysis.Livelock{
+		{States: []fsm.State{"limbo", "purgatory"}},
+		{States: []fsm.State{"review", "rework"}, Exits: []fsm.T{{O: "review", E: "approved"}}},
+	})
+}
+
+func TestReachableProduct(t *testing.T) {
+	payment := fsm.CreateRuleset(
+		fsm.T{O: "unpaid", E: "paid"},
+		fsm.T{O: "unpaid", E: "unpaid"},
+		fsm.T{O: "paid", E: "paid"},
+	)
+	shipping := fsm.CreateRuleset(
+		fsm.T{O: "packing", E: "shipped"},
+		fsm.T{O: "packing", E: "packing"},
+	)
+
+	reached := analysis.Reachable(fsm.Product(payment, shipping), fsm.Pair("unpaid", "packing"))
+	st.Expect(t, reached, []fsm.State{
+		fsm.Pair("paid", "packing"),
+		fsm.Pair("paid", "shipped"),
+		fsm.Pair("unpaid", "packing"),
+		fsm.Pair("unpaid", "shipped"),
+	})
+}
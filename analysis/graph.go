@@ -0,0 +1,167 @@
+// Package analysis examines the structure of rulesets: which states can
+// reach which, and where a machine can get stuck.
+package analysis
+
+import (
+	"slices"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// graph is the transitions of a ruleset as adjacency lists over state
+// indices, ignoring guards.
+type graph struct {
+	states []fsm.State
+	ids    map[fsm.State]int
+	out    [][]int
+}
+
+func newGraph(r fsm.Ruleset) *graph {
+	g := &graph{ids: map[fsm.State]int{}}
+	for t := range r {
+		g.add(t.Origin())
+		g.add(t.Exit())
+	}
+	slices.Sort(g.states)
+	for i, s := range g.states {
+		g.ids[s] = i
+	}
+
+	g.out = make([][]int, len(g.states))
+	for t := range r {
+		o := g.ids[t.Origin()]
+		g.out[o] = append(g.out[o], g.ids[t.Exit()])
+	}
+	for _, out := range g.out {
+		slices.Sort(out)
+	}
+
+	return g
+}
+
+func (g *graph) add(s fsm.State) {
+	if _, ok := g.ids[s]; !ok {
+		g.ids[s] = -1
+		g.states = append(g.states, s)
+	}
+}
+
+// terminal reports the states without exits.
+func (g *graph) terminal() []bool {
+	t := make([]bool, len(g.states))
+	for i, out := range g.out {
+		t[i] = len(out) == 0
+	}
+	return t
+}
+
+// reaching returns the states from which any of targets can be reached.
+func (g *graph) reaching(targets []bool) []bool {
+	in := make([][]int, len(g.states))
+	for o, out := range g.out {
+		for _, e := range out {
+			in[e] = append(in[e], o)
+		}
+	}
+
+	seen := slices.Clone(targets)
+	var queue []int
+	for i, t := range targets {
+		if t {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, o := range in[s] {
+			if !seen[o] {
+				seen[o] = true
+				queue = append(queue, o)
+			}
+		}
+	}
+	return seen
+}
+
+// sccs returns the strongly connected components of g, using Tarjan's
+// algorithm, each sorted and in reverse topological order.
+func (g *graph) sccs() [][]int {
+	n := len(g.states)
+	index, low := make([]int, n), make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+
+	var (
+		stack      []int
+		components [][]int
+		next       int
+		visit      func(v int)
+	)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.out[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+
+		if low[v] == index[v] {
+			var c []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				c = append(c, w)
+				if w == v {
+					break
+				}
+			}
+			slices.Sort(c)
+			components = append(components, c)
+		}
+	}
+
+	for v := range n {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return components
+}
+
+// cyclic reports whether the component c contains a cycle.
+func (g *graph) cyclic(c []int) bool {
+	return len(c) > 1 || slices.Contains(g.out[c[0]], c[0])
+}
+
+func (g *graph) names(ids []int) []fsm.State {
+	names := make([]fsm.State, len(ids))
+	for i, id := range ids {
+		names[i] = g.states[id]
+	}
+	return names
+}
+
+// finals marks states, or the terminal states when none are given.
+func (g *graph) finals(states []fsm.State) []bool {
+	if len(states) == 0 {
+		return g.terminal()
+	}
+	f := make([]bool, len(g.states))
+	for _, s := range states {
+		if id, ok := g.ids[s]; ok {
+			f[id] = true
+		}
+	}
+	return f
+}
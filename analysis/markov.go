@@ -0,0 +1,231 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+var (
+	ErrProbabilities = errors.New("transition probabilities don't add up")
+	ErrNotAbsorbing  = errors.New("chain can run forever")
+)
+
+// Markov runs a ruleset as a Markov chain, moving from each state to one of
+// its exits at random, for simulation and capacity planning. Guards are
+// ignored.
+type Markov struct {
+	g *graph
+	p [][]float64 // p[o][i] is the probability of g.out[o][i]
+}
+
+// NewMarkov annotates the transitions of r with probabilities. The exits of
+// a state that aren't given one share what the others leave of 1 equally;
+// when every exit is given one they must add up to 1.
+func NewMarkov(r fsm.Ruleset, probabilities map[fsm.T]float64) (*Markov, error) {
+	g := newGraph(r)
+	m := &Markov{g: g, p: make([][]float64, len(g.states))}
+
+	for t := range probabilities {
+		if _, ok := r[t]; !ok {
+			return nil, fmt.Errorf("%w: %s -> %s isn't a transition", ErrProbabilities, t.O, t.E)
+		}
+	}
+
+	const epsilon = 1e-9
+	for o, out := range g.out {
+		m.p[o] = make([]float64, len(out))
+		total, unset := 0.0, 0
+		for i, e := range out {
+			p, ok := probabilities[fsm.T{O: g.states[o], E: g.states[e]}]
+			if !ok {
+				unset++
+				p = math.NaN()
+			}
+			if p < 0 || p > 1 {
+				return nil, fmt.Errorf("%w: %s -> %s has %v", ErrProbabilities, g.states[o], g.states[e], p)
+			}
+			m.p[o][i] = p
+			if ok {
+				total += p
+			}
+		}
+
+		switch {
+		case total > 1+epsilon, unset == 0 && len(out) > 0 && math.Abs(total-1) > epsilon:
+			return nil, fmt.Errorf("%w: exits of %s add up to %v", ErrProbabilities, g.states[o], total)
+		}
+		for i := range m.p[o] {
+			if math.IsNaN(m.p[o][i]) {
+				m.p[o][i] = (1 - total) / float64(unset)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Step returns the state a machine in s moves to next, or s itself if it
+// has no exits.
+func (m *Markov) Step(rng *rand.Rand, s fsm.State) fsm.State {
+	o, ok := m.g.ids[s]
+	if !ok || len(m.g.out[o]) == 0 {
+		return s
+	}
+
+	x := rng.Float64()
+	for i, p := range m.p[o] {
+		if x -= p; x < 0 {
+			return m.g.states[m.g.out[o][i]]
+		}
+	}
+	return m.g.states[m.g.out[o][len(m.g.out[o])-1]]
+}
+
+// Simulate walks the chain from start for at most steps transitions,
+// stopping early at a state without exits. The path includes start.
+func (m *Markov) Simulate(rng *rand.Rand, start fsm.State, steps int) []fsm.State {
+	path := []fsm.State{start}
+	for range steps {
+		o, ok := m.g.ids[start]
+		if !ok || len(m.g.out[o]) == 0 {
+			break
+		}
+		start = m.Step(rng, start)
+		path = append(path, start)
+	}
+	return path
+}
+
+// Distribution returns the long-run probability of being in each state,
+// for a machine starting at start. For chains with states without exits it
+// is the probability of ending up in each of them. It is found by
+// iterating the chain until it settles, or for at most a million steps,
+// which periodic chains never settle within.
+func (m *Markov) Distribution(start fsm.State) map[fsm.State]float64 {
+	n := len(m.g.states)
+	x := make([]float64, n)
+	if id, ok := m.g.ids[start]; ok {
+		x[id] = 1
+	} else {
+		return map[fsm.State]float64{start: 1}
+	}
+
+	for range 1_000_000 {
+		next := make([]float64, n)
+		for o, out := range m.g.out {
+			if len(out) == 0 {
+				next[o] += x[o]
+			}
+			for i, e := range out {
+				next[e] += x[o] * m.p[o][i]
+			}
+		}
+
+		delta := 0.0
+		for i := range x {
+			delta = max(delta, math.Abs(next[i]-x[i]))
+		}
+		x = next
+		if delta < 1e-12 {
+			break
+		}
+	}
+
+	d := map[fsm.State]float64{}
+	for i, p := range x {
+		if p > 1e-12 {
+			d[m.g.states[i]] = p
+		}
+	}
+	return d
+}
+
+// ExpectedSteps returns the expected number of transitions before a
+// machine starting at start reaches a state without exits.
+func (m *Markov) ExpectedSteps(start fsm.State) (float64, error) {
+	return m.absorption(start, func(fsm.State) float64 { return 1 })
+}
+
+// ExpectedTime is like ExpectedSteps but weighs each visit to a state by
+// how long a machine spends in it on average, such as a review queue's
+// handling time. States missing from holding take no time.
+func (m *Markov) ExpectedTime(start fsm.State, holding map[fsm.State]time.Duration) (time.Duration, error) {
+	t, err := m.absorption(start, func(s fsm.State) float64 { return float64(holding[s]) })
+	return time.Duration(t), err
+}
+
+// absorption solves (I - Q) x = cost over the transient states reachable
+// from start, where Q holds the probabilities among them.
+func (m *Markov) absorption(start fsm.State, cost func(fsm.State) float64) (float64, error) {
+	id, ok := m.g.ids[start]
+	if !ok || len(m.g.out[id]) == 0 {
+		return 0, nil
+	}
+
+	var transient []int
+	index := map[int]int{}
+	for s, d := range m.g.bfs(id) {
+		if d >= 0 && len(m.g.out[s]) > 0 {
+			index[s] = len(transient)
+			transient = append(transient, s)
+		}
+	}
+
+	n := len(transient)
+	a := make([][]float64, n)
+	for i, s := range transient {
+		a[i] = make([]float64, n+1)
+		a[i][i] = 1
+		for k, e := range m.g.out[s] {
+			if j, ok := index[e]; ok {
+				a[i][j] -= m.p[s][k]
+			}
+		}
+		a[i][n] = cost(m.g.states[s])
+	}
+
+	x, ok := solve(a)
+	if !ok {
+		return 0, fmt.Errorf("%w: from %s", ErrNotAbsorbing, start)
+	}
+	return x[index[id]], nil
+}
+
+// solve solves the augmented system a by Gaussian elimination with partial
+// pivoting, reporting false if it is singular.
+func solve(a [][]float64) ([]float64, bool) {
+	n := len(a)
+	for c := range n {
+		pivot := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[pivot][c]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][c]) < 1e-12 {
+			return nil, false
+		}
+		a[c], a[pivot] = a[pivot], a[c]
+
+		for r := range n {
+			if r == c || a[r][c] == 0 {
+				continue
+			}
+			f := a[r][c] / a[c][c]
+			for k := c; k <= n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = a[i][n] / a[i][i]
+	}
+	return x, true
+}
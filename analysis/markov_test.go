@@ -0,0 +1,76 @@
+package analysis_test
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/analysis"
+)
+
+var review = fsm.CreateRuleset(
+	fsm.T{O: "queued", E: "reviewing"},
+	fsm.T{O: "reviewing", E: "approved"},
+	fsm.T{O: "reviewing", E: "rejected"},
+	fsm.T{O: "reviewing", E: "queued"},
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMarkov(t *testing.T) {
+	chain, err := analysis.NewMarkov(review, map[fsm.T]float64{
+		{O: "reviewing", E: "approved"}: 0.6,
+		{O: "reviewing", E: "queued"}:   0.2,
+	})
+	st.Expect(t, err, nil)
+
+	d := chain.Distribution("queued")
+	st.Expect(t, near(d["approved"], 0.75), true)
+	st.Expect(t, near(d["rejected"], 0.25), true)
+
+	// every pass through review takes two steps and is repeated a fifth of
+	// the time: 2 / (1 - 0.2)
+	steps, err := chain.ExpectedSteps("queued")
+	st.Expect(t, err, nil)
+	st.Expect(t, near(steps, 2.5), true)
+
+	wait, err := chain.ExpectedTime("queued", map[fsm.State]time.Duration{
+		"queued":    3 * time.Hour,
+		"reviewing": time.Hour,
+	})
+	st.Expect(t, err, nil)
+	st.Expect(t, wait, 5*time.Hour)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	approved := 0
+	for range 10000 {
+		path := chain.Simulate(rng, "queued", 100)
+		if path[len(path)-1] == "approved" {
+			approved++
+		}
+	}
+	st.Expect(t, approved > 7300 && approved < 7700, true)
+}
+
+func TestMarkovErrors(t *testing.T) {
+	_, err := analysis.NewMarkov(review, map[fsm.T]float64{
+		{O: "reviewing", E: "approved"}: 0.6,
+		{O: "reviewing", E: "rejected"}: 0.6,
+	})
+	st.Expect(t, errors.Is(err, analysis.ErrProbabilities), true)
+
+	_, err = analysis.NewMarkov(review, map[fsm.T]float64{{O: "queued", E: "approved"}: 1})
+	st.Expect(t, errors.Is(err, analysis.ErrProbabilities), true)
+
+	loop, err := analysis.NewMarkov(fsm.CreateRuleset(
+		fsm.T{O: "a", E: "b"},
+		fsm.T{O: "b", E: "a"},
+	), nil)
+	st.Expect(t, err, nil)
+	_, err = loop.ExpectedSteps("a")
+	st.Expect(t, errors.Is(err, analysis.ErrNotAbsorbing), true)
+}
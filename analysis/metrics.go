@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"slices"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// SCCs returns the strongly connected components of r: the groups of
+// states that can all reach each other. Each is sorted, and they are
+// listed so that a component comes before any component that can reach it.
+func SCCs(r fsm.Ruleset) [][]fsm.State {
+	g := newGraph(r)
+	components := g.sccs()
+
+	sccs := make([][]fsm.State, len(components))
+	for i, c := range components {
+		sccs[i] = g.names(c)
+	}
+	return sccs
+}
+
+// Cycles returns every elementary cycle of r, each starting at its
+// smallest state, in order. The number of cycles can grow exponentially
+// with the size of a densely connected ruleset.
+func Cycles(r fsm.Ruleset) [][]fsm.State {
+	g := newGraph(r)
+	var (
+		cycles [][]fsm.State
+		path   []int
+		onPath = make([]bool, len(g.states))
+		walk   func(start, v int)
+	)
+	walk = func(start, v int) {
+		path = append(path, v)
+		onPath[v] = true
+		for _, w := range g.out[v] {
+			switch {
+			case w == start:
+				cycles = append(cycles, g.names(path))
+			case w > start && !onPath[w]:
+				walk(start, w)
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[v] = false
+	}
+
+	// states are sorted, so starting only from the smallest state of each
+	// cycle finds it exactly once
+	for start := range g.states {
+		walk(start, start)
+	}
+
+	slices.SortFunc(cycles, slices.Compare)
+	return cycles
+}
+
+// Unbounded is the Longest distance of a state that can go round a cycle
+// on its way to a final state.
+const Unbounded = -1
+
+// Distance is how many transitions a state is from the nearest and the
+// furthest final state.
+type Distance struct {
+	Shortest, Longest int
+}
+
+// Distances returns the Distance of each state that can reach one of
+// finals. When no finals are given, the states without exits are taken to
+// be final.
+func Distances(r fsm.Ruleset, finals ...fsm.State) map[fsm.State]Distance {
+	g := newGraph(r)
+	final := g.finals(finals)
+	reaching := g.reaching(final)
+
+	// a state is unbounded if it can reach a cycle from which a final state
+	// can still be reached
+	cyclic := make([]bool, len(g.states))
+	for _, c := range g.sccs() {
+		if g.cyclic(c) {
+			for _, s := range c {
+				cyclic[s] = reaching[s]
+			}
+		}
+	}
+	unbounded := g.reaching(cyclic)
+
+	longest := make([]int, len(g.states))
+	done := make([]bool, len(g.states))
+	var measure func(v int) int
+	measure = func(v int) int {
+		if !done[v] {
+			done[v] = true
+			for _, w := range g.out[v] {
+				if reaching[w] {
+					longest[v] = max(longest[v], measure(w)+1)
+				}
+			}
+		}
+		return longest[v]
+	}
+
+	d := map[fsm.State]Distance{}
+	for v, s := range g.states {
+		if !reaching[v] {
+			continue
+		}
+		dist := Distance{Shortest: g.shortest(v, final), Longest: Unbounded}
+		if !unbounded[v] {
+			dist.Longest = measure(v)
+		}
+		d[s] = dist
+	}
+	return d
+}
+
+// shortest returns the length of the shortest path from v to a target, or
+// -1 if there is none.
+func (g *graph) shortest(v int, targets []bool) int {
+	dist := g.bfs(v)
+	best := -1
+	for w, d := range dist {
+		if targets[w] && d >= 0 && (best < 0 || d < best) {
+			best = d
+		}
+	}
+	return best
+}
+
+// bfs returns the length of the shortest path from v to every state, -1
+// where there is none.
+func (g *graph) bfs(v int) []int {
+	dist := make([]int, len(g.states))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[v] = 0
+	queue := []int{v}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, w := range g.out[s] {
+			if dist[w] < 0 {
+				dist[w] = dist[s] + 1
+				queue = append(queue, w)
+			}
+		}
+	}
+	return dist
+}
+
+// Diameter returns the greatest number of transitions needed to get from
+// one state of r to another that it can reach.
+func Diameter(r fsm.Ruleset) int {
+	g := newGraph(r)
+	diameter := 0
+	for v := range g.states {
+		for _, d := range g.bfs(v) {
+			diameter = max(diameter, d)
+		}
+	}
+	return diameter
+}
@@ -0,0 +1,55 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/analysis"
+)
+
+func TestSCCs(t *testing.T) {
+	st.Expect(t, analysis.SCCs(rules), [][]fsm.State{
+		{"done"},
+		{"approved"},
+		{"limbo", "purgatory"},
+		{"review", "rework"},
+		{"new"},
+	})
+}
+
+func TestCycles(t *testing.T) {
+	looped := fsm.CreateRuleset(
+		fsm.T{O: "a", E: "b"},
+		fsm.T{O: "b", E: "a"},
+		fsm.T{O: "b", E: "c"},
+		fsm.T{O: "c", E: "a"},
+		fsm.T{O: "c", E: "c"},
+	)
+	st.Expect(t, analysis.Cycles(looped), [][]fsm.State{
+		{"a", "b"},
+		{"a", "b", "c"},
+		{"c"},
+	})
+}
+
+func TestDistances(t *testing.T) {
+	st.Expect(t, analysis.Distances(rules), map[fsm.State]analysis.Distance{
+		"new":      {Shortest: 3, Longest: analysis.Unbounded},
+		"review":   {Shortest: 2, Longest: analysis.Unbounded},
+		"rework":   {Shortest: 3, Longest: analysis.Unbounded},
+		"approved": {Shortest: 1, Longest: 1},
+		"done":     {Shortest: 0, Longest: 0},
+	})
+
+	linear := fsm.CreateRuleset(
+		fsm.T{O: "a", E: "b"},
+		fsm.T{O: "b", E: "c"},
+		fsm.T{O: "a", E: "c"},
+	)
+	st.Expect(t, analysis.Distances(linear)["a"], analysis.Distance{Shortest: 1, Longest: 2})
+}
+
+func TestDiameter(t *testing.T) {
+	st.Expect(t, analysis.Diameter(rules), 3)
+}
@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// Equivalences returns the groups of states that behave the same: they can
+// move to the same states, up to equivalence, so a machine couldn't tell
+// them apart. Guards are functions that can't be compared, so states with
+// guarded exits are only ever equivalent to themselves, as are states
+// without exits, which usually record distinct outcomes. Each group is
+// sorted, and only groups of more than one state are returned.
+func Equivalences(r fsm.Ruleset) [][]fsm.State {
+	g := newGraph(r)
+	var groups [][]fsm.State
+	for _, c := range g.equivalences(r) {
+		if len(c) > 1 {
+			groups = append(groups, g.names(c))
+		}
+	}
+	return groups
+}
+
+// Minimize returns r with each group of equivalent states merged into its
+// smallest member, and the mapping from every state of r to the state
+// standing for it.
+func Minimize(r fsm.Ruleset) (fsm.Ruleset, map[fsm.State]fsm.State) {
+	g := newGraph(r)
+	mapping := make(map[fsm.State]fsm.State, len(g.states))
+	for _, c := range g.equivalences(r) {
+		for _, s := range c {
+			mapping[g.states[s]] = g.states[c[0]]
+		}
+	}
+
+	reduced := fsm.Ruleset{}
+	for t, guards := range r {
+		if mapping[t.Origin()] != t.Origin() {
+			continue // its representative contributes the same transitions
+		}
+		merged := fsm.T{O: t.Origin(), E: mapping[t.Exit()]}
+		reduced.AddTransition(merged)
+		reduced.AddRule(merged, guards...)
+	}
+	return reduced, mapping
+}
+
+// equivalences partitions the states by refining, until it is stable, the
+// partition that sets apart only terminal states and those with guards.
+func (g *graph) equivalences(r fsm.Ruleset) [][]int {
+	guarded := make([]bool, len(g.states))
+	for t, guards := range r {
+		if len(guards) > 0 {
+			guarded[g.ids[t.Origin()]] = true
+		}
+	}
+
+	class := make([]int, len(g.states))
+	for s := range g.states {
+		if len(g.out[s]) == 0 || guarded[s] {
+			class[s] = s + 1
+		}
+	}
+
+	for {
+		signatures := map[string]int{}
+		next := make([]int, len(g.states))
+		for s := range g.states {
+			goals := make([]int, len(g.out[s]))
+			for i, e := range g.out[s] {
+				goals[i] = class[e]
+			}
+			slices.Sort(goals)
+			sig := fmt.Sprint(class[s], slices.Compact(goals))
+
+			id, ok := signatures[sig]
+			if !ok {
+				id = len(signatures)
+				signatures[sig] = id
+			}
+			next[s] = id
+		}
+
+		stable := len(signatures) == countDistinct(class)
+		class = next
+		if stable {
+			break
+		}
+	}
+
+	groups := map[int][]int{}
+	for s, c := range class {
+		groups[c] = append(groups[c], s)
+	}
+	partition := make([][]int, 0, len(groups))
+	for _, c := range groups {
+		partition = append(partition, c)
+	}
+	slices.SortFunc(partition, func(a, b []int) int { return a[0] - b[0] })
+	return partition
+}
+
+func countDistinct(class []int) int {
+	seen := map[int]bool{}
+	for _, c := range class {
+		seen[c] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,39 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/analysis"
+)
+
+func TestMinimize(t *testing.T) {
+	guard := func(subject fsm.Stater, goal fsm.State) bool { return true }
+
+	rules := fsm.CreateRuleset(
+		fsm.T{O: "new", E: "check_a"},
+		fsm.T{O: "new", E: "check_b"},
+		fsm.T{O: "check_a", E: "verify_a"},
+		fsm.T{O: "check_b", E: "verify_b"},
+		fsm.T{O: "verify_a", E: "done"},
+		fsm.T{O: "verify_b", E: "done"},
+		fsm.T{O: "new", E: "manual"},
+		fsm.T{O: "manual", E: "done"},
+		fsm.T{O: "new", E: "cancelled"},
+	)
+	rules.AddRule(fsm.T{O: "manual", E: "done"}, guard)
+
+	st.Expect(t, analysis.Equivalences(rules), [][]fsm.State{
+		{"check_a", "check_b"},
+		{"verify_a", "verify_b"},
+	})
+
+	minimized, mapping := analysis.Minimize(rules)
+	st.Expect(t, mapping["check_b"], fsm.State("check_a"))
+	st.Expect(t, mapping["verify_b"], fsm.State("verify_a"))
+	st.Expect(t, mapping["manual"], fsm.State("manual"))
+	st.Expect(t, len(minimized), 6)
+	st.Expect(t, minimized.ExitsFrom("new"), []fsm.State{"cancelled", "check_a", "manual"})
+	st.Expect(t, len(minimized[fsm.T{O: "manual", E: "done"}]), 1)
+}
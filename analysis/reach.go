@@ -0,0 +1,22 @@
+package analysis
+
+import "github.com/ryanfaerman/fsm/v3"
+
+// Reachable returns the states that can be reached from from, including
+// from itself, whatever the guards decide. Together with fsm.Product it
+// shows whether two workflows can end up in a combination of states.
+func Reachable(r fsm.Ruleset, from fsm.State) []fsm.State {
+	g := newGraph(r)
+	id, ok := g.ids[from]
+	if !ok {
+		return []fsm.State{from}
+	}
+
+	var reached []int
+	for s, d := range g.bfs(id) {
+		if d >= 0 {
+			reached = append(reached, s)
+		}
+	}
+	return g.names(reached)
+}
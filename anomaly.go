@@ -0,0 +1,125 @@
+package fsm
+
+import (
+	"sync"
+	"time"
+)
+
+// RateThreshold says when the rate of a transition is anomalous. Rates are
+// in transitions per second over the RateMonitor's Window.
+type RateThreshold struct {
+	Max    float64 // rate above which to alert; 0 for no limit
+	Factor float64 // multiple of the baseline rate above which to alert; 0 to not compare
+
+	// MinCount is the number of transitions the Window must hold before
+	// the rate is compared with the baseline, so that two transitions
+	// where there is usually one aren't taken for a spike.
+	MinCount int
+}
+
+// RateAnomaly reports a transition seen at an anomalous rate.
+type RateAnomaly struct {
+	Transition T
+	Rate       float64 // per second, over the Window
+	Baseline   float64 // per second, over the Baseline before the Window
+	At         time.Time
+}
+
+// RateMonitor tracks how often each transition happens and calls Alert
+// when one is seen far more often than it should be, such as a sudden
+// spike of failed payments. Its Observe method is a Hook, to be registered
+// WithHooks on any number of machines.
+type RateMonitor struct {
+	// Window is the sliding window rates are measured over, and Baseline
+	// the longer one before it that they are compared with.
+	Window, Baseline time.Duration
+
+	Threshold  RateThreshold       // for transitions not in Thresholds
+	Thresholds map[T]RateThreshold // per transition
+	Clock      Clock               // defaults to SystemClock
+
+	// Alert is called when a transition's rate crosses its threshold, once
+	// until the rate has fallen back below it.
+	Alert func(RateAnomaly)
+
+	mu       sync.Mutex
+	seen     map[T][]time.Time
+	alerting map[T]bool
+}
+
+// Observe counts the transition described by tc.
+func (r *RateMonitor) Observe(tc TransitionContext) {
+	clock := r.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+	t := T{tc.Origin, tc.Goal}
+
+	r.mu.Lock()
+	if r.seen == nil {
+		r.seen, r.alerting = map[T][]time.Time{}, map[T]bool{}
+	}
+	r.seen[t] = append(r.prune(r.seen[t], now), now)
+	anomaly, anomalous := r.measure(t, now)
+	alert := anomalous && !r.alerting[t]
+	r.alerting[t] = anomalous
+	r.mu.Unlock()
+
+	if alert && r.Alert != nil {
+		r.Alert(anomaly)
+	}
+}
+
+// Rate returns the current rate of t, per second over the Window.
+func (r *RateMonitor) Rate(t T) float64 {
+	clock := r.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	anomaly, _ := r.measure(t, clock.Now())
+	return anomaly.Rate
+}
+
+// prune drops the times that have fallen out of the Baseline.
+func (r *RateMonitor) prune(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-r.Window - r.Baseline)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func (r *RateMonitor) measure(t T, now time.Time) (RateAnomaly, bool) {
+	threshold, ok := r.Thresholds[t]
+	if !ok {
+		threshold = r.Threshold
+	}
+
+	recent, earlier := 0, 0
+	for _, seen := range r.seen[t] {
+		switch {
+		case seen.After(now.Add(-r.Window)):
+			recent++
+		case seen.After(now.Add(-r.Window - r.Baseline)):
+			earlier++
+		}
+	}
+
+	anomaly := RateAnomaly{Transition: t, At: now}
+	if r.Window > 0 {
+		anomaly.Rate = float64(recent) / r.Window.Seconds()
+	}
+	if r.Baseline > 0 {
+		anomaly.Baseline = float64(earlier) / r.Baseline.Seconds()
+	}
+
+	spiked := threshold.Factor > 0 && recent >= threshold.MinCount &&
+		anomaly.Rate > threshold.Factor*anomaly.Baseline
+	return anomaly, threshold.Max > 0 && anomaly.Rate > threshold.Max || spiked
+}
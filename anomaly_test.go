@@ -0,0 +1,57 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRateMonitor(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	failed := fsm.T{O: "pending", E: "failed"}
+
+	var anomalies []fsm.RateAnomaly
+	monitor := &fsm.RateMonitor{
+		Window:     time.Minute,
+		Baseline:   time.Hour,
+		Threshold:  fsm.RateThreshold{Max: 1},
+		Thresholds: map[fsm.T]fsm.RateThreshold{failed: {Factor: 10, MinCount: 5}},
+		Clock:      fsm.ClockFunc(func() time.Time { return now }),
+		Alert:      func(a fsm.RateAnomaly) { anomalies = append(anomalies, a) },
+	}
+	observe := func(origin, goal fsm.State) {
+		monitor.Observe(fsm.TransitionContext{Origin: origin, Goal: goal})
+	}
+
+	// one failure every ten minutes is normal
+	for range 6 {
+		observe("pending", "failed")
+		now = now.Add(10 * time.Minute)
+	}
+	st.Expect(t, len(anomalies), 0)
+
+	// then five in a minute is not
+	for range 5 {
+		observe("pending", "failed")
+		now = now.Add(time.Second)
+	}
+	st.Expect(t, len(anomalies), 1)
+	st.Expect(t, anomalies[0].Transition, failed)
+	st.Expect(t, anomalies[0].Rate, 5.0/60)
+	st.Expect(t, monitor.Rate(failed), 5.0/60)
+
+	// and isn't reported again until it has calmed down
+	observe("pending", "failed")
+	st.Expect(t, len(anomalies), 1)
+	now = now.Add(2 * time.Hour)
+	st.Expect(t, monitor.Rate(failed), 0.0)
+
+	// the default threshold caps the rate of other transitions
+	for range 61 {
+		observe("pending", "paid")
+	}
+	st.Expect(t, len(anomalies), 2)
+	st.Expect(t, anomalies[1].Transition, fsm.T{O: "pending", E: "paid"})
+}
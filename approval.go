@@ -0,0 +1,139 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrApprovalRequired = errors.New("approval required")
+	ErrNotApprover      = errors.New("not an approver")
+)
+
+// RequireApprovals is intended to be passed to New. It only permits the
+// transition t once n of the named approvers have approved it with
+// Machine.Approve. Approvals are given in a state and are discarded when the
+// machine leaves that state.
+func RequireApprovals(t Transition, n int, approvers ...string) func(*Machine) {
+	return func(m *Machine) {
+		if m.approvals == nil {
+			m.approvals = &approvals{required: map[T]approval{}}
+		}
+
+		for _, t := range expand(t) {
+			a := approval{n: n, approvers: map[string]bool{}}
+			for _, name := range approvers {
+				a.approvers[name] = true
+			}
+			m.approvals.required[T{t.Origin(), t.Exit()}] = a
+		}
+	}
+}
+
+type approval struct {
+	n         int
+	approvers map[string]bool
+}
+
+type approvals struct {
+	required map[T]approval
+
+	mu    sync.Mutex
+	state State           // the state the approvals below were given in
+	given map[string]bool // by actor
+}
+
+// current returns the approvals given in state.
+func (a *approvals) current(state State) map[string]bool {
+	if a.state != state || a.given == nil {
+		a.state, a.given = state, map[string]bool{}
+	}
+	return a.given
+}
+
+// reset discards every approval; the machine has left the state they were
+// given in.
+func (a *approvals) reset() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state, a.given = "", nil
+}
+
+// check returns ErrApprovalRequired if tc still lacks approvals, for its
+// own transition or that of any state h nests its origin within.
+func (a *approvals) check(tc TransitionContext, h Hierarchy) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range h.lineage(tc.Origin) {
+		req, ok := a.required[T{s, tc.Goal}]
+		if !ok {
+			continue
+		}
+
+		count := 0
+		for actor := range a.current(tc.Origin) {
+			if req.approvers[actor] {
+				count++
+			}
+		}
+
+		if count < req.n {
+			return fmt.Errorf("%w: %w: %d of %d for %s -> %s",
+				ErrInvalidTransition, ErrApprovalRequired, count, req.n, s, tc.Goal)
+		}
+	}
+	return nil
+}
+
+// Approve records the approval of actor for transitions out of the current
+// state, or the states it is nested within. The actor must be an approver
+// of at least one of them.
+func (m Machine) Approve(actor string) error {
+	if m.approvals == nil {
+		return fmt.Errorf("%w: %q", ErrNotApprover, actor)
+	}
+
+	origin := m.Subject.CurrentState()
+	for t, req := range m.approvals.required {
+		if m.hierarchy.In(origin, t.O) && req.approvers[actor] {
+			m.approvals.mu.Lock()
+			defer m.approvals.mu.Unlock()
+
+			m.approvals.current(origin)[actor] = true
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", ErrNotApprover, actor)
+}
+
+// Approvals returns the actors that have approved a transition out of the
+// current state, sorted.
+func (m Machine) Approvals() []string {
+	if m.approvals == nil {
+		return nil
+	}
+
+	m.approvals.mu.Lock()
+	defer m.approvals.mu.Unlock()
+
+	var actors []string
+	for actor := range m.approvals.current(m.Subject.CurrentState()) {
+		actors = append(actors, actor)
+	}
+	sort.Strings(actors)
+
+	return actors
+}
@@ -0,0 +1,46 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRequireApprovals(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"draft", "review"},
+		fsm.T{"review", "draft"},
+		fsm.T{"review", "published"},
+	)
+
+	some_thing := Thing{State: "draft"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.RequireApprovals(fsm.T{"review", "published"}, 2, "alice", "bob", "carol"),
+	)
+
+	st.Expect(t, errors.Is(the_machine.Approve("alice"), fsm.ErrNotApprover), true)
+	st.Expect(t, the_machine.Transition("review"), nil)
+
+	st.Expect(t, errors.Is(the_machine.Approve("mallory"), fsm.ErrNotApprover), true)
+	st.Expect(t, the_machine.Approve("alice"), nil)
+	st.Expect(t, the_machine.Approve("alice"), nil)
+
+	err := the_machine.Transition("published")
+	st.Expect(t, errors.Is(err, fsm.ErrApprovalRequired), true)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, the_machine.Stats().Rejections["approval"], uint64(1))
+
+	// leaving the state discards the approvals given in it
+	st.Expect(t, the_machine.Transition("draft"), nil)
+	st.Expect(t, the_machine.Transition("review"), nil)
+	st.Expect(t, len(the_machine.Approvals()), 0)
+
+	st.Expect(t, the_machine.Approve("bob"), nil)
+	st.Expect(t, the_machine.Approve("carol"), nil)
+	st.Expect(t, the_machine.Approvals(), []string{"bob", "carol"})
+	st.Expect(t, the_machine.Transition("published"), nil)
+}
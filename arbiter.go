@@ -0,0 +1,143 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownParty = errors.New("unknown party")
+	ErrUnknownMove  = errors.New("unknown move")
+)
+
+// Move is a transition proposed for the machine of one party.
+type Move struct {
+	ID      int
+	Party   string
+	Goal    State
+	Payload interface{}
+}
+
+// Arbiter coordinates the machines of several parties, such as the players
+// of a game or the sides of a negotiation. Parties propose moves, and a
+// move is only committed when the Referee machine permits the transition
+// that Ruling requires of it, e.g. from "alice's turn" to "bob's turn".
+type Arbiter struct {
+	Referee Machine
+
+	// Ruling returns the state the referee must move to for move to be
+	// committed.
+	Ruling func(move Move) State
+
+	mu      sync.Mutex
+	parties map[string]Machine
+	pending map[int]Move
+	nextID  int
+}
+
+// NewArbiter creates an Arbiter with the given referee and ruling.
+func NewArbiter(referee Machine, ruling func(move Move) State) *Arbiter {
+	return &Arbiter{
+		Referee: referee,
+		Ruling:  ruling,
+		parties: map[string]Machine{},
+		pending: map[int]Move{},
+	}
+}
+
+// Join adds the machine of party to the arbiter.
+func (a *Arbiter) Join(party string, m Machine) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.parties[party] = m
+}
+
+// Propose records a move for party without committing it. The party's own
+// rules must permit the move at the time it is proposed.
+func (a *Arbiter) Propose(party string, goal State, payload interface{}) (Move, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m, ok := a.parties[party]
+	if !ok {
+		return Move{}, fmt.Errorf("%w: %q", ErrUnknownParty, party)
+	}
+	if !m.rules().Permitted(m.Subject, goal) {
+		return Move{}, ErrInvalidTransition
+	}
+
+	a.nextID++
+	move := Move{ID: a.nextID, Party: party, Goal: goal, Payload: payload}
+	a.pending[move.ID] = move
+
+	return move, nil
+}
+
+// Pending returns the moves that are neither resolved nor withdrawn, in the
+// order they were proposed.
+func (a *Arbiter) Pending() []Move {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	moves := make([]Move, 0, len(a.pending))
+	for _, move := range a.pending {
+		moves = append(moves, move)
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].ID < moves[j].ID })
+
+	return moves
+}
+
+// Resolve commits the move with the given id if both the referee and the
+// party's machine permit it: the referee moves to the state given by Ruling
+// and the party to the goal of the move. Both transitions are checked in
+// full, authorizer, guards, approvals and checks, before either is made: if
+// either would be refused, neither changes and the move stays pending.
+//
+// The party moves first, as its machine isn't held by the arbiter and may
+// have changed since the check, or fail to record its event. If it fails,
+// the referee is left as it was and the move stays pending. The referee,
+// which only the arbiter moves, follows; should it fail all the same, the
+// party's move stands and is no longer pending, and the referee's error
+// is returned for it to be set right.
+func (a *Arbiter) Resolve(id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	move, ok := a.pending[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownMove, id)
+	}
+	m := a.parties[move.Party]
+
+	ruling := a.Ruling(move)
+	if err := a.Referee.permit(a.Referee.context(ruling, move)); err != nil {
+		return err
+	}
+	if err := m.permit(m.context(move.Goal, move.Payload)); err != nil {
+		return err
+	}
+
+	if err := m.TransitionWith(move.Goal, move.Payload); err != nil {
+		return err
+	}
+	delete(a.pending, id)
+
+	return a.Referee.TransitionWith(ruling, move)
+}
+
+// Withdraw discards the move with the given id.
+func (a *Arbiter) Withdraw(id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.pending[id]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownMove, id)
+	}
+	delete(a.pending, id)
+
+	return nil
+}
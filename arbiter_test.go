@@ -0,0 +1,107 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestArbiter(t *testing.T) {
+	player := fsm.CreateRuleset(
+		fsm.T{"waiting", "moved"},
+		fsm.T{"moved", "moved"},
+	)
+	referee := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"alice", "bob"},
+			fsm.T{"bob", "alice"},
+		)),
+		fsm.WithSubject(&Thing{State: "alice"}),
+	)
+
+	// whoever moves hands the turn to the other player
+	arbiter := fsm.NewArbiter(referee, func(move fsm.Move) fsm.State {
+		if move.Party == "alice" {
+			return "bob"
+		}
+		return "alice"
+	})
+
+	alice, bob := Thing{State: "waiting"}, Thing{State: "waiting"}
+	arbiter.Join("alice", fsm.New(fsm.WithRules(player), fsm.WithSubject(&alice)))
+	arbiter.Join("bob", fsm.New(fsm.WithRules(player), fsm.WithSubject(&bob)))
+
+	_, err := arbiter.Propose("carol", "moved", nil)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownParty), true)
+
+	bobs, err := arbiter.Propose("bob", "moved", "e5")
+	st.Expect(t, err, nil)
+	alices, err := arbiter.Propose("alice", "moved", "e4")
+	st.Expect(t, err, nil)
+	st.Expect(t, len(arbiter.Pending()), 2)
+
+	// it's not bob's turn, so nothing changes
+	st.Expect(t, arbiter.Resolve(bobs.ID), fsm.ErrInvalidTransition)
+	st.Expect(t, bob.State, fsm.State("waiting"))
+
+	st.Expect(t, arbiter.Resolve(alices.ID), nil)
+	st.Expect(t, alice.State, fsm.State("moved"))
+	st.Expect(t, referee.Subject.CurrentState(), fsm.State("bob"))
+
+	st.Expect(t, arbiter.Resolve(bobs.ID), nil)
+	st.Expect(t, bob.State, fsm.State("moved"))
+	st.Expect(t, len(arbiter.Pending()), 0)
+
+	st.Expect(t, errors.Is(arbiter.Resolve(bobs.ID), fsm.ErrUnknownMove), true)
+}
+
+func TestArbiterChecksBothMachinesFirst(t *testing.T) {
+	referee := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"alice", "bob"})),
+		fsm.WithSubject(&Thing{State: "alice"}),
+	)
+	arbiter := fsm.NewArbiter(referee, func(fsm.Move) fsm.State { return "bob" })
+
+	errIllegal := errors.New("illegal move")
+	alice := Thing{State: "idle"}
+	arbiter.Join("alice", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"idle", "moved"})),
+		fsm.WithSubject(&alice),
+		fsm.WithChecks(fsm.T{"idle", "moved"}, func(fsm.TransitionContext) error { return errIllegal }),
+	))
+
+	move, err := arbiter.Propose("alice", "moved", nil)
+	st.Expect(t, err, nil)
+
+	// the party's check refuses, so the referee isn't moved either
+	st.Expect(t, errors.Is(arbiter.Resolve(move.ID), errIllegal), true)
+	st.Expect(t, referee.Subject.CurrentState(), fsm.State("alice"))
+	st.Expect(t, alice.State, fsm.State("idle"))
+	st.Expect(t, len(arbiter.Pending()), 1)
+}
+
+func TestArbiterPartyFails(t *testing.T) {
+	referee := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"alice", "bob"})),
+		fsm.WithSubject(&Thing{State: "alice"}),
+	)
+	arbiter := fsm.NewArbiter(referee, func(fsm.Move) fsm.State { return "bob" })
+
+	// permitted, but the party can't record its move
+	alice := Thing{State: "idle"}
+	arbiter.Join("alice", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"idle", "moved"})),
+		fsm.WithSubject(&alice),
+		fsm.WithEventStore(&brokenStore{broken: true}, 0),
+	))
+
+	move, err := arbiter.Propose("alice", "moved", nil)
+	st.Expect(t, err, nil)
+
+	st.Expect(t, arbiter.Resolve(move.ID), errStoreDown)
+	st.Expect(t, referee.Subject.CurrentState(), fsm.State("alice"))
+	st.Expect(t, alice.State, fsm.State("idle"))
+	st.Expect(t, len(arbiter.Pending()), 1)
+}
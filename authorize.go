@@ -0,0 +1,85 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operation names something a caller can ask of a Machine or Registry.
+type Operation string
+
+const (
+	OpTransition Operation = "transition" // a guarded transition
+	OpForce      Operation = "force"      // ForceTransition
+	OpSwapRules  Operation = "swap-rules" // Registry.SwapRules
+	OpMigrate    Operation = "migrate"    // Registry.Migrate
+)
+
+// Privileged reports whether op sidesteps the rules, rather than playing
+// by them.
+func (op Operation) Privileged() bool {
+	return op != OpTransition
+}
+
+// AuthRequest describes an operation to be authorized. Key is only set by
+// SwapRules; Origin and Goal are empty for operations that don't move a
+// subject.
+type AuthRequest struct {
+	Operation    Operation
+	Actor        string
+	Key          string
+	Origin, Goal State
+}
+
+// Authorizer decides whether an operation may go ahead, returning nil if
+// it may. Its errors are returned wrapped in ErrUnauthorized.
+type Authorizer func(req AuthRequest) error
+
+func (a Authorizer) authorize(req AuthRequest) error {
+	if a == nil {
+		return nil
+	}
+	err := a(req)
+	if err == nil || errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnauthorized, err)
+}
+
+// WithAuthorizer is intended to be passed to New to consult a before every
+// Transition and ForceTransition of the machine. Transitions made with
+// Transition or TransitionWith have no Actor.
+func WithAuthorizer(a Authorizer) func(*Machine) {
+	return func(m *Machine) {
+		m.authorizer = a
+	}
+}
+
+// WithRegistryAuthorizer is intended to be passed to NewRegistry to consult
+// a before the registry's own privileged operations: SwapRules and
+// Migrate. Transitions of its machines are authorized by the machines.
+func WithRegistryAuthorizer(a Authorizer) func(*Registry) {
+	return func(r *Registry) {
+		r.authorizer = a
+	}
+}
+
+// SwapRules replaces the Rules of the machine stored under key, on behalf
+// of actor, once no transition of it is in progress.
+func (r *Registry) SwapRules(actor, key string, rules Permitter) error {
+	err := r.authorizer.authorize(AuthRequest{Operation: OpSwapRules, Actor: actor, Key: key})
+	if err != nil {
+		return err
+	}
+
+	e, ok := r.entry(key)
+	if !ok {
+		return ErrUnknownMachine
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.machine.setRules(rules)
+	return nil
+}
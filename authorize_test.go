@@ -0,0 +1,65 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+var errNotAdmin = errors.New("not an admin")
+
+func adminsOnly(requests *[]fsm.AuthRequest) fsm.Authorizer {
+	return func(req fsm.AuthRequest) error {
+		*requests = append(*requests, req)
+		if req.Operation.Privileged() && req.Actor != "admin" {
+			return errNotAdmin
+		}
+		return nil
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	var requests []fsm.AuthRequest
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithAuthorizer(adminsOnly(&requests)),
+	)
+
+	st.Expect(t, the_machine.TransitionAs("alice", "started", nil), nil)
+	err := the_machine.ForceTransition("pending", "retry", "alice")
+	st.Expect(t, errors.Is(err, fsm.ErrUnauthorized), true)
+	st.Expect(t, errors.Is(err, errNotAdmin), true)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+	st.Expect(t, the_machine.ForceTransition("pending", "retry", "admin"), nil)
+
+	st.Expect(t, requests, []fsm.AuthRequest{
+		{Operation: fsm.OpTransition, Actor: "alice", Origin: "pending", Goal: "started"},
+		{Operation: fsm.OpForce, Actor: "alice", Origin: "started", Goal: "pending"},
+		{Operation: fsm.OpForce, Actor: "admin", Origin: "started", Goal: "pending"},
+	})
+	st.Expect(t, the_machine.Stats().Rejections, map[string]uint64{})
+}
+
+func TestRegistryAuthorizer(t *testing.T) {
+	var requests []fsm.AuthRequest
+	registry := fsm.NewRegistry(fsm.WithRegistryAuthorizer(adminsOnly(&requests)))
+	some_thing := Thing{State: "pending"}
+	registry.Put("a", fsm.New(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(&some_thing)))
+
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	err := registry.SwapRules("alice", "a", &rules)
+	st.Expect(t, errors.Is(err, fsm.ErrUnauthorized), true)
+	st.Expect(t, registry.Transition("a", "started"), fsm.ErrInvalidTransition)
+
+	_, err = registry.Migrate(context.Background(), fsm.Migration{From: "pending", To: "started", Actor: "alice"})
+	st.Expect(t, errors.Is(err, fsm.ErrUnauthorized), true)
+
+	st.Expect(t, registry.SwapRules("admin", "a", &rules), nil)
+	st.Expect(t, registry.Transition("a", "started"), nil)
+	st.Expect(t, len(requests), 3)
+}
@@ -0,0 +1,102 @@
+package fsm
+
+// AvailableExits returns the goal of every transition out of the subject's
+// current state whose guards currently pass, sorted.
+func (r Ruleset) AvailableExits(subject Stater) []State {
+	return available(r, subject)
+}
+
+// AvailableExits returns the goal of every transition out of the subject's
+// current state whose guards currently pass, sorted.
+func (c *CompiledRuleset) AvailableExits(subject Stater) []State {
+	return available(c, subject)
+}
+
+func available(r interface {
+	Permitter
+	ExitsFrom(State) []State
+}, subject Stater) []State {
+	var exits []State
+	for _, goal := range r.ExitsFrom(subject.CurrentState()) {
+		if r.Permitted(subject, goal) {
+			exits = append(exits, goal)
+		}
+	}
+	return exits
+}
+
+// AvailableTransitions returns the states the subject could move to now,
+// sorted: those its Rules and Checks permit, as when deciding which
+// actions to offer. It requires Rules that can list their exits, such as a
+// Ruleset or CompiledRuleset, and returns nil otherwise. Approvals and the
+// Authorizer are not consulted, as they depend on who asks.
+func (m Machine) AvailableTransitions() []State {
+	r, ok := m.rules().(interface{ AvailableExits(Stater) []State })
+	if !ok {
+		return nil
+	}
+
+	tc, ok := m.peek("")
+	if !ok {
+		return nil
+	}
+
+	var goals []State
+	for _, goal := range r.AvailableExits(tc.Subject) {
+		tc.Goal = goal
+		if m.check(tc) == nil {
+			goals = append(goals, goal)
+		}
+	}
+	return goals
+}
+
+// CanTransition reports whether the subject could move to goal now: whether
+// its Rules and Checks permit it. Nothing is changed, recorded or counted,
+// so it is safe for read-only callers. Approvals and the Authorizer are not
+// consulted, as they depend on who asks.
+func (m Machine) CanTransition(goal State) bool {
+	tc, ok := m.peek(goal)
+	return ok && m.consult(tc) == nil && m.check(tc) == nil
+}
+
+// Allowed reports whether a subject in start may move to goal. The guards
+// are handed a stand-in subject that is in start and ignores SetState.
+func (r Ruleset) Allowed(start, goal State) bool {
+	return r.Permitted(stateView{start}, goal)
+}
+
+// Allowed reports whether a subject in start may move to goal, like
+// Ruleset.Allowed.
+func (c *CompiledRuleset) Allowed(start, goal State) bool {
+	return c.Permitted(stateView{start}, goal)
+}
+
+// stateView is a read-only stand-in for a subject in a state.
+type stateView struct{ state State }
+
+func (v stateView) CurrentState() State { return v.state }
+func (v stateView) SetState(State)      {}
+
+// peek describes the transition to goal without giving the subject its
+// DefaultState, as context would: a subject without a state is stood in
+// for by a stateView of its default. It is false for a subject that has
+// neither.
+func (m Machine) peek(goal State) (TransitionContext, bool) {
+	tc := TransitionContext{
+		Subject: m.Subject,
+		Origin:  m.Subject.CurrentState(),
+		Goal:    goal,
+		Variant: m.variant,
+		Labels:  m.labels,
+	}
+	if tc.Origin == "" {
+		d, ok := m.Subject.(Defaulter)
+		if !ok {
+			return tc, false
+		}
+		tc.Origin = d.DefaultState()
+		tc.Subject = stateView{tc.Origin}
+	}
+	return tc, true
+}
@@ -0,0 +1,68 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestAvailableTransitions(t *testing.T) {
+	paid := false
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "cancelled"},
+		fsm.T{"pending", "on_hold"},
+		fsm.T{"started", "finished"},
+	)
+	rules.AddRule(fsm.T{"pending", "started"}, func(fsm.Stater, fsm.State) bool { return paid })
+
+	some_thing := Thing{State: "pending"}
+	st.Expect(t, rules.AvailableExits(&some_thing), []fsm.State{"cancelled", "on_hold"})
+	st.Expect(t, rules.Compile().AvailableExits(&some_thing), []fsm.State{"cancelled", "on_hold"})
+
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithChecks(fsm.T{"pending", "on_hold"}, func(tc fsm.TransitionContext) error {
+			return errors.New("holds are suspended")
+		}),
+	)
+	st.Expect(t, the_machine.AvailableTransitions(), []fsm.State{"cancelled"})
+
+	paid = true
+	st.Expect(t, the_machine.AvailableTransitions(), []fsm.State{"cancelled", "started"})
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+}
+
+func TestCanTransition(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	rules.AddRule(fsm.T{"started", "finished"}, func(subject fsm.Stater, goal fsm.State) bool {
+		subject.SetState("tampered")
+		return true
+	})
+
+	st.Expect(t, rules.Allowed("pending", "started"), true)
+	st.Expect(t, rules.Allowed("pending", "finished"), false)
+	st.Expect(t, rules.Allowed("started", "finished"), true)
+	st.Expect(t, rules.Compile().Allowed("started", "finished"), true)
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithHistory())
+	st.Expect(t, the_machine.CanTransition("started"), true)
+	st.Expect(t, the_machine.CanTransition("finished"), false)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+	st.Expect(t, len(the_machine.History()), 0)
+	st.Expect(t, the_machine.Stats().Rejections["invalid"], uint64(0))
+
+	// A subject without a state is asked about as if in its default,
+	// without being moved there.
+	defaulted := DefaultedThing{}
+	the_machine = fsm.New(fsm.WithRules(rules), fsm.WithSubject(&defaulted))
+	st.Expect(t, the_machine.CanTransition("started"), true)
+	st.Expect(t, the_machine.AvailableTransitions(), []fsm.State{"started"})
+	st.Expect(t, defaulted.CurrentState(), fsm.State(""))
+
+	the_machine = fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{}))
+	st.Expect(t, the_machine.CanTransition("started"), false)
+}
@@ -0,0 +1,258 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrDuplicateTransition = errors.New("duplicate transition")
+	ErrDuplicateGuard      = errors.New("duplicate guard name")
+	ErrUnknownGuard        = errors.New("unknown guard")
+	ErrFinalHasExits       = errors.New("final state has exits")
+	ErrGuardCycle          = errors.New("guard dependency cycle")
+	ErrReservedState       = errors.New("reserved state name")
+)
+
+// Builder assembles a Ruleset from transitions, named guards, and final
+// states. Problems are collected rather than reported as they happen, so
+// Build can return every one of them at once.
+type Builder struct {
+	guards    map[string]Guard
+	factories map[string]func(arg string) (Guard, error)
+	entries   []builderEntry
+	depends   map[string][]string
+	sets      map[string][]string
+	finals    []State
+	scripts   map[string]DefinedScript
+	errs      []error
+}
+
+type builderEntry struct {
+	t      Transition
+	guards []string
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return &Builder{
+		guards:    map[string]Guard{},
+		factories: map[string]func(arg string) (Guard, error){},
+		depends:   map[string][]string{},
+		sets:      map[string][]string{},
+	}
+}
+
+// Guard registers a Guard under name so transitions can refer to it.
+func (b *Builder) Guard(name string, g Guard) *Builder {
+	_, isSet := b.sets[name]
+	if _, ok := b.guards[name]; ok || isSet {
+		b.errs = append(b.errs, fmt.Errorf("%w: %q", ErrDuplicateGuard, name))
+		return b
+	}
+	b.guards[name] = g
+	return b
+}
+
+// GuardFactory registers a way to make parameterized guards. Transitions
+// refer to them as "name:arg", e.g. "role:manager", and f is called with
+// arg to make the Guard.
+func (b *Builder) GuardFactory(name string, f func(arg string) (Guard, error)) *Builder {
+	if _, ok := b.factories[name]; ok {
+		b.errs = append(b.errs, fmt.Errorf("%w: %q", ErrDuplicateGuard, name))
+		return b
+	}
+	b.factories[name] = f
+	return b
+}
+
+// guard resolves a guard name used by a transition.
+func (b *Builder) guard(name string) (Guard, error) {
+	if g, ok := b.guards[name]; ok {
+		return g, nil
+	}
+
+	factory, arg, _ := strings.Cut(name, ":")
+	f, ok := b.factories[factory]
+	if !ok {
+		return nil, ErrUnknownGuard
+	}
+
+	g, err := f(arg)
+	if err != nil {
+		return nil, err
+	}
+	b.guards[name] = g
+	return g, nil
+}
+
+// GuardSet registers a bundle of named guards under name, such as
+// authentication, a tenant check and an audit precondition. Transitions
+// listing name are protected by every guard in the set.
+func (b *Builder) GuardSet(name string, guards ...string) *Builder {
+	_, isGuard := b.guards[name]
+	if _, isSet := b.sets[name]; isGuard || isSet {
+		b.errs = append(b.errs, fmt.Errorf("%w: %q", ErrDuplicateGuard, name))
+		return b
+	}
+	b.sets[name] = guards
+	return b
+}
+
+// Depends declares that the guard name must only run once the guards on
+// have passed, such as an expensive inventory check after a cheap
+// authorization one. Transitions using name are protected by on as well,
+// and their guards are ordered so that each runs after those it depends
+// on. Build reports cycles.
+func (b *Builder) Depends(name string, on ...string) *Builder {
+	b.depends[name] = append(b.depends[name], on...)
+	return b
+}
+
+// order returns guards, with guard sets expanded, preceded by everything
+// they depend on, keeping their order otherwise.
+func (b *Builder) order(guards []string) []string {
+	var ordered []string
+	seen := map[string]bool{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, dep := range b.depends[name] {
+			visit(dep)
+		}
+		if members, ok := b.sets[name]; ok {
+			for _, member := range members {
+				visit(member)
+			}
+			return
+		}
+		ordered = append(ordered, name)
+	}
+	for _, name := range guards {
+		visit(name)
+	}
+
+	return ordered
+}
+
+// cycles reports each dependency cycle among the guards.
+func (b *Builder) cycles() []error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	var errs []error
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			cycle := append(append([]string(nil), path[start:]...), name)
+			errs = append(errs, fmt.Errorf("%w: %s", ErrGuardCycle, strings.Join(cycle, " -> ")))
+			return
+		case done:
+			return
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range b.depends[name] {
+			visit(dep)
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+	}
+
+	names := make([]string, 0, len(b.depends))
+	for name := range b.depends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		visit(name)
+	}
+
+	return errs
+}
+
+// Transition adds a transition protected by the named guards, on top of
+// the default rule added by Ruleset.AddTransition.
+func (b *Builder) Transition(t Transition, guards ...string) *Builder {
+	b.entries = append(b.entries, builderEntry{t, guards})
+	return b
+}
+
+// Final declares states that must not have any exits.
+func (b *Builder) Final(states ...State) *Builder {
+	b.finals = append(b.finals, states...)
+	return b
+}
+
+// Build validates the configuration and returns the Ruleset. When there are
+// problems, the error joins all of them and the Ruleset is nil.
+func (b *Builder) Build() (Ruleset, error) {
+	errs := append([]error(nil), b.errs...)
+	errs = append(errs, b.cycles()...)
+	seen := map[Transition]bool{}
+	exits := map[State]bool{}
+
+	for _, e := range b.entries {
+		for _, t := range expand(e.t) {
+			if !hashable(t) {
+				errs = append(errs, fmt.Errorf("%w: %T", ErrUnhashableTransition, t))
+				continue
+			}
+
+			key := T{t.Origin(), t.Exit()}
+			if seen[key] {
+				errs = append(errs, fmt.Errorf("%w: %s -> %s", ErrDuplicateTransition, key.O, key.E))
+			}
+			seen[key] = true
+			exits[key.O] = true
+
+			if reserved(key.O) || reserved(key.E) {
+				errs = append(errs, fmt.Errorf("%w: %q -> %q", ErrReservedState, key.O, key.E))
+			}
+
+			for _, name := range b.order(e.guards) {
+				if _, err := b.guard(name); errors.Is(err, ErrUnknownGuard) {
+					errs = append(errs, fmt.Errorf("%w: %q on %s -> %s", ErrUnknownGuard, name, key.O, key.E))
+				} else if err != nil {
+					errs = append(errs, fmt.Errorf("guard %q on %s -> %s: %w", name, key.O, key.E, err))
+				}
+			}
+		}
+	}
+
+	for _, s := range b.finals {
+		if exits[s] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrFinalHasExits, s))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	r := Ruleset{}
+	for _, e := range b.entries {
+		r.AddTransition(e.t)
+		for _, name := range b.order(e.guards) {
+			r.AddRule(e.t, b.guards[name])
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,79 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestBuilderBuild(t *testing.T) {
+	allow := func(subject fsm.Stater, goal fsm.State) bool { return true }
+	deny := func(subject fsm.Stater, goal fsm.State) bool { return false }
+
+	rules, err := fsm.NewBuilder().
+		Guard("allow", allow).
+		Guard("deny", deny).
+		Transition(fsm.T{"pending", "started"}, "allow").
+		Transition(fsm.T{"started", "finished"}, "deny").
+		Final("finished").
+		Build()
+
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "started"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "finished"), false)
+}
+
+func TestBuilderCollectsAllErrors(t *testing.T) {
+	allow := func(subject fsm.Stater, goal fsm.State) bool { return true }
+
+	rules, err := fsm.NewBuilder().
+		Guard("allow", allow).
+		Guard("allow", allow).
+		Transition(fsm.T{"pending", "started"}).
+		Transition(fsm.T{"pending", "started"}).
+		Transition(fsm.T{"started", "finished"}, "missing").
+		Final("started").
+		Build()
+
+	st.Expect(t, rules == nil, true)
+	st.Expect(t, errors.Is(err, fsm.ErrDuplicateGuard), true)
+	st.Expect(t, errors.Is(err, fsm.ErrDuplicateTransition), true)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownGuard), true)
+	st.Expect(t, errors.Is(err, fsm.ErrFinalHasExits), true)
+}
+
+func TestBuilderOrdersGuardsByDependency(t *testing.T) {
+	var ran []string
+	guard := func(name string, pass bool) fsm.Guard {
+		return func(subject fsm.Stater, goal fsm.State) bool {
+			ran = append(ran, name)
+			return pass
+		}
+	}
+
+	rules, err := fsm.NewBuilder().
+		Guard("authenticated", guard("authenticated", true)).
+		Guard("authorized", guard("authorized", false)).
+		Guard("in-stock", guard("in-stock", true)).
+		Depends("in-stock", "authorized").
+		Depends("authorized", "authenticated").
+		Transition(fsm.T{"cart", "ordered"}, "in-stock").
+		Build()
+
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "ordered"), false)
+	st.Expect(t, ran, []string{"authenticated", "authorized"})
+
+	_, err = fsm.NewBuilder().
+		Guard("a", guard("a", true)).
+		Guard("b", guard("b", true)).
+		Depends("a", "b").
+		Depends("b", "a").
+		Transition(fsm.T{"cart", "ordered"}, "a").
+		Build()
+
+	st.Expect(t, errors.Is(err, fsm.ErrGuardCycle), true)
+	st.Expect(t, err.Error(), "guard dependency cycle: a -> b -> a")
+}
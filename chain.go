@@ -0,0 +1,102 @@
+package fsm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+var ErrChainBroken = errors.New("hash chain broken")
+
+// hashChain links each event of a machine to the one before it. A nil
+// *hashChain, as found in a Machine without WithHashChain, leaves events
+// alone.
+type hashChain struct {
+	prev string
+}
+
+// WithHashChain is intended to be passed to New to make the machine's
+// events and History tamper evident: each event carries the hash of the
+// event before it, and its own hash covers that. VerifyChain checks an
+// exported log.
+func WithHashChain() func(*Machine) {
+	return func(m *Machine) {
+		m.chain = &hashChain{}
+	}
+}
+
+func (c *hashChain) link(e *TransitionEvent) {
+	if c == nil {
+		return
+	}
+	e.Prev = c.prev
+	e.Hash = ""
+	e.Hash = hashEvent(*e)
+}
+
+func (c *hashChain) advance(e TransitionEvent) {
+	if c == nil {
+		return
+	}
+	c.prev = e.Hash
+}
+
+func (c *hashChain) head() string {
+	if c == nil {
+		return ""
+	}
+	return c.prev
+}
+
+func (c *hashChain) restore(hash string) {
+	if c == nil {
+		return
+	}
+	c.prev = hash
+}
+
+// hashEvent hashes the canonical JSON encoding of e, without its Hash. The
+// encoding is decoded and encoded again so that a payload hashes the same
+// before and after a trip through JSON.
+func hashEvent(e TransitionEvent) string {
+	e.Hash = ""
+	raw, err := json.Marshal(e)
+	if err == nil {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		if err = d.Decode(&v); err == nil {
+			raw, err = json.Marshal(v)
+		}
+	}
+	if err != nil {
+		// unencodable payloads still get a hash, of what can be encoded
+		e.Payload = fmt.Sprintf("%#v", e.Payload)
+		return hashEvent(e)
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyChain checks that events, in order, form an unbroken hash chain:
+// that no event was changed, and none removed or inserted between the first
+// and the last. The first event may link to one that was compacted away.
+func VerifyChain(events iter.Seq[TransitionEvent]) error {
+	var prev *TransitionEvent
+	for e := range events {
+		if e.Hash == "" || e.Hash != hashEvent(e) {
+			return fmt.Errorf("%w: event %d was modified", ErrChainBroken, e.Seq)
+		}
+		if prev != nil && e.Prev != prev.Hash {
+			return fmt.Errorf("%w: event %d doesn't follow event %d", ErrChainBroken, e.Seq, prev.Seq)
+		}
+		prev = &e
+	}
+
+	return nil
+}
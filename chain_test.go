@@ -0,0 +1,79 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type ticket struct {
+	Reason string `json:"reason"`
+	By     string `json:"by"`
+}
+
+func TestHashChain(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithHistory(),
+		fsm.WithHashChain(),
+	)
+	for i := 0; i < 3; i++ {
+		st.Expect(t, the_machine.TransitionAs("alice", "started", ticket{"go", "alice"}), nil)
+		st.Expect(t, the_machine.TransitionAs("bob", "pending", nil), nil)
+	}
+
+	history := the_machine.History()
+	st.Expect(t, fsm.VerifyChain(slices.Values(history)), nil)
+	st.Expect(t, history[1].Prev, history[0].Hash)
+
+	// the exported log still verifies once payloads are plain JSON
+	raw, err := json.Marshal(history)
+	st.Expect(t, err, nil)
+	var exported []fsm.TransitionEvent
+	st.Expect(t, json.Unmarshal(raw, &exported), nil)
+	st.Expect(t, fsm.VerifyChain(slices.Values(exported)), nil)
+
+	tampered := slices.Clone(history)
+	tampered[2].Actor = "mallory"
+	st.Expect(t, errors.Is(fsm.VerifyChain(slices.Values(tampered)), fsm.ErrChainBroken), true)
+
+	removed := slices.Delete(slices.Clone(history), 2, 3)
+	st.Expect(t, errors.Is(fsm.VerifyChain(slices.Values(removed)), fsm.ErrChainBroken), true)
+}
+
+func TestHashChainContinuesAfterRestore(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	store := &fsm.MemoryEventStore{}
+	opts := func(s fsm.Stater) []func(*fsm.Machine) {
+		return []func(*fsm.Machine){fsm.WithRules(rules), fsm.WithSubject(s), fsm.WithEventStore(store, 2), fsm.WithHashChain()}
+	}
+
+	the_machine := fsm.New(opts(&Thing{State: "pending"})...)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("pending"), nil)
+	st.Expect(t, the_machine.Transition("started"), nil)
+
+	restored := fsm.New(opts(&Thing{})...)
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, restored.Transition("pending"), nil)
+
+	var events []fsm.TransitionEvent
+	for e, err := range store.Events(0) {
+		st.Expect(t, err, nil)
+		events = append(events, e)
+	}
+	st.Expect(t, len(events), 4)
+	st.Expect(t, fsm.VerifyChain(slices.Values(events)), nil)
+}
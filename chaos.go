@@ -0,0 +1,77 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrChaos marks failures injected by WithChaos.
+var ErrChaos = errors.New("chaos")
+
+// Chaos sets how often WithChaos injects each kind of failure, as a
+// probability from 0 to 1 per transition.
+type Chaos struct {
+	Seed uint64 // makes the injected failures repeatable
+
+	GuardFailure     float64 // a permitted transition is rejected
+	PersistenceError float64 // recording the event fails
+	HookPanic        float64 // a hook panics
+	Latency          float64 // guards take Delay longer
+	Delay            time.Duration
+}
+
+// chaos injects failures into transitions. A nil *chaos, as found in a
+// Machine without WithChaos, injects none.
+type chaos struct {
+	Chaos
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// WithChaos is intended to be passed to New in tests, to check that an
+// application copes with every way a transition can fail. Injected errors
+// wrap ErrChaos, and injected panics panic with it.
+func WithChaos(c Chaos) func(*Machine) {
+	return func(m *Machine) {
+		m.chaos = &chaos{Chaos: c, rng: rand.New(rand.NewPCG(c.Seed, c.Seed))}
+	}
+}
+
+// roll reports whether a failure of probability p happens.
+func (c *chaos) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
+
+func (c *chaos) delay() {
+	if c != nil && c.roll(c.Latency) {
+		time.Sleep(c.Delay)
+	}
+}
+
+func (c *chaos) guard(tc TransitionContext) error {
+	if c != nil && c.roll(c.GuardFailure) {
+		return fmt.Errorf("%w: %w: guard of %s -> %s failed", ErrInvalidTransition, ErrChaos, tc.Origin, tc.Goal)
+	}
+	return nil
+}
+
+func (c *chaos) persistence() error {
+	if c != nil && c.roll(c.PersistenceError) {
+		return fmt.Errorf("%w: event store unavailable", ErrChaos)
+	}
+	return nil
+}
+
+func (c *chaos) hook() {
+	if c != nil && c.roll(c.HookPanic) {
+		panic(fmt.Errorf("%w: hook panicked", ErrChaos))
+	}
+}
@@ -0,0 +1,73 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestChaos(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+
+	run := func(seed uint64) (failures map[string]int, trail []fsm.State) {
+		failures = map[string]int{}
+		some_thing := Thing{State: "pending"}
+		the_machine := fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&some_thing),
+			fsm.WithEventStore(&fsm.MemoryEventStore{}, 0),
+			fsm.WithHooks(func(tc fsm.TransitionContext) {}),
+			fsm.WithChaos(fsm.Chaos{
+				Seed:             seed,
+				GuardFailure:     0.2,
+				PersistenceError: 0.2,
+				HookPanic:        0.2,
+				Latency:          0.1,
+				Delay:            time.Microsecond,
+			}),
+		)
+
+		for range 200 {
+			goal := fsm.State("started")
+			if some_thing.State == "started" {
+				goal = "pending"
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						st.Expect(t, errors.Is(r.(error), fsm.ErrChaos), true)
+						failures["panic"]++
+					}
+				}()
+
+				err := the_machine.Transition(goal)
+				switch {
+				case err == nil:
+				case errors.Is(err, fsm.ErrInvalidTransition):
+					st.Expect(t, errors.Is(err, fsm.ErrChaos), true)
+					failures["guard"]++
+				case errors.Is(err, fsm.ErrChaos):
+					failures["persistence"]++
+				}
+			}()
+			trail = append(trail, some_thing.State)
+		}
+		return failures, trail
+	}
+
+	failures, trail := run(42)
+	for _, kind := range []string{"guard", "persistence", "panic"} {
+		st.Expect(t, failures[kind] > 10, true)
+	}
+
+	again, retrail := run(42)
+	st.Expect(t, again, failures)
+	st.Expect(t, retrail, trail)
+}
@@ -0,0 +1,48 @@
+package fsm
+
+// Check is a guard that sees the whole TransitionContext, payload included,
+// and explains a rejection with an error.
+type Check func(tc TransitionContext) error
+
+// WithChecks is intended to be passed to New to protect the transition t
+// with checks. They run after the Rules have permitted t, in order, and the
+// first error stops the transition.
+func WithChecks(t Transition, checks ...Check) func(*Machine) {
+	return func(m *Machine) {
+		if m.checks == nil {
+			m.checks = map[T][]Check{}
+		}
+		for _, t := range expand(t) {
+			key := T{t.Origin(), t.Exit()}
+			m.checks[key] = append(m.checks[key], checks...)
+		}
+	}
+}
+
+// WithPolicies is intended to be passed to New to protect every transition
+// of the machine with checks. They run after the checks of the transition
+// itself.
+func WithPolicies(checks ...Check) func(*Machine) {
+	return func(m *Machine) {
+		m.policies = append(m.policies, checks...)
+	}
+}
+
+func (m Machine) check(tc TransitionContext) error {
+	if len(m.checks) > 0 {
+		// those of the states the origin is nested within apply too
+		for _, s := range m.hierarchy.lineage(tc.Origin) {
+			for _, check := range m.checks[T{s, tc.Goal}] {
+				if err := check(tc); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, check := range m.policies {
+		if err := check(tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package fsm
+
+import "time"
+
+// Clock tells the time. Machines and time-based guards take one so tests
+// and simulations can control the time they see.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock of the system: time.Now.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+// WithClock is intended to be passed to New to set the Clock used to time
+// stamp the machine's events. It defaults to SystemClock.
+func WithClock(c Clock) func(*Machine) {
+	return func(m *Machine) {
+		m.clock = c
+	}
+}
+
+func (m Machine) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
@@ -0,0 +1,227 @@
+// Command fsmdebug steps forwards and backwards through the recorded events
+// of a machine, showing at each step the state it was in, the event about
+// to be applied with its payload, and whether the rules of a Definition
+// would permit that event today:
+//
+//	fsmdebug [-rules definition.json] [-param name=value]... [-guard name=false]... events.ndjson
+//
+// events.ndjson holds one TransitionEvent per line, as read by
+// Machine.ReplayJSON. Commands are read from standard input:
+//
+//	n, or an empty line  step forward
+//	b                    step back
+//	g N                  go to the position after N events
+//	q                    quit
+//
+// The guards named by a definition are registered by the program that uses
+// it, so fsmdebug can't run them: each is taken to pass unless it is named
+// with -guard name=false. Scripts are run with an fsm.ExprEngine, which
+// only sees the state, and feature flags are guards like any other, named
+// "flag:name".
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "fsmdebug:", err)
+		os.Exit(1)
+	}
+}
+
+// pairs collects repeated name=value flags.
+type pairs map[string]string
+
+func (p pairs) String() string { return fmt.Sprint(map[string]string(p)) }
+
+func (p pairs) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("%q is not name=value", s)
+	}
+	p[name] = value
+	return nil
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("fsmdebug", flag.ContinueOnError)
+	flags.SetOutput(out)
+	definition := flags.String("rules", "", "definition of the rules to check events against")
+	params, outcomes := pairs{}, pairs{}
+	flags.Var(params, "param", "value of a definition parameter, as name=value")
+	flags.Var(outcomes, "guard", "outcome of a guard of the definition, as name=false")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: fsmdebug [-rules definition.json] [-param name=value]... [-guard name=false]... events.ndjson")
+	}
+
+	rules := fsm.Ruleset{}
+	if *definition != "" {
+		var err error
+		if rules, err = load(*definition, params, outcomes); err != nil {
+			return err
+		}
+	}
+
+	events, err := read(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	m := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&state{}))
+	d := m.Debug(func(yield func(fsm.TransitionEvent) bool) {
+		for _, e := range events {
+			if !yield(e) {
+				return
+			}
+		}
+	})
+	show(out, d.Frame(), d.Len(), *definition != "")
+
+	commands := bufio.NewScanner(in)
+	for commands.Scan() {
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(commands.Text()), " ")
+		var f fsm.Frame
+		switch cmd {
+		case "", "n":
+			f, _ = d.Step()
+		case "b":
+			f, _ = d.Back()
+		case "g":
+			i, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil {
+				fmt.Fprintln(out, "g needs a position")
+				continue
+			}
+			f = d.Seek(i)
+		case "q":
+			return nil
+		default:
+			fmt.Fprintf(out, "unknown command %q: n, b, g N or q\n", cmd)
+			continue
+		}
+		show(out, f, d.Len(), *definition != "")
+	}
+	return commands.Err()
+}
+
+// read decodes the events of the file at path.
+func read(path string) ([]fsm.TransitionEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []fsm.TransitionEvent
+	dec := json.NewDecoder(f)
+	for {
+		var e fsm.TransitionEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, e)
+	}
+}
+
+var paramRef = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// load builds the ruleset of the definition at path, standing in for its
+// guards with the given outcomes.
+func load(path string, params, outcomes map[string]string) (fsm.Ruleset, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var def fsm.Definition
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	expand := func(s string) string {
+		return paramRef.ReplaceAllStringFunc(s, func(ref string) string {
+			name := paramRef.FindStringSubmatch(ref)[1]
+			if v, ok := params[name]; ok {
+				return v
+			}
+			if p, ok := def.Params[name]; ok && p.Default != nil {
+				return *p.Default
+			}
+			return ref
+		})
+	}
+
+	var names []string
+	for _, t := range def.Transitions {
+		if t.Flag != "" {
+			names = append(names, "flag:"+t.Flag)
+		}
+		names = append(names, t.Guards...)
+	}
+	for name, on := range def.Depends {
+		names = append(append(names, name), on...)
+	}
+
+	b := fsm.NewBuilder().Scripts(fsm.ExprEngine{})
+	stubbed := map[string]bool{}
+	for _, name := range names {
+		name = expand(name)
+		if stubbed[name] || strings.HasPrefix(name, "script:") {
+			continue
+		}
+		stubbed[name] = true
+		pass := outcomes[name] != "false"
+		b.Guard(name, func(fsm.Stater, fsm.State) bool { return pass })
+	}
+
+	return b.LoadJSONWithParams(strings.NewReader(string(raw)), params).Build()
+}
+
+// show prints f, of a debugger over n events.
+func show(w io.Writer, f fsm.Frame, n int, checked bool) {
+	fmt.Fprintf(w, "[%d/%d] %s\n", f.Index, n, f.State)
+	if f.Next == nil {
+		fmt.Fprintln(w, "  end of events")
+		return
+	}
+
+	e := f.Next
+	fmt.Fprintf(w, "  next #%d %s -> %s", e.Seq, e.Origin, e.Goal)
+	if e.Actor != "" {
+		fmt.Fprintf(w, " by %s", e.Actor)
+	}
+	fmt.Fprintf(w, " at %s\n", e.Time.Format("2006-01-02T15:04:05Z07:00"))
+	if e.Payload != nil {
+		payload, _ := json.Marshal(e.Payload)
+		fmt.Fprintf(w, "  payload %s\n", payload)
+	}
+	if checked {
+		fmt.Fprintf(w, "  permitted %t, guards %v\n", f.Permitted, f.Guards)
+	}
+	if f.Err != nil {
+		fmt.Fprintf(w, "  %v\n", f.Err)
+	}
+}
+
+// state is the subject being debugged: nothing but the state it is in.
+type state struct{ s fsm.State }
+
+func (s *state) CurrentState() fsm.State { return s.s }
+func (s *state) SetState(g fsm.State)    { s.s = g }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	events := filepath.Join(dir, "events.ndjson")
+	definition := filepath.Join(dir, "definition.json")
+	st.Expect(t, os.WriteFile(events, []byte(
+		`{"seq":1,"origin":"pending","goal":"started","actor":"ann","payload":{"card":"4242"}}
+{"seq":2,"origin":"started","goal":"finished"}
+`), 0o600), nil)
+	st.Expect(t, os.WriteFile(definition, []byte(`{
+	"transitions": [
+		{"from": "pending", "to": "started"},
+		{"from": "started", "to": "finished", "guards": ["paid"]}
+	]
+}`), 0o600), nil)
+
+	var out strings.Builder
+	err := run([]string{"-rules", definition, "-guard", "paid=false", events}, strings.NewReader("n\nn\nb\ng 0\nq\n"), &out)
+	st.Expect(t, err, nil)
+
+	lines := strings.Split(out.String(), "\n")
+	st.Expect(t, lines[0], "[0/2] pending")
+	st.Expect(t, strings.HasPrefix(lines[1], "  next #1 pending -> started by ann"), true)
+	st.Expect(t, lines[2], `  payload {"card":"4242"}`)
+	st.Expect(t, lines[3], "  permitted true, guards []")
+	st.Expect(t, lines[4], "[1/2] started")
+	st.Expect(t, strings.Contains(out.String(), "  permitted false, guards [false]"), true)
+	st.Expect(t, strings.Contains(out.String(), "[2/2] finished\n  end of events"), true)
+	st.Expect(t, strings.Count(out.String(), "[0/2] pending"), 2)
+}
+
+func TestRunUsage(t *testing.T) {
+	var out strings.Builder
+	st.Expect(t, run(nil, strings.NewReader(""), &out) != nil, true)
+}
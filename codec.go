@@ -0,0 +1,234 @@
+package fsm
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+var (
+	ErrUnknownCodec  = errors.New("unknown codec")
+	ErrNotCompressed = errors.New("not stored by a CompressedStore")
+)
+
+// Codec compresses the encoded events and snapshots that a store persists
+// as bytes. Event logs are repetitive JSON and compress well. ID tells
+// codecs apart in what they encoded, so it must be unique and never '{'.
+// This package provides Flate; the codecs package adds Snappy and Zstd.
+type Codec interface {
+	ID() byte
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// Flate is a Codec that compresses with DEFLATE at the given level, from
+// flate.BestSpeed to flate.BestCompression. Its ID is 'f'.
+type Flate int
+
+func (f Flate) ID() byte { return 'f' }
+
+func (f Flate) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, int(f))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f Flate) Decompress(data []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+}
+
+// EncodeEvent encodes e as JSON compressed by c, prefixed by c's ID. A nil
+// c leaves the JSON as it is.
+func EncodeEvent(c Codec, e TransitionEvent) ([]byte, error) {
+	return encode(c, e)
+}
+
+// DecodeEvent decodes an event encoded by EncodeEvent with any of codecs,
+// or not compressed at all, so that a store can change codecs without
+// rewriting what it already holds.
+func DecodeEvent(data []byte, codecs ...Codec) (TransitionEvent, error) {
+	var e TransitionEvent
+	return e, decode(data, &e, codecs)
+}
+
+// EncodeSnapshot is EncodeEvent for snapshots.
+func EncodeSnapshot(c Codec, s Snapshot) ([]byte, error) {
+	return encode(c, s)
+}
+
+// DecodeSnapshot is DecodeEvent for snapshots.
+func DecodeSnapshot(data []byte, codecs ...Codec) (Snapshot, error) {
+	var s Snapshot
+	return s, decode(data, &s, codecs)
+}
+
+func encode(c Codec, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || c == nil {
+		return data, err
+	}
+	compressed, err := c.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{c.ID()}, compressed...), nil
+}
+
+func decode(data []byte, v interface{}, codecs []Codec) error {
+	if len(data) > 0 && data[0] != '{' {
+		var codec Codec
+		for _, c := range codecs {
+			if c.ID() == data[0] {
+				codec = c
+			}
+		}
+		if codec == nil {
+			return fmt.Errorf("%w: %q", ErrUnknownCodec, data[0])
+		}
+
+		var err error
+		if data, err = codec.Decompress(data[1:]); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// CompressedEvent stands in for an event stored by a CompressedStore: the
+// event, encoded by EncodeEvent.
+type CompressedEvent struct {
+	Data []byte `json:"compressed"`
+}
+
+// CompressedStore is an EventStore that compresses events and snapshots
+// with Codec before handing them to Store, for stores that keep them as
+// bytes. Events are handed over with only their Seq in the clear and the
+// rest in a CompressedEvent payload; snapshots with their Seq, and the rest
+// Sealed. Events and snapshots compressed by any of Decoders, or not at
+// all, are read back too, so that Codec can be changed without rewriting
+// what Store already holds.
+//
+// Wrap an EncryptedStore, rather than the other way around, to compress
+// payloads before they are encrypted. A CompressedStore passes on the
+// context of AppendContext to Store if it is a ContextAppender, and can be
+// compacted if Store is a Compacter.
+type CompressedStore struct {
+	Store    EventStore
+	Codec    Codec
+	Decoders []Codec
+}
+
+func (s *CompressedStore) Append(e TransitionEvent) error {
+	e, err := s.compress(e)
+	if err != nil {
+		return err
+	}
+	return s.Store.Append(e)
+}
+
+func (s *CompressedStore) AppendContext(ctx context.Context, e TransitionEvent) error {
+	e, err := s.compress(e)
+	if err != nil {
+		return err
+	}
+	if a, ok := s.Store.(ContextAppender); ok {
+		return a.AppendContext(ctx, e)
+	}
+	return s.Store.Append(e)
+}
+
+// Truncate discards events of Store, or returns ErrNotCompactable if it
+// isn't a Compacter.
+func (s *CompressedStore) Truncate(through uint64) error {
+	c, ok := s.Store.(Compacter)
+	if !ok {
+		return ErrNotCompactable
+	}
+	return c.Truncate(through)
+}
+
+func (s *CompressedStore) compactable() bool { return compactable(s.Store) }
+
+func (s *CompressedStore) compress(e TransitionEvent) (TransitionEvent, error) {
+	data, err := EncodeEvent(s.Codec, e)
+	if err != nil {
+		return e, err
+	}
+	return TransitionEvent{Seq: e.Seq, Payload: CompressedEvent{Data: data}}, nil
+}
+
+func (s *CompressedStore) Events(after uint64) iter.Seq2[TransitionEvent, error] {
+	return func(yield func(TransitionEvent, error) bool) {
+		for e, err := range s.Store.Events(after) {
+			if err == nil {
+				e, err = s.decompress(e)
+			}
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *CompressedStore) decompress(e TransitionEvent) (TransitionEvent, error) {
+	var compressed CompressedEvent
+	switch p := e.Payload.(type) {
+	case CompressedEvent:
+		compressed = p
+	default:
+		// as decoded by a store that keeps events as JSON
+		raw, err := json.Marshal(p)
+		if err == nil {
+			err = json.Unmarshal(raw, &compressed)
+		}
+		if err != nil || compressed.Data == nil {
+			return e, fmt.Errorf("%w: event %d", ErrNotCompressed, e.Seq)
+		}
+	}
+	return DecodeEvent(compressed.Data, s.codecs()...)
+}
+
+func (s *CompressedStore) SaveSnapshot(snap Snapshot) error {
+	data, err := EncodeSnapshot(s.Codec, snap)
+	if err != nil {
+		return err
+	}
+	return s.Store.SaveSnapshot(Snapshot{Seq: snap.Seq, Sealed: data})
+}
+
+func (s *CompressedStore) LatestSnapshot() (Snapshot, bool, error) {
+	sealed, ok, err := s.Store.LatestSnapshot()
+	if err != nil || !ok {
+		return sealed, ok, err
+	}
+	if sealed.Sealed == nil {
+		return Snapshot{}, false, fmt.Errorf("%w: snapshot %d", ErrNotCompressed, sealed.Seq)
+	}
+	snap, err := DecodeSnapshot(sealed.Sealed, s.codecs()...)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	return snap, true, nil
+}
+
+// codecs returns every codec the store reads.
+func (s *CompressedStore) codecs() []Codec {
+	if s.Codec == nil {
+		return s.Decoders
+	}
+	return append([]Codec{s.Codec}, s.Decoders...)
+}
@@ -0,0 +1,93 @@
+package fsm_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestCodec(t *testing.T) {
+	e := fsm.TransitionEvent{
+		Seq:     1,
+		Origin:  "pending",
+		Goal:    "started",
+		Time:    time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		Payload: map[string]interface{}{"note": "the same words, the same words, the same words"},
+	}
+
+	plain, err := fsm.EncodeEvent(nil, e)
+	st.Expect(t, err, nil)
+	compressed, err := fsm.EncodeEvent(fsm.Flate(flate.BestCompression), e)
+	st.Expect(t, err, nil)
+	st.Expect(t, compressed[0], byte('f'))
+	st.Expect(t, len(compressed) < len(plain), true)
+
+	for i, data := range [][]byte{plain, compressed} {
+		decoded, err := fsm.DecodeEvent(data, fsm.Flate(0))
+		st.Expect(t, err, nil, i)
+		st.Expect(t, decoded, e, i)
+	}
+
+	_, err = fsm.DecodeEvent(compressed)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownCodec), true)
+
+	snap := fsm.Snapshot{Seq: 1, State: "started", Time: e.Time}
+	data, err := fsm.EncodeSnapshot(fsm.Flate(flate.BestSpeed), snap)
+	st.Expect(t, err, nil)
+	decoded, err := fsm.DecodeSnapshot(data, fsm.Flate(0))
+	st.Expect(t, err, nil)
+	st.Expect(t, decoded, snap)
+}
+
+func TestCompressedStore(t *testing.T) {
+	inner := &fsm.MemoryEventStore{}
+	encrypted := &fsm.EncryptedStore{Store: inner, Encrypter: fsm.AESEncrypter{Key: bytes.Repeat([]byte{7}, 32)}}
+	store := &fsm.CompressedStore{Store: encrypted, Codec: fsm.Flate(flate.BestSpeed)}
+	rules := fsm.CreateRuleset(fsm.T{O: "pending", E: "started"}, fsm.T{O: "started", E: "finished"})
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 1),
+		fsm.WithRetention(fsm.Retention{MaxEntries: 1}),
+	)
+	st.Expect(t, the_machine.TransitionWith("started", map[string]string{"note": "fragile"}), nil)
+	st.Expect(t, the_machine.Transition("finished"), nil)
+
+	// compressed, then encrypted, and compacted through both
+	for e, err := range inner.Events(0) {
+		st.Expect(t, err, nil)
+		st.Expect(t, e.Seq, uint64(2))
+		st.Expect(t, e.Goal, fsm.State(""))
+	}
+	var goals []fsm.State
+	for e, err := range store.Events(0) {
+		st.Expect(t, err, nil)
+		goals = append(goals, e.Goal)
+	}
+	st.Expect(t, goals, []fsm.State{"finished"})
+
+	other_thing := Thing{State: "pending"}
+	restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing), fsm.WithEventStore(store, 0))
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, other_thing.State, fsm.State("finished"))
+
+	// a store can change codecs and still read what it wrote before
+	changed := &fsm.CompressedStore{Store: encrypted, Decoders: []fsm.Codec{fsm.Flate(0)}}
+	snap, ok, err := changed.LatestSnapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	st.Expect(t, snap.State, fsm.State("finished"))
+
+	plain := &fsm.CompressedStore{Store: &fsm.MemoryEventStore{}}
+	st.Expect(t, plain.Store.Append(fsm.TransitionEvent{Seq: 1, Goal: "started"}), nil)
+	for _, err := range plain.Events(0) {
+		st.Expect(t, errors.Is(err, fsm.ErrNotCompressed), true)
+	}
+}
@@ -0,0 +1,88 @@
+// Package codecs provides fsm Codecs for compression formats the standard
+// library lacks, kept apart so that programs which don't use them needn't
+// build their implementations.
+package codecs
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+var (
+	_ fsm.Codec = Snappy{}
+	_ fsm.Codec = Zstd(0)
+)
+
+// Snappy is a Codec that compresses with Snappy, which is very fast but
+// compresses less than the others. Its ID is 's'.
+type Snappy struct{}
+
+func (Snappy) ID() byte { return 's' }
+
+func (Snappy) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (Snappy) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+// Zstd is a Codec that compresses with Zstandard at the given level, from
+// 1 for the fastest to 22 for the smallest, with 0 meaning the default.
+// Its ID is 'z'.
+type Zstd int
+
+func (z Zstd) ID() byte { return 'z' }
+
+func (z Zstd) Compress(data []byte) ([]byte, error) {
+	enc, err := z.encoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(data, nil), nil
+}
+
+func (z Zstd) Decompress(data []byte) ([]byte, error) {
+	dec, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(data, nil)
+}
+
+// Encoders and decoders are costly to make but safe to share, so one
+// encoder is kept for each level in use, and a single decoder.
+var (
+	encoders sync.Map // Zstd to *zstd.Encoder
+
+	decoderOnce sync.Once
+	decoder     *zstd.Decoder
+	decoderErr  error
+)
+
+func (z Zstd) encoder() (*zstd.Encoder, error) {
+	if enc, ok := encoders.Load(z); ok {
+		return enc.(*zstd.Encoder), nil
+	}
+
+	level := zstd.SpeedDefault
+	if z > 0 {
+		level = zstd.EncoderLevelFromZstd(int(z))
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	shared, _ := encoders.LoadOrStore(z, enc)
+	return shared.(*zstd.Encoder), nil
+}
+
+func zstdDecoder() (*zstd.Decoder, error) {
+	decoderOnce.Do(func() {
+		decoder, decoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	return decoder, decoderErr
+}
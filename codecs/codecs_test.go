@@ -0,0 +1,48 @@
+package codecs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/codecs"
+)
+
+type thing struct{ state fsm.State }
+
+func (t *thing) CurrentState() fsm.State  { return t.state }
+func (t *thing) SetState(state fsm.State) { t.state = state }
+
+func TestCodecs(t *testing.T) {
+	note := string(bytes.Repeat([]byte("the same words, "), 20))
+	e := fsm.TransitionEvent{Seq: 1, Origin: "pending", Goal: "started", Payload: map[string]interface{}{"note": note}}
+	plain, _ := fsm.EncodeEvent(nil, e)
+
+	for i, codec := range []fsm.Codec{codecs.Snappy{}, codecs.Zstd(0), codecs.Zstd(19)} {
+		data, err := fsm.EncodeEvent(codec, e)
+		st.Expect(t, err, nil, i)
+		st.Expect(t, data[0], codec.ID(), i)
+		st.Expect(t, len(data) < len(plain), true, i)
+
+		decoded, err := fsm.DecodeEvent(data, codecs.Snappy{}, codecs.Zstd(0))
+		st.Expect(t, err, nil, i)
+		st.Expect(t, decoded, e, i)
+	}
+}
+
+func TestCompressedStore(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{O: "pending", E: "started"}, fsm.T{O: "started", E: "finished"})
+
+	for i, codec := range []fsm.Codec{codecs.Snappy{}, codecs.Zstd(3)} {
+		store := &fsm.CompressedStore{Store: &fsm.MemoryEventStore{}, Codec: codec}
+		the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&thing{"pending"}), fsm.WithEventStore(store, 1))
+		st.Expect(t, the_machine.TransitionWith("started", map[string]string{"note": "fragile"}), nil, i)
+		st.Expect(t, the_machine.Transition("finished"), nil, i)
+
+		some_thing := &thing{"pending"}
+		restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(some_thing), fsm.WithEventStore(store, 0))
+		st.Expect(t, restored.Restore(), nil, i)
+		st.Expect(t, some_thing.state, fsm.State("finished"), i)
+	}
+}
@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Collector evicts machines from a Registry once they have sat in a final
+// state for longer than TTL, so long-running registries don't grow
+// forever. Final states are those declared WithFinalStates on each
+// machine, plus any in Final.
+type Collector struct {
+	Registry *Registry
+	TTL      time.Duration
+	Final    StateSet // final for every machine
+	Clock    Clock    // defaults to SystemClock
+
+	// Archive, if set, is called before a machine is evicted, such as to
+	// save it elsewhere. A machine it fails for is kept, to be tried again
+	// by the next collection. Other calls for machines in the same shard
+	// wait for it, so it should be quick.
+	Archive func(key string, m Machine) error
+
+	// OnEvict is called after a machine has been evicted.
+	OnEvict func(key string, m Machine)
+}
+
+// Collect evicts the machines that have expired and returns their keys,
+// sorted, along with the first error Archive returned.
+func (c *Collector) Collect() ([]string, error) {
+	clock := c.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+
+	expired := func(m Machine) bool {
+		state := m.Subject.CurrentState()
+		final := c.Final.Contains(state) || StateSet(m.finals).Contains(state)
+		return final && now.Sub(m.Since()) > c.TTL
+	}
+
+	var (
+		evicted []string
+		first   error
+	)
+	for i := range c.Registry.shards {
+		for _, key := range c.Registry.shards[i].keys() {
+			m, err := c.Registry.evict(key, func(m Machine) (bool, error) {
+				if !expired(m) {
+					return false, nil
+				}
+				if c.Archive != nil {
+					if err := c.Archive(key, m); err != nil {
+						return false, err
+					}
+				}
+				return true, nil
+			})
+			if err != nil && first == nil {
+				first = err
+			}
+			if m == nil {
+				continue
+			}
+
+			evicted = append(evicted, key)
+			if c.OnEvict != nil {
+				c.OnEvict(key, *m)
+			}
+		}
+	}
+	sort.Strings(evicted)
+
+	return evicted, first
+}
+
+// Run collects every interval until ctx is done, and returns ctx's error.
+// Errors from Archive are passed to onError, if not nil.
+func (c *Collector) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Collect(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// evict deletes the machine stored under key if decide says so, with no
+// transition of it in progress, and returns it when it was deleted.
+func (r *Registry) evict(key string, decide func(m Machine) (bool, error)) (*Machine, error) {
+	s := r.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if evict, err := decide(e.machine); !evict {
+		return nil, err
+	}
+	s.remove(key)
+	return &e.machine, nil
+}
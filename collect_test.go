@@ -0,0 +1,63 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestCollector(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := fsm.ClockFunc(func() time.Time { return now })
+	rules := fsm.CreateRuleset(fsm.T{"pending", "done"}, fsm.T{"pending", "cancelled"})
+
+	registry := fsm.NewRegistry()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		registry.Put(key, fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithClock(clock),
+			fsm.WithFinalStates("done"),
+		))
+	}
+
+	st.Expect(t, registry.Transition("a", "done"), nil)
+	st.Expect(t, registry.Transition("b", "cancelled"), nil)
+	st.Expect(t, registry.Transition("c", "done"), nil)
+	now = now.Add(2 * time.Hour)
+
+	archiveDown := true
+	var archived, evicted []string
+	collector := &fsm.Collector{
+		Registry: registry,
+		TTL:      time.Hour,
+		Final:    fsm.StateSet{"cancelled"},
+		Clock:    clock,
+		Archive: func(key string, m fsm.Machine) error {
+			if key == "c" && archiveDown {
+				return errors.New("archive unavailable")
+			}
+			archived = append(archived, key)
+			return nil
+		},
+		OnEvict: func(key string, m fsm.Machine) { evicted = append(evicted, key) },
+	}
+
+	keys, err := collector.Collect()
+	st.Expect(t, err.Error(), "archive unavailable")
+	st.Expect(t, keys, []string{"a", "b"})
+	st.Expect(t, len(evicted), 2)
+	st.Expect(t, registry.Len(), 2)
+
+	archiveDown = false
+	keys, err = collector.Collect()
+	st.Expect(t, err, nil)
+	st.Expect(t, keys, []string{"c"})
+	st.Expect(t, len(archived), 3)
+
+	_, ok := registry.Get("d")
+	st.Expect(t, ok, true)
+}
@@ -0,0 +1,212 @@
+package fsm
+
+import (
+	"iter"
+	"slices"
+	"sort"
+	"unique"
+)
+
+// Permitter decides whether a subject may move to the goal State. Both
+// Ruleset and CompiledRuleset are Permitters.
+type Permitter interface {
+	Permitted(subject Stater, goal State) bool
+}
+
+// CompiledRuleset is an immutable form of a Ruleset tuned for lookups.
+// States are interned to small integers, and the transitions are indexed by
+// origin. For rulesets of up to denseStates states the permitted exits of
+// each origin are also kept in a bitset, so invalid transitions are rejected
+// without hashing a Transition.
+type CompiledRuleset struct {
+	ids     map[State]int
+	states  []State
+	handles []unique.Handle[State] // keep the interned states alive
+	exits   bitset                 // origin*len(states)+goal, or nil when too large
+	edges   [][]edge
+}
+
+// denseStates caps the size of the exit bitset, which grows with the square
+// of the number of states (2MiB at this limit).
+const denseStates = 1 << 12
+
+type edge struct {
+	goal   int
+	guards []Guard
+}
+
+// Compile creates a CompiledRuleset from r. Later changes to r are not
+// reflected in the result.
+func (r Ruleset) Compile() *CompiledRuleset {
+	c := &CompiledRuleset{ids: make(map[State]int, len(r))}
+
+	for t := range r {
+		c.intern(t.Origin())
+		c.intern(t.Exit())
+	}
+
+	// carve every origin's edges out of a single allocation
+	n := len(c.states)
+	counts := make([]int, n)
+	for t := range r {
+		counts[c.ids[t.Origin()]]++
+	}
+	backing := make([]edge, len(r))
+	c.edges = make([][]edge, n)
+	for o, off := 0, 0; o < n; o++ {
+		c.edges[o] = backing[off : off : off+counts[o]]
+		off += counts[o]
+	}
+
+	if n <= denseStates {
+		c.exits = newBitset(n * n)
+	}
+
+	for t, guards := range r {
+		o, g := c.ids[t.Origin()], c.ids[t.Exit()]
+		e := c.edge(o, g)
+		if e == nil {
+			if c.exits != nil {
+				c.exits.set(o*n + g)
+			}
+			c.edges[o] = append(c.edges[o], edge{goal: g})
+			e = &c.edges[o][len(c.edges[o])-1]
+		}
+		e.guards = append(e.guards, guards...)
+	}
+
+	for _, edges := range c.edges {
+		slices.SortFunc(edges, func(a, b edge) int { return a.goal - b.goal })
+	}
+
+	return c
+}
+
+func (c *CompiledRuleset) intern(s State) int {
+	if id, ok := c.ids[s]; ok {
+		return id
+	}
+	h := unique.Make(s)
+	s = h.Value()
+	id := len(c.states)
+	c.ids[s] = id
+	c.states = append(c.states, s)
+	c.handles = append(c.handles, h)
+	return id
+}
+
+// Handle returns the handle of s, if it is known to the ruleset. Two
+// handles are equal exactly when their States are, and comparing them is a
+// single pointer comparison.
+func (c *CompiledRuleset) Handle(s State) (unique.Handle[State], bool) {
+	id, ok := c.ids[s]
+	if !ok {
+		return unique.Handle[State]{}, false
+	}
+	return c.handles[id], true
+}
+
+func (c *CompiledRuleset) edge(origin, goal int) *edge {
+	edges := c.edges[origin]
+	for i := range edges {
+		if edges[i].goal == goal {
+			return &edges[i]
+		}
+	}
+	return nil
+}
+
+// States returns every State known to the ruleset, indexed by its
+// interned ID.
+func (c *CompiledRuleset) States() []State {
+	return append([]State(nil), c.states...)
+}
+
+// ExitsFrom returns the goal of every transition out of origin, sorted.
+// Transitions are indexed by origin, so this doesn't depend on the size of
+// the ruleset.
+func (c *CompiledRuleset) ExitsFrom(origin State) []State {
+	o, ok := c.ids[origin]
+	if !ok {
+		return nil
+	}
+
+	exits := make([]State, len(c.edges[o]))
+	for i, e := range c.edges[o] {
+		exits[i] = c.states[e.goal]
+	}
+	sort.Slice(exits, func(i, j int) bool { return exits[i] < exits[j] })
+
+	return exits
+}
+
+// All yields every transition in the ruleset, by origin.
+func (c *CompiledRuleset) All() iter.Seq[Transition] {
+	return func(yield func(Transition) bool) {
+		for _, origin := range c.states {
+			for t := range c.From(origin) {
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// From yields every transition out of origin.
+func (c *CompiledRuleset) From(origin State) iter.Seq[Transition] {
+	return func(yield func(Transition) bool) {
+		o, ok := c.ids[origin]
+		if !ok {
+			return
+		}
+		for _, e := range c.edges[o] {
+			if !yield(T{origin, c.states[e.goal]}) {
+				return
+			}
+		}
+	}
+}
+
+// Permitted determines if a transition is allowed.
+func (c *CompiledRuleset) Permitted(subject Stater, goal State) bool {
+	e := c.find(subject.CurrentState(), goal)
+	if e == nil {
+		return false // No rule found for the transition
+	}
+	for _, guard := range e.guards {
+		if !guard(subject, goal) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// find returns the edge from origin to goal, or nil if there is none.
+func (c *CompiledRuleset) find(origin, goal State) *edge {
+	o, ok := c.ids[origin]
+	if !ok {
+		return nil
+	}
+	g, ok := c.ids[goal]
+	if !ok || c.exits != nil && !c.exits.has(o*len(c.states)+g) {
+		return nil
+	}
+
+	edges := c.edges[o]
+	i := sort.Search(len(edges), func(i int) bool { return edges[i].goal >= g })
+	if i == len(edges) || edges[i].goal != g {
+		return nil
+	}
+	return &edges[i]
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int)      { b[i/64] |= 1 << (uint(i) % 64) }
+func (b bitset) has(i int) bool { return b[i/64]&(1<<(uint(i)%64)) != 0 }
@@ -0,0 +1,156 @@
+package fsm_test
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestCompiledRulesetPermitted(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "finished"},
+	)
+	rules.AddRule(fsm.T{"started", "finished"}, func(subject fsm.Stater, goal fsm.State) bool {
+		return subject.(*Thing).State == "started"
+	})
+	compiled := rules.Compile()
+
+	for i, from := range []fsm.State{"", "pending", "started", "finished", "unknown"} {
+		for j, to := range []fsm.State{"pending", "started", "finished", "unknown"} {
+			subject := &Thing{State: from}
+			st.Expect(t, compiled.Permitted(subject, to), rules.Permitted(subject, to), i, j)
+		}
+	}
+	st.Expect(t, len(compiled.States()), 3)
+}
+
+func TestMachineWithCompiledRuleset(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithPermitter(fsm.CreateRuleset(fsm.T{"pending", "started"}).Compile()),
+		fsm.WithSubject(&some_thing),
+	)
+
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+	st.Expect(t, the_machine.Rules == nil, true)
+}
+
+func TestMachineRulesField(t *testing.T) {
+	// Machines set up by hand with a *Ruleset, as before Permitters, keep
+	// working, and WithRules after WithPermitter switches back to them.
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.Machine{Rules: &rules, Subject: &some_thing}
+	st.Expect(t, the_machine.Transition("started"), nil)
+
+	some_thing.State = "pending"
+	the_machine = fsm.New(
+		fsm.WithPermitter(fsm.CreateRuleset().Compile()),
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+	)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+}
+
+func BenchmarkCompiledRulesetTransitionInvalid(b *testing.B) {
+	rules := fsm.Ruleset{}
+	rules.AddTransition(fsm.T{"pending", "started"})
+	rules.AddTransition(fsm.T{"started", "finished"})
+	compiled := rules.Compile()
+
+	some_thing := &Thing{State: "pending"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		compiled.Permitted(some_thing, "finished")
+	}
+}
+
+func TestExitsFrom(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"pending", "cancelled"},
+		fsm.T{"started", "finished"},
+	)
+	compiled := rules.Compile()
+
+	st.Expect(t, rules.ExitsFrom("pending"), []fsm.State{"cancelled", "started"})
+	st.Expect(t, compiled.ExitsFrom("pending"), []fsm.State{"cancelled", "started"})
+	st.Expect(t, len(rules.ExitsFrom("finished")), 0)
+	st.Expect(t, len(compiled.ExitsFrom("finished")), 0)
+	st.Expect(t, len(compiled.ExitsFrom("unknown")), 0)
+}
+
+func TestRulesetIterators(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"pending", "cancelled"},
+		fsm.T{"started", "finished"},
+	)
+	compiled := rules.Compile()
+
+	sorted := func(ts iter.Seq[fsm.Transition]) []fsm.T {
+		var s []fsm.T
+		for t := range ts {
+			s = append(s, fsm.T{t.Origin(), t.Exit()})
+		}
+		slices.SortFunc(s, func(a, b fsm.T) int {
+			return cmp.Or(cmp.Compare(a.O, b.O), cmp.Compare(a.E, b.E))
+		})
+		return s
+	}
+
+	all := []fsm.T{{"pending", "cancelled"}, {"pending", "started"}, {"started", "finished"}}
+	st.Expect(t, sorted(rules.All()), all)
+	st.Expect(t, sorted(compiled.All()), all)
+	st.Expect(t, sorted(rules.From("pending")), all[:2])
+	st.Expect(t, sorted(compiled.From("pending")), all[:2])
+	st.Expect(t, len(sorted(compiled.From("unknown"))), 0)
+
+	for range compiled.All() {
+		break // stopping early must not panic
+	}
+}
+
+func BenchmarkExitsFrom(b *testing.B) {
+	rules := fsm.Ruleset{}
+	for i := 0; i < 10000; i++ {
+		rules.AddTransition(fsm.T{fsm.State(fmt.Sprint(i)), fsm.State(fmt.Sprint(i + 1))})
+	}
+	compiled := rules.Compile()
+
+	b.Run("ruleset", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			rules.ExitsFrom("500")
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			compiled.ExitsFrom("500")
+		}
+	})
+}
+
+func TestCompiledRulesetLarge(t *testing.T) {
+	// past the dense bitset limit, lookups fall back to the origin index
+	rules := fsm.Ruleset{}
+	for i := 0; i < 5000; i++ {
+		rules.AddTransition(fsm.T{fsm.State(fmt.Sprint(i)), fsm.State(fmt.Sprint(i + 1))})
+	}
+	compiled := rules.Compile()
+
+	st.Expect(t, compiled.Permitted(&Thing{State: "10"}, "11"), true)
+	st.Expect(t, compiled.Permitted(&Thing{State: "10"}, "12"), false)
+	st.Expect(t, compiled.Permitted(&Thing{State: "5000"}, "1"), false)
+}
@@ -0,0 +1,148 @@
+// Package consume applies deliveries from an at-least-once message source,
+// such as a queue, to the machines of a Registry. A message is acknowledged
+// only once its transition has been made and recorded, and otherwise
+// negatively acknowledged to be redelivered after a backoff, or dead
+// lettered when retrying won't help.
+package consume
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// Message is a delivery received from a Source.
+type Message interface {
+	Delivery() fsm.Delivery
+
+	// Attempt is 1 for the first delivery of the message, 2 for its first
+	// redelivery, and so on.
+	Attempt() int
+
+	// Ack tells the source the message has been processed.
+	Ack() error
+
+	// Nack tells the source to redeliver the message after delay.
+	Nack(delay time.Duration) error
+}
+
+// Source yields messages. Receive blocks until there is one, or ctx is
+// done.
+type Source interface {
+	Receive(ctx context.Context) (Message, error)
+}
+
+// Consumer applies the messages of a Source to the machines of a Registry.
+type Consumer struct {
+	Registry *fsm.Registry
+	Source   Source
+
+	// Backoff is how long to wait before the first redelivery, doubling
+	// with each attempt up to MaxBackoff, when set.
+	Backoff, MaxBackoff time.Duration
+
+	// MaxAttempts, if positive, is the number of attempts after which a
+	// message is given up on even if its failure may pass.
+	MaxAttempts int
+
+	// Retryable decides if a failed message may be redelivered. It
+	// defaults to fsm.Transient.
+	Retryable func(error) bool
+
+	// DeadLetters keeps the messages given up on; without it they are
+	// dropped.
+	DeadLetters fsm.DeadLetterStore
+
+	// Seen, if set, absorbs redeliveries of messages already applied,
+	// by their Delivery ID, for SeenTTL. The ID is claimed while the
+	// machine is held, just before its transition, and released if the
+	// transition fails. The claim isn't stored with the transition,
+	// though: a process that dies between the two leaves the ID claimed
+	// for a message that wasn't applied, and its redeliveries are absorbed
+	// until the claim expires. SeenTTL must be set when Seen is, so that
+	// such a claim does expire and a later redelivery is applied; zero
+	// keeps every claim for good, stranded ones included.
+	Seen    fsm.IdempotencyStore
+	SeenTTL time.Duration
+}
+
+// Run processes messages until ctx is done or the Source fails, and
+// returns the error that stopped it.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msg, err := c.Source.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.Process(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// Process applies msg and acknowledges it accordingly. It only returns an
+// error when msg couldn't be acknowledged either way.
+func (c *Consumer) Process(ctx context.Context, msg Message) error {
+	d := msg.Delivery()
+	dedupe := c.Seen != nil && d.ID != ""
+
+	var (
+		state    fsm.State
+		seen     bool
+		claimErr error
+	)
+	err := c.Registry.Do(d.Key, func(m fsm.Machine) error {
+		state = m.Subject.CurrentState()
+		if dedupe {
+			first, err := c.Seen.Claim(ctx, d.ID, c.SeenTTL)
+			if err != nil || !first {
+				seen, claimErr = err == nil, err
+				return nil
+			}
+		}
+
+		err := m.TransitionAsCtx(ctx, d.Actor, d.Goal, d.Payload)
+		state = m.Subject.CurrentState()
+		if err != nil && dedupe {
+			if rerr := c.Seen.Release(ctx, d.ID); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+		return err
+	})
+	if claimErr != nil {
+		return msg.Nack(c.backoff(msg.Attempt()))
+	}
+	if err == nil || seen {
+		return msg.Ack()
+	}
+
+	retryable := c.Retryable
+	if retryable == nil {
+		retryable = fsm.Transient
+	}
+	if retryable(err) && (c.MaxAttempts <= 0 || msg.Attempt() < c.MaxAttempts) {
+		return msg.Nack(c.backoff(msg.Attempt()))
+	}
+
+	if c.DeadLetters != nil {
+		letter := fsm.DeadLetter{Delivery: d, State: state, Attempts: msg.Attempt(), Err: err, Time: time.Now()}
+		if err := c.DeadLetters.Put(letter); err != nil {
+			return msg.Nack(c.backoff(msg.Attempt()))
+		}
+	}
+	return msg.Ack()
+}
+
+func (c *Consumer) backoff(attempt int) time.Duration {
+	d := c.Backoff
+	for i := 1; i < attempt && (c.MaxBackoff <= 0 || d < c.MaxBackoff); i++ {
+		d *= 2
+	}
+	if c.MaxBackoff > 0 && d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
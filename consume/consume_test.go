@@ -0,0 +1,131 @@
+package consume_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/consume"
+)
+
+type Order struct {
+	State fsm.State
+}
+
+func (o *Order) CurrentState() fsm.State { return o.State }
+func (o *Order) SetState(s fsm.State)    { o.State = s }
+
+type message struct {
+	delivery fsm.Delivery
+	attempt  int
+	acked    bool
+	nacked   []time.Duration
+}
+
+func (m *message) Delivery() fsm.Delivery { return m.delivery }
+func (m *message) Attempt() int           { return m.attempt }
+func (m *message) Ack() error             { m.acked = true; return nil }
+
+func (m *message) Nack(delay time.Duration) error {
+	m.nacked = append(m.nacked, delay)
+	m.attempt++
+	return nil
+}
+
+// queue redelivers nacked messages straight away.
+type queue struct {
+	messages []*message
+}
+
+func (q *queue) Receive(ctx context.Context) (consume.Message, error) {
+	for _, m := range q.messages {
+		if !m.acked {
+			return m, nil
+		}
+	}
+	return nil, context.Canceled
+}
+
+type flakyStore struct {
+	fsm.MemoryEventStore
+	failures int
+}
+
+func (s *flakyStore) Append(e fsm.TransitionEvent) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("store unavailable")
+	}
+	return s.MemoryEventStore.Append(e)
+}
+
+func TestConsumer(t *testing.T) {
+	order := &Order{State: "pending"}
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "pending", E: "paid"}, fsm.T{O: "paid", E: "shipped"})),
+		fsm.WithSubject(order),
+		fsm.WithEventStore(&flakyStore{failures: 2}, 0),
+	))
+
+	paid := &message{delivery: fsm.Delivery{Key: "order-1", Goal: "paid", ID: "m1"}, attempt: 1}
+	again := &message{delivery: fsm.Delivery{Key: "order-1", Goal: "paid", ID: "m1"}, attempt: 1}
+	refunded := &message{delivery: fsm.Delivery{Key: "order-1", Goal: "refunded", ID: "m2"}, attempt: 1}
+
+	dead := &fsm.MemoryDeadLetters{}
+	seen := &fsm.MemoryIdempotencyStore{}
+	consumer := &consume.Consumer{
+		Registry:    registry,
+		Source:      &queue{messages: []*message{paid, again, refunded}},
+		Backoff:     time.Second,
+		MaxBackoff:  90 * time.Second,
+		DeadLetters: dead,
+		Seen:        seen,
+	}
+
+	st.Expect(t, consumer.Run(context.Background()), context.Canceled)
+	st.Expect(t, order.State, fsm.State("paid"))
+
+	st.Expect(t, paid.acked, true)
+	st.Expect(t, paid.nacked, []time.Duration{time.Second, 2 * time.Second})
+	st.Expect(t, again.acked, true)
+	st.Expect(t, len(again.nacked), 0)
+	st.Expect(t, refunded.acked, true)
+	st.Expect(t, len(refunded.nacked), 0)
+
+	letters := dead.Letters()
+	st.Expect(t, len(letters), 1)
+	st.Expect(t, letters[0].Delivery.ID, "m2")
+	st.Expect(t, letters[0].State, fsm.State("paid"))
+
+	// only the message that was applied counts as seen
+	claimed, _ := seen.Claim(context.Background(), "m1", 0)
+	st.Expect(t, claimed, false)
+	claimed, _ = seen.Claim(context.Background(), "m2", 0)
+	st.Expect(t, claimed, true)
+}
+
+func TestConsumerMaxAttempts(t *testing.T) {
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "pending", E: "paid"})),
+		fsm.WithSubject(&Order{State: "pending"}),
+		fsm.WithEventStore(&flakyStore{failures: 10}, 0),
+	))
+
+	msg := &message{delivery: fsm.Delivery{Key: "order-1", Goal: "paid"}, attempt: 1}
+	consumer := &consume.Consumer{
+		Registry:    registry,
+		Source:      &queue{messages: []*message{msg}},
+		Backoff:     time.Minute,
+		MaxBackoff:  3 * time.Minute,
+		MaxAttempts: 4,
+	}
+
+	st.Expect(t, consumer.Run(context.Background()), context.Canceled)
+	st.Expect(t, msg.acked, true)
+	st.Expect(t, msg.nacked, []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute})
+}
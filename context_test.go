@@ -0,0 +1,50 @@
+package fsm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type traceKey struct{}
+
+type contextStore struct {
+	fsm.MemoryEventStore
+	traces []interface{}
+}
+
+func (s *contextStore) AppendContext(ctx context.Context, e fsm.TransitionEvent) error {
+	s.traces = append(s.traces, ctx.Value(traceKey{}))
+	return s.Append(e)
+}
+
+func TestTransitionCtx(t *testing.T) {
+	var seen []interface{}
+	see := func(tc fsm.TransitionContext) error {
+		seen = append(seen, tc.Context().Value(traceKey{}))
+		return nil
+	}
+
+	store := &contextStore{}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 0),
+		fsm.WithPolicies(see),
+		fsm.WithActions(fsm.T{"pending", "started"}, see),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { see(tc) }),
+	)
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	st.Expect(t, the_machine.TransitionCtx(ctx, "started"), nil)
+	st.Expect(t, seen, []interface{}{"trace-1", "trace-1", "trace-1"})
+	st.Expect(t, store.traces, []interface{}{"trace-1"})
+
+	st.Expect(t, the_machine.Transition("finished"), nil)
+	st.Expect(t, seen[3], nil)
+	st.Expect(t, store.traces[1], nil)
+	st.Expect(t, fsm.TransitionContext{}.Context(), context.Background())
+}
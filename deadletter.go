@@ -0,0 +1,192 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delivery asks for the machine stored under Key to be moved to Goal, as
+// read from an event queue.
+type Delivery struct {
+	Key     string
+	Goal    State
+	Payload interface{}
+	Actor   string
+
+	// ID identifies the delivery to a Dispatcher with an IdempotencyStore,
+	// which applies a delivery with the same ID only once. Deliveries
+	// without one are never taken for duplicates.
+	ID string
+}
+
+// DeadLetter is a Delivery that could not be applied, with what is needed
+// to understand why and to replay it later.
+type DeadLetter struct {
+	Delivery
+	State    State // of the machine when the delivery was given up on
+	Attempts int
+	Err      error
+	Time     time.Time
+}
+
+// DeadLetterStore keeps DeadLetters.
+type DeadLetterStore interface {
+	Put(l DeadLetter) error
+}
+
+// Dispatcher applies Deliveries to the machines of a Registry, retrying
+// failures that may pass, and sending the rest to DeadLetters rather than
+// dropping them.
+type Dispatcher struct {
+	Registry    *Registry
+	DeadLetters DeadLetterStore
+
+	Retries int           // after the first attempt
+	Backoff time.Duration // between attempts, doubling each time
+
+	// Retryable decides if a failed attempt may be retried. By default
+	// rejections, such as invalid or unauthorized transitions, are not.
+	Retryable func(error) bool
+
+	// Seen, if set, absorbs deliveries whose ID it has seen in the last
+	// SeenTTL, or ever when SeenTTL is zero. Only deliveries that were
+	// applied count as seen: the ID is claimed while the machine is held,
+	// just before its transition, and released if the transition fails, so
+	// a delivery that failed, or was interrupted, may be made again.
+	Seen    IdempotencyStore
+	SeenTTL time.Duration
+
+	inFlight, retrying atomic.Int64
+}
+
+// DispatcherStats gives the depth of a Dispatcher's queue.
+type DispatcherStats struct {
+	InFlight int // deliveries being applied, or waiting to be retried
+	Retrying int // of those, the ones waiting out a backoff
+}
+
+// Stats returns the depth of the dispatcher's queue, for exporting along
+// with the Stats of its Registry.
+func (d *Dispatcher) Stats() DispatcherStats {
+	return DispatcherStats{InFlight: int(d.inFlight.Load()), Retrying: int(d.retrying.Load())}
+}
+
+// Deliver applies d. If it fails for good, d is put in the dead letter
+// store and the failure is returned; the store's own error is joined to it
+// if the letter couldn't be kept either. A duplicate delivery is ignored.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery Delivery) error {
+	d.inFlight.Add(1)
+	defer d.inFlight.Add(-1)
+
+	retryable := d.Retryable
+	if retryable == nil {
+		retryable = Transient
+	}
+
+	var (
+		state    State
+		seen     bool
+		err      error
+		attempts int
+		backoff  = d.Backoff
+	)
+attempt:
+	for {
+		attempts++
+		if state, seen, err = d.attempt(ctx, delivery); err == nil || seen {
+			return nil
+		}
+		if attempts > d.Retries || !retryable(err) {
+			break
+		}
+
+		d.retrying.Add(1)
+		select {
+		case <-time.After(backoff):
+			d.retrying.Add(-1)
+			backoff *= 2
+		case <-ctx.Done():
+			d.retrying.Add(-1)
+			err = errors.Join(err, ctx.Err())
+			break attempt
+		}
+	}
+
+	letter := DeadLetter{Delivery: delivery, State: state, Attempts: attempts, Err: err, Time: time.Now()}
+	if d.DeadLetters != nil {
+		if serr := d.DeadLetters.Put(letter); serr != nil {
+			err = errors.Join(err, serr)
+		}
+	}
+	return err
+}
+
+// attempt applies delivery to its machine once, returning the machine's
+// state after the attempt. It reports true, and does nothing, if the
+// delivery's ID was seen already.
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) (State, bool, error) {
+	e, ok := d.Registry.entry(delivery.Key)
+	if !ok {
+		return "", false, ErrUnknownMachine
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	dedupe := d.Seen != nil && delivery.ID != ""
+	if dedupe {
+		first, err := d.Seen.Claim(ctx, delivery.ID, d.SeenTTL)
+		if err != nil || !first {
+			return e.machine.Subject.CurrentState(), err == nil, err
+		}
+	}
+
+	err := e.machine.TransitionAsCtx(ctx, delivery.Actor, delivery.Goal, delivery.Payload)
+	if err != nil && dedupe {
+		if rerr := d.Seen.Release(ctx, delivery.ID); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}
+	return e.machine.Subject.CurrentState(), false, err
+}
+
+// Transient reports whether err, returned by a transition, may pass if the
+// transition is tried again. Rejections, such as invalid or unauthorized
+// transitions, and failures that moved the subject to an error state won't.
+// Timeouts may.
+func Transient(err error) bool {
+	var failed *FailedTransitionError
+	switch rejectionReason(err) {
+	case "timeout":
+		return true
+	case "error":
+		return !errors.Is(err, ErrUnknownMachine) && !errors.As(err, &failed)
+	default:
+		return false
+	}
+}
+
+// MemoryDeadLetters is a DeadLetterStore that keeps everything in memory.
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+func (s *MemoryDeadLetters) Put(l DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters = append(s.letters, l)
+	return nil
+}
+
+// Letters returns the dead letters, oldest first.
+func (s *MemoryDeadLetters) Letters() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]DeadLetter(nil), s.letters...)
+}
@@ -0,0 +1,75 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	store := &brokenStore{broken: true}
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "paid"})),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithEventStore(store, 0),
+	))
+
+	dead := &fsm.MemoryDeadLetters{}
+	dispatcher := &fsm.Dispatcher{Registry: registry, DeadLetters: dead, Retries: 2}
+
+	examples := []struct {
+		delivery fsm.Delivery
+		err      error
+		attempts int
+	}{
+		{fsm.Delivery{Key: "order-1", Goal: "shipped"}, fsm.ErrInvalidTransition, 1},
+		{fsm.Delivery{Key: "order-2", Goal: "paid"}, fsm.ErrUnknownMachine, 1},
+		{fsm.Delivery{Key: "order-1", Goal: "paid", Actor: "billing"}, errStoreDown, 3},
+	}
+
+	for i, ex := range examples {
+		err := dispatcher.Deliver(context.Background(), ex.delivery)
+		st.Expect(t, errors.Is(err, ex.err), true, i)
+
+		letters := dead.Letters()
+		st.Expect(t, len(letters), i+1, i)
+		st.Expect(t, letters[i].Delivery, ex.delivery, i)
+		st.Expect(t, letters[i].Attempts, ex.attempts, i)
+		st.Expect(t, errors.Is(letters[i].Err, ex.err), true, i)
+	}
+	st.Expect(t, dead.Letters()[0].State, fsm.State("pending"))
+
+	store.broken = false
+	st.Expect(t, dispatcher.Deliver(context.Background(), examples[2].delivery), nil)
+	st.Expect(t, len(dead.Letters()), 3)
+	st.Expect(t, dispatcher.Stats(), fsm.DispatcherStats{})
+}
+
+func TestDispatcherStats(t *testing.T) {
+	store := &brokenStore{broken: true}
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "paid"})),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithEventStore(store, 0),
+	))
+
+	dispatcher := &fsm.Dispatcher{Registry: registry, Retries: 1, Backoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "paid"}) }()
+
+	for dispatcher.Stats().Retrying == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	st.Expect(t, dispatcher.Stats(), fsm.DispatcherStats{InFlight: 1, Retrying: 1})
+
+	cancel()
+	st.Expect(t, errors.Is(<-done, context.Canceled), true)
+	st.Expect(t, dispatcher.Stats(), fsm.DispatcherStats{})
+}
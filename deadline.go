@@ -0,0 +1,36 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrTimeout = errors.New("transition timed out")
+
+// TimeoutError reports a transition abandoned because its context was done
+// before it reached Step. The subject is left where it was: a transition
+// either completes or, until its event has been recorded, is abandoned.
+type TimeoutError struct {
+	Origin, Goal State
+	Step         Step
+	Err          error // the context's error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s -> %s timed out at %s: %v", e.Origin, e.Goal, e.Step, e.Err)
+}
+
+// Unwrap returns both ErrTimeout and the context's error, so that either
+// can be matched with errors.Is.
+func (e *TimeoutError) Unwrap() []error { return []error{ErrTimeout, e.Err} }
+
+// expired returns a TimeoutError if the context of tc is done.
+func expired(tc TransitionContext, step Step) error {
+	if tc.ctx == nil {
+		return nil
+	}
+	if err := tc.ctx.Err(); err != nil {
+		return &TimeoutError{Origin: tc.Origin, Goal: tc.Goal, Step: step, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type slowStore struct {
+	fsm.MemoryEventStore
+	delay time.Duration
+}
+
+func (s *slowStore) AppendContext(ctx context.Context, e fsm.TransitionEvent) error {
+	select {
+	case <-time.After(s.delay):
+		return s.Append(e)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestTransitionDeadline(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	slow := func(tc fsm.TransitionContext) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	examples := []struct {
+		opts []func(*fsm.Machine)
+		step fsm.Step
+	}{
+		{nil, fsm.StepGuards},
+		{[]func(*fsm.Machine){fsm.WithPolicies(slow)}, fsm.StepPersistence},
+		{[]func(*fsm.Machine){fsm.WithEventStore(&slowStore{delay: time.Hour}, 0)}, fsm.StepPersistence},
+	}
+
+	for i, ex := range examples {
+		some_thing := Thing{State: "pending"}
+		opts := append([]func(*fsm.Machine){
+			fsm.WithRules(rules),
+			fsm.WithSubject(&some_thing),
+			fsm.WithErrorState("failed"),
+		}, ex.opts...)
+		the_machine := fsm.New(opts...)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if ex.step == fsm.StepGuards {
+			cancel()
+		}
+		err := the_machine.TransitionCtx(ctx, "started")
+		cancel()
+
+		var timeout *fsm.TimeoutError
+		st.Expect(t, errors.As(err, &timeout), true, i)
+		st.Expect(t, timeout.Step, ex.step, i)
+		st.Expect(t, errors.Is(err, fsm.ErrTimeout), true, i)
+		st.Expect(t, some_thing.State, fsm.State("pending"), i)
+		st.Expect(t, the_machine.Stats().Rejections["timeout"], uint64(1), i)
+	}
+}
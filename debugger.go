@@ -0,0 +1,150 @@
+package fsm
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// Debugger steps forwards and backwards through a machine's recorded
+// events, as created by Machine.Debug.
+type Debugger struct {
+	m       Machine
+	subject Stater // a copy of the machine's, moved by the Debugger
+	events  []TransitionEvent
+	pos     int
+}
+
+// Frame is the view of a Debugger at one position: the subject's State
+// after the first Index events, and the event that comes next.
+type Frame struct {
+	Index int
+	State State
+
+	// Next is the event about to be applied; nil once all have been.
+	Next *TransitionEvent
+
+	// Permitted tells if the machine's Rules would permit Next today, and
+	// Guards gives the outcome of each of its guards when the Rules are a
+	// Ruleset. Guards are evaluated with the Subject in State.
+	Permitted bool
+	Guards    []bool
+
+	// Err reports an event that doesn't start where the previous one
+	// ended, which Replay would refuse.
+	Err error
+}
+
+// Debug loads events for stepping through with the machine's Rules. The
+// Debugger moves a copy of the Subject, which starts in the origin of the
+// first event, so a machine in use can be debugged without disturbing it;
+// hooks, checks, and stores are never involved.
+func (m Machine) Debug(events iter.Seq[TransitionEvent]) *Debugger {
+	d := &Debugger{m: m, subject: detach(m.Subject)}
+	for e := range events {
+		d.events = append(d.events, e)
+	}
+	d.Seek(0)
+
+	return d
+}
+
+// Len returns the number of events.
+func (d *Debugger) Len() int {
+	return len(d.events)
+}
+
+// Frame describes the current position.
+func (d *Debugger) Frame() Frame {
+	f := Frame{Index: d.pos, State: d.state(d.pos)}
+	if d.pos == len(d.events) {
+		return f
+	}
+
+	next := d.events[d.pos]
+	f.Next = &next
+	if next.Origin != f.State {
+		f.Err = fmt.Errorf("%w: event %d moves %s -> %s but subject is %s",
+			ErrReplayMismatch, next.Seq, next.Origin, next.Goal, f.State)
+	}
+
+	subject := d.subject
+	f.Permitted = d.m.rules().Permitted(subject, next.Goal)
+	var rules Ruleset
+	switch r := d.m.rules().(type) {
+	case Ruleset:
+		rules = r
+	case *Ruleset:
+		rules = *r
+	}
+	for _, guard := range rules[T{f.State, next.Goal}] {
+		f.Guards = append(f.Guards, guard(subject, next.Goal))
+	}
+
+	return f
+}
+
+// Step applies the next event, reporting false at the end.
+func (d *Debugger) Step() (Frame, bool) {
+	if d.pos == len(d.events) {
+		return d.Frame(), false
+	}
+	return d.Seek(d.pos + 1), true
+}
+
+// Back undoes the last event, reporting false at the start.
+func (d *Debugger) Back() (Frame, bool) {
+	if d.pos == 0 {
+		return d.Frame(), false
+	}
+	return d.Seek(d.pos - 1), true
+}
+
+// Seek moves to the position after the first i events, clamped to the
+// events there are.
+func (d *Debugger) Seek(i int) Frame {
+	d.pos = min(max(i, 0), len(d.events))
+	if len(d.events) > 0 {
+		d.subject.SetState(d.state(d.pos))
+	}
+	return d.Frame()
+}
+
+// Subject returns the copy of the machine's Subject the Debugger moves.
+func (d *Debugger) Subject() Stater {
+	return d.subject
+}
+
+// state returns the subject's state after the first i events.
+func (d *Debugger) state(i int) State {
+	switch {
+	case len(d.events) == 0:
+		return d.subject.CurrentState()
+	case i == 0:
+		return d.events[0].Origin
+	default:
+		return d.events[i-1].Goal
+	}
+}
+
+// detach returns a copy of subject that can be moved without changing it:
+// a shallow copy of what subject points to, when it is a pointer, and
+// otherwise a Stater that only holds the state.
+func detach(subject Stater) Stater {
+	if d, ok := subject.(interface{ detach() Stater }); ok {
+		return d.detach()
+	}
+	if v := reflect.ValueOf(subject); v.Kind() == reflect.Pointer && !v.IsNil() {
+		c := reflect.New(v.Elem().Type())
+		c.Elem().Set(v.Elem())
+		if s, ok := c.Interface().(Stater); ok {
+			return s
+		}
+	}
+	return &stateOnly{subject.CurrentState()}
+}
+
+type stateOnly struct{ state State }
+
+func (s *stateOnly) CurrentState() State  { return s.state }
+func (s *stateOnly) SetState(state State) { s.state = state }
@@ -0,0 +1,84 @@
+package fsm_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestDebugger(t *testing.T) {
+	allow := func(subject fsm.Stater, goal fsm.State) bool { return true }
+	deny := func(subject fsm.Stater, goal fsm.State) bool { return false }
+
+	rules := fsm.Ruleset{}
+	rules.AddRule(fsm.T{"pending", "started"}, allow)
+	rules.AddRule(fsm.T{"started", "finished"}, allow, deny)
+
+	events := []fsm.TransitionEvent{
+		{Seq: 1, Origin: "pending", Goal: "started", Payload: "go"},
+		{Seq: 2, Origin: "started", Goal: "finished"},
+		{Seq: 3, Origin: "pending", Goal: "started"},
+	}
+
+	some_thing := Thing{}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing))
+	debugger := the_machine.Debug(slices.Values(events))
+	st.Expect(t, debugger.Len(), 3)
+
+	frame := debugger.Frame()
+	st.Expect(t, frame.State, fsm.State("pending"))
+	st.Expect(t, frame.Next.Payload, "go")
+	st.Expect(t, frame.Permitted, true)
+	st.Expect(t, frame.Guards, []bool{true})
+
+	frame, ok := debugger.Step()
+	st.Expect(t, ok, true)
+	st.Expect(t, debugger.Subject().CurrentState(), fsm.State("started"))
+	st.Expect(t, some_thing.State, fsm.State(""))
+	st.Expect(t, frame.Permitted, false)
+	st.Expect(t, frame.Guards, []bool{true, false})
+
+	frame, _ = debugger.Step()
+	st.Expect(t, frame.State, fsm.State("finished"))
+	st.Expect(t, errors.Is(frame.Err, fsm.ErrReplayMismatch), true)
+
+	frame, _ = debugger.Step()
+	st.Expect(t, frame.Next == nil, true)
+	_, ok = debugger.Step()
+	st.Expect(t, ok, false)
+
+	frame, ok = debugger.Back()
+	st.Expect(t, ok, true)
+	st.Expect(t, frame.Index, 2)
+	st.Expect(t, debugger.Seek(0).State, fsm.State("pending"))
+	_, ok = debugger.Back()
+	st.Expect(t, ok, false)
+}
+
+func TestDebuggerLeavesSubjectAlone(t *testing.T) {
+	// guards see a copy of the subject, in the state being debugged
+	var seen []fsm.State
+	rules := fsm.Ruleset{}
+	rules.AddRule(fsm.T{"draft", "published"}, func(subject fsm.Stater, goal fsm.State) bool {
+		seen = append(seen, subject.(*Thing).State)
+		return true
+	})
+	events := []fsm.TransitionEvent{{Seq: 1, Origin: "draft", Goal: "published"}}
+
+	some_thing := Thing{State: "archived"}
+	debugger := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing)).Debug(slices.Values(events))
+	debugger.Step()
+	st.Expect(t, slices.Compact(seen), []fsm.State{"draft"})
+	st.Expect(t, some_thing.State, fsm.State("archived"))
+
+	some_post := Post{Status: Published}
+	posts := fsm.CreateRuleset(fsm.T{fsm.StateOf(Draft), fsm.StateOf(Published)})
+	debugger = fsm.New(fsm.WithRules(posts), fsm.WithSubject(fsm.StaterOf(&some_post.Status, Draft, Published))).Debug(slices.Values([]fsm.TransitionEvent{
+		{Seq: 1, Origin: "draft", Goal: "published"},
+	}))
+	st.Expect(t, debugger.Subject().CurrentState(), fsm.State("draft"))
+	st.Expect(t, some_post.Status, Published)
+}
@@ -0,0 +1,34 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoState = errors.New("subject has no state")
+
+// Defaulter is implemented by subjects that know which state they start
+// in. A machine whose subject has no state yet moves it to its
+// DefaultState before transitioning, so zero-value subjects work.
+type Defaulter interface {
+	DefaultState() State
+}
+
+// defaultState gives the subject its DefaultState if it has no state.
+func (m Machine) defaultState() {
+	if m.Subject.CurrentState() != "" {
+		return
+	}
+	if d, ok := m.Subject.(Defaulter); ok {
+		m.Subject.SetState(d.DefaultState())
+	}
+}
+
+// stateless returns an error for transitions of subjects without a state,
+// which no rule could permit.
+func stateless(tc TransitionContext) error {
+	if tc.Origin != "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %w: %T has none and isn't a Defaulter", ErrInvalidTransition, ErrNoState, tc.Subject)
+}
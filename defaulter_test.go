@@ -0,0 +1,31 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type DefaultedThing struct {
+	Thing
+}
+
+func (t *DefaultedThing) DefaultState() fsm.State { return "pending" }
+
+func TestDefaulter(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+
+	some_thing := DefaultedThing{}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing))
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	other_thing := Thing{}
+	other_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing))
+	err := other_machine.Transition("started")
+	st.Expect(t, errors.Is(err, fsm.ErrNoState), true)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, err.Error(), "invalid transition: subject has no state: *fsm_test.Thing has none and isn't a Defaulter")
+}
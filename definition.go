@@ -0,0 +1,171 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+var ErrParameter = errors.New("bad definition parameter")
+
+// Definition is the JSON form of a ruleset, as read by Builder.LoadJSON:
+//
+//	{
+//	  "params": {"approver": {"default": "manager"}, "region": {}},
+//	  "transitions": [
+//	    {"from": "pending", "to": "approved", "guards": ["role:${approver}"]},
+//	    {"from": ["pending", "approved"], "to": "cancelled"},
+//	    {"from": "pending", "to": "expedited", "flag": "express-approval"}
+//	  ],
+//	  "depends": {"role:${approver}": ["authenticated"]},
+//	  "scripts": {"small": {"source": "subject.Amount < ${limit}", "timeout": "10ms"}},
+//	  "final": ["cancelled"]
+//	}
+//
+// Guards are referred to by the names they are registered under with the
+// Builder. A transition with a flag is only permitted while that feature
+// flag is enabled, as told by the FlagProvider given to Builder.Flags.
+// Scripts are guards too, referred to as "script:name" and compiled by the
+// ScriptEngine given to Builder.Scripts.
+//
+// Wherever "${name}" appears in a state, guard or flag name, or in the
+// source of a script, it is replaced by the value of the parameter name;
+// parameters without a default must be given a value when loading.
+type Definition struct {
+	Params      map[string]DefinedParam  `json:"params,omitempty"`
+	Transitions []DefinedTransition      `json:"transitions"`
+	Depends     map[string][]string      `json:"depends,omitempty"` // see Builder.Depends
+	Scripts     map[string]DefinedScript `json:"scripts,omitempty"` // see Builder.Scripts
+	Final       []State                  `json:"final,omitempty"`
+}
+
+// DefinedScript is a script of a Definition. Its Timeout, such as "10ms",
+// limits each run of it.
+type DefinedScript struct {
+	Source  string `json:"source"`
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// DefinedParam declares a Definition parameter.
+type DefinedParam struct {
+	Default *string `json:"default,omitempty"`
+}
+
+// DefinedTransition is a transition of a Definition. From holds either a
+// single state or a list of them.
+type DefinedTransition struct {
+	From   definedOrigins `json:"from"`
+	To     State          `json:"to"`
+	Guards []string       `json:"guards,omitempty"`
+	Flag   string         `json:"flag,omitempty"` // checked before the guards
+}
+
+type definedOrigins StateSet
+
+func (o *definedOrigins) UnmarshalJSON(data []byte) error {
+	var one State
+	if err := json.Unmarshal(data, &one); err == nil {
+		*o = definedOrigins{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]State)(o))
+}
+
+func (o definedOrigins) MarshalJSON() ([]byte, error) {
+	if len(o) == 1 {
+		return json.Marshal(o[0])
+	}
+	return json.Marshal([]State(o))
+}
+
+// LoadJSON adds the transitions and final states of the Definition read
+// from r to the builder. Problems are reported by Build.
+func (b *Builder) LoadJSON(r io.Reader) *Builder {
+	return b.LoadJSONWithParams(r, nil)
+}
+
+var paramRef = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// LoadJSONWithParams is like LoadJSON, substituting params for the
+// parameters declared by the Definition, so one definition can serve
+// several environments or tenants.
+func (b *Builder) LoadJSONWithParams(r io.Reader, params map[string]string) *Builder {
+	var d Definition
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("fsm: definition: %w", err))
+		return b
+	}
+
+	values := map[string]string{}
+	for name, p := range d.Params {
+		if v, ok := params[name]; ok {
+			values[name] = v
+		} else if p.Default != nil {
+			values[name] = *p.Default
+		} else {
+			b.errs = append(b.errs, fmt.Errorf("%w: %q has no value", ErrParameter, name))
+		}
+	}
+	for name := range params {
+		if _, ok := d.Params[name]; !ok {
+			b.errs = append(b.errs, fmt.Errorf("%w: %q is not declared", ErrParameter, name))
+		}
+	}
+
+	expand := func(s string) string {
+		return paramRef.ReplaceAllStringFunc(s, func(ref string) string {
+			name := paramRef.FindStringSubmatch(ref)[1]
+			if v, ok := values[name]; ok {
+				return v
+			}
+			if _, declared := d.Params[name]; !declared {
+				b.errs = append(b.errs, fmt.Errorf("%w: %q is used but not declared", ErrParameter, name))
+			}
+			return ref
+		})
+	}
+	state := func(s State) State { return State(expand(string(s))) }
+
+	for _, t := range d.Transitions {
+		origins := make(StateSet, len(t.From))
+		for i, o := range t.From {
+			origins[i] = state(o)
+		}
+		var guards []string
+		if t.Flag != "" {
+			guards = append(guards, "flag:"+expand(t.Flag))
+		}
+		for _, g := range t.Guards {
+			guards = append(guards, expand(g))
+		}
+		b.Transition(origins.To(state(t.To)), guards...)
+	}
+	for name, on := range d.Depends {
+		deps := make([]string, len(on))
+		for i, dep := range on {
+			deps[i] = expand(dep)
+		}
+		b.Depends(expand(name), deps...)
+	}
+	for name, s := range d.Scripts {
+		var timeout time.Duration
+		if s.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(s.Timeout); err != nil {
+				b.errs = append(b.errs, fmt.Errorf("fsm: definition: script %q: %w", name, err))
+				continue
+			}
+		}
+		b.Script(name, expand(s.Source), timeout)
+	}
+	for _, s := range d.Final {
+		b.Final(state(s))
+	}
+
+	return b
+}
@@ -0,0 +1,58 @@
+package fsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+const approvalDefinition = `{
+  "params": {"approver": {"default": "manager"}, "queue": {}},
+  "transitions": [
+    {"from": "${queue}", "to": "approved", "guards": ["role:${approver}"]},
+    {"from": ["${queue}", "approved"], "to": "cancelled"}
+  ],
+  "final": ["cancelled"]
+}`
+
+func roleGuard(role string) (fsm.Guard, error) {
+	return func(subject fsm.Stater, goal fsm.State) bool {
+		return subject.(*Thing).State != "" && role == "admin"
+	}, nil
+}
+
+func TestLoadJSONWithParams(t *testing.T) {
+	rules, err := fsm.NewBuilder().
+		GuardFactory("role", roleGuard).
+		LoadJSONWithParams(strings.NewReader(approvalDefinition), map[string]string{
+			"queue":    "pending",
+			"approver": "admin",
+		}).
+		Build()
+
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "approved"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "approved"}, "cancelled"), true)
+
+	rules, err = fsm.NewBuilder().
+		GuardFactory("role", roleGuard).
+		LoadJSONWithParams(strings.NewReader(approvalDefinition), map[string]string{"queue": "pending"}).
+		Build()
+
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "approved"), false)
+}
+
+func TestLoadJSONWithParamsErrors(t *testing.T) {
+	_, err := fsm.NewBuilder().
+		LoadJSONWithParams(strings.NewReader(approvalDefinition), map[string]string{"tenant": "acme"}).
+		Build()
+
+	st.Expect(t, errors.Is(err, fsm.ErrParameter), true)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownGuard), true)
+	st.Expect(t, strings.Contains(err.Error(), `"queue" has no value`), true)
+	st.Expect(t, strings.Contains(err.Error(), `"tenant" is not declared`), true)
+}
@@ -0,0 +1,251 @@
+package fsm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"iter"
+)
+
+var ErrCiphertext = errors.New("malformed ciphertext")
+
+// Encrypter encrypts and decrypts the payloads of persisted events.
+type Encrypter interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// EncryptedPayload stands in for the payload of an event stored by an
+// EncryptedStore.
+type EncryptedPayload struct {
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedStore is an EventStore that encrypts event payloads, which may
+// hold customer data, before handing them to Store, so that they can be
+// kept in shared storage. Events read back carry their decrypted payload
+// as a json.RawMessage. Snapshots are encrypted whole but for their Seq,
+// and handed to Store Sealed.
+//
+// An EncryptedStore passes on the context of AppendContext to Store if it
+// is a ContextAppender, and can be compacted if Store is a Compacter.
+type EncryptedStore struct {
+	Store     EventStore
+	Encrypter Encrypter
+}
+
+func (s *EncryptedStore) Append(e TransitionEvent) error {
+	e, err := s.encrypt(e)
+	if err != nil {
+		return err
+	}
+	return s.Store.Append(e)
+}
+
+func (s *EncryptedStore) AppendContext(ctx context.Context, e TransitionEvent) error {
+	e, err := s.encrypt(e)
+	if err != nil {
+		return err
+	}
+	if a, ok := s.Store.(ContextAppender); ok {
+		return a.AppendContext(ctx, e)
+	}
+	return s.Store.Append(e)
+}
+
+// Truncate discards events of Store, or returns ErrNotCompactable if it
+// isn't a Compacter.
+func (s *EncryptedStore) Truncate(through uint64) error {
+	c, ok := s.Store.(Compacter)
+	if !ok {
+		return ErrNotCompactable
+	}
+	return c.Truncate(through)
+}
+
+func (s *EncryptedStore) compactable() bool { return compactable(s.Store) }
+
+func (s *EncryptedStore) encrypt(e TransitionEvent) (TransitionEvent, error) {
+	if e.Payload == nil {
+		return e, nil
+	}
+	plaintext, err := json.Marshal(e.Payload)
+	if err != nil {
+		return e, err
+	}
+	ciphertext, err := s.Encrypter.Encrypt(plaintext)
+	if err != nil {
+		return e, err
+	}
+	e.Payload = EncryptedPayload{Ciphertext: ciphertext}
+	return e, nil
+}
+
+func (s *EncryptedStore) Events(after uint64) iter.Seq2[TransitionEvent, error] {
+	return func(yield func(TransitionEvent, error) bool) {
+		for e, err := range s.Store.Events(after) {
+			if err == nil {
+				e, err = s.decrypt(e)
+			}
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *EncryptedStore) decrypt(e TransitionEvent) (TransitionEvent, error) {
+	var payload EncryptedPayload
+	switch p := e.Payload.(type) {
+	case nil:
+		return e, nil
+	case EncryptedPayload:
+		payload = p
+	default:
+		// as decoded by a store that keeps events as JSON
+		raw, err := json.Marshal(p)
+		if err == nil {
+			err = json.Unmarshal(raw, &payload)
+		}
+		if err != nil || payload.Ciphertext == nil {
+			return e, ErrCiphertext
+		}
+	}
+
+	plaintext, err := s.Encrypter.Decrypt(payload.Ciphertext)
+	if err != nil {
+		return e, err
+	}
+	e.Payload = json.RawMessage(plaintext)
+	return e, nil
+}
+
+func (s *EncryptedStore) SaveSnapshot(snap Snapshot) error {
+	plaintext, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	sealed, err := s.Encrypter.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+	return s.Store.SaveSnapshot(Snapshot{Seq: snap.Seq, Sealed: sealed})
+}
+
+func (s *EncryptedStore) LatestSnapshot() (Snapshot, bool, error) {
+	sealed, ok, err := s.Store.LatestSnapshot()
+	if err != nil || !ok {
+		return sealed, ok, err
+	}
+	if sealed.Sealed == nil {
+		return Snapshot{}, false, ErrCiphertext
+	}
+
+	plaintext, err := s.Encrypter.Decrypt(sealed.Sealed)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(plaintext, &snap); err != nil {
+		return Snapshot{}, false, ErrCiphertext
+	}
+	return snap, true, nil
+}
+
+// AESEncrypter encrypts with AES-GCM under a fixed key of 16, 24 or 32
+// bytes.
+type AESEncrypter struct {
+	Key []byte
+}
+
+func (a AESEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	return sealGCM(a.Key, plaintext)
+}
+
+func (a AESEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	return openGCM(a.Key, ciphertext)
+}
+
+// KMS wraps and unwraps data keys with a key that never leaves it, as the
+// key management services of cloud providers do.
+type KMS interface {
+	WrapKey(key []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// EnvelopeEncrypter encrypts each payload with a fresh AES-256 data key,
+// which it stores alongside the ciphertext wrapped by KMS. Rotating the
+// KMS key therefore doesn't require re-encrypting anything.
+type EnvelopeEncrypter struct {
+	KMS KMS
+}
+
+func (e EnvelopeEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wrapped, err := e.KMS.WrapKey(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealGCM(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	out := binary.AppendUvarint(nil, uint64(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+func (e EnvelopeEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	n, size := binary.Uvarint(ciphertext)
+	if size <= 0 || uint64(len(ciphertext)-size) < n {
+		return nil, ErrCiphertext
+	}
+	wrapped, sealed := ciphertext[size:size+int(n)], ciphertext[size+int(n):]
+
+	key, err := e.KMS.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return openGCM(key, sealed)
+}
+
+// sealGCM encrypts plaintext with AES-GCM under key, prefixing the nonce.
+func sealGCM(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openGCM(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
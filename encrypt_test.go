@@ -0,0 +1,121 @@
+package fsm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// xorKMS stands in for a key management service.
+type xorKMS struct{ wrapped int }
+
+func (k *xorKMS) WrapKey(key []byte) ([]byte, error) {
+	k.wrapped++
+	return xor(key), nil
+}
+
+func (k *xorKMS) UnwrapKey(wrapped []byte) ([]byte, error) { return xor(wrapped), nil }
+
+func xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ 0x5a
+	}
+	return out
+}
+
+func TestEncryptedStore(t *testing.T) {
+	kms := &xorKMS{}
+	encrypters := []fsm.Encrypter{
+		fsm.AESEncrypter{Key: bytes.Repeat([]byte{7}, 32)},
+		fsm.EnvelopeEncrypter{KMS: kms},
+	}
+
+	for i, encrypter := range encrypters {
+		inner := &fsm.MemoryEventStore{}
+		store := &fsm.EncryptedStore{Store: inner, Encrypter: encrypter}
+		rules := fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+
+		some_thing := Thing{State: "pending"}
+		the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithEventStore(store, 0))
+		st.Expect(t, the_machine.TransitionWith("started", map[string]string{"card": "4242"}), nil, i)
+		st.Expect(t, the_machine.Transition("finished"), nil, i)
+
+		for e, err := range inner.Events(0) {
+			st.Expect(t, err, nil, i)
+			if e.Payload != nil {
+				raw, _ := json.Marshal(e.Payload)
+				st.Expect(t, bytes.Contains(raw, []byte("4242")), false, i)
+			}
+		}
+
+		var payloads []string
+		for e, err := range store.Events(0) {
+			st.Expect(t, err, nil, i)
+			raw, _ := json.Marshal(e.Payload)
+			payloads = append(payloads, string(raw))
+		}
+		st.Expect(t, payloads, []string{`{"card":"4242"}`, "null"}, i)
+
+		other_thing := Thing{State: "pending"}
+		restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing), fsm.WithEventStore(store, 0))
+		st.Expect(t, restored.Restore(), nil, i)
+		st.Expect(t, other_thing.State, fsm.State("finished"), i)
+	}
+	st.Expect(t, kms.wrapped, 1)
+
+	inner := &fsm.MemoryEventStore{}
+	right := &fsm.EncryptedStore{Store: inner, Encrypter: fsm.AESEncrypter{Key: bytes.Repeat([]byte{7}, 32)}}
+	wrong := &fsm.EncryptedStore{Store: inner, Encrypter: fsm.AESEncrypter{Key: bytes.Repeat([]byte{8}, 32)}}
+	st.Expect(t, right.Append(fsm.TransitionEvent{Seq: 1, Payload: "secret"}), nil)
+	for _, err := range wrong.Events(0) {
+		st.Expect(t, err != nil, true)
+	}
+
+	_, err := fsm.EnvelopeEncrypter{KMS: kms}.Decrypt([]byte{0xff})
+	st.Expect(t, errors.Is(err, fsm.ErrCiphertext), true)
+}
+
+// appendOnly hides the Truncate of the store it wraps.
+type appendOnly struct{ fsm.EventStore }
+
+func TestEncryptedStoreWraps(t *testing.T) {
+	inner := &contextStore{}
+	store := &fsm.EncryptedStore{Store: inner, Encrypter: fsm.AESEncrypter{Key: bytes.Repeat([]byte{7}, 32)}}
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithEventStore(store, 1),
+		fsm.WithRetention(fsm.Retention{MaxEntries: 1}),
+	)
+	ctx := context.WithValue(context.Background(), traceKey{}, "t1")
+	st.Expect(t, the_machine.TransitionCtx(ctx, "started"), nil)
+	st.Expect(t, the_machine.Transition("finished"), nil)
+	st.Expect(t, inner.traces, []interface{}{"t1", nil})
+
+	// snapshots are sealed, and retention compacts through the wrapper
+	sealed, ok, _ := inner.LatestSnapshot()
+	st.Expect(t, ok, true)
+	st.Expect(t, sealed.State, fsm.State(""))
+	st.Expect(t, sealed.Sealed != nil, true)
+	var kept int
+	for range inner.Events(0) {
+		kept++
+	}
+	st.Expect(t, kept, 1)
+
+	other_thing := Thing{State: "pending"}
+	restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing), fsm.WithEventStore(store, 0))
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, other_thing.State, fsm.State("finished"))
+
+	plain := &fsm.EncryptedStore{Store: appendOnly{&fsm.MemoryEventStore{}}, Encrypter: store.Encrypter}
+	st.Expect(t, fsm.Compact(plain, 1), fsm.ErrNotCompactable)
+}
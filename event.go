@@ -0,0 +1,106 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Events names what moves a subject, such as "submit" or "cancel", and the
+// transitions each name stands for. One event may lead to a different
+// goal from each origin, so callers can Fire it without knowing where the
+// subject is.
+type Events map[string][]T
+
+// AddEvent adds transitions to the event called name.
+func (e Events) AddEvent(name string, transitions ...Transition) {
+	for _, t := range transitions {
+		for _, t := range expand(t) {
+			e[name] = append(e[name], T{t.Origin(), t.Exit()})
+		}
+	}
+}
+
+// WithEvents is intended to be passed to New to let the machine Fire the
+// events of e. The transitions of an event must still be permitted by the
+// machine's Rules.
+func WithEvents(e Events) func(*Machine) {
+	return func(m *Machine) {
+		m.events = e
+	}
+}
+
+// Fire moves the subject along the transition that event stands for from
+// its current state. When the event has several transitions out of that
+// state, the machine's Resolver picks among those the Rules permit, as in
+// TransitionAmong. The event's name is recorded in the TransitionContext
+// and TransitionEvent.
+func (m Machine) Fire(event string) error {
+	return m.fire(context.Background(), "", event, nil)
+}
+
+// FireWith is like Fire but hands payload to any hooks.
+func (m Machine) FireWith(event string, payload interface{}) error {
+	return m.fire(context.Background(), "", event, payload)
+}
+
+// FireCtx is like Fire but carries ctx, as TransitionCtx does.
+func (m Machine) FireCtx(ctx context.Context, event string) error {
+	return m.fire(ctx, "", event, nil)
+}
+
+// FireAs is like FireWith but attributes the transition to actor, as
+// TransitionAs does.
+func (m Machine) FireAs(actor, event string, payload interface{}) error {
+	return m.fire(context.Background(), actor, event, payload)
+}
+
+func (m Machine) fire(ctx context.Context, actor, event string, payload interface{}) error {
+	transitions, ok := m.events[event]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+	}
+
+	tc := m.context("", payload).WithContext(ctx)
+	tc.Actor, tc.Event = actor, event
+
+	// those defined on the states the origin is nested within apply too
+	var goals []State
+	for _, s := range m.hierarchy.lineage(tc.Origin) {
+		for _, t := range transitions {
+			if t.O == s {
+				goals = append(goals, t.E)
+			}
+		}
+	}
+	if len(goals) == 0 {
+		err := fmt.Errorf("%w: event %q does not apply in %s", ErrInvalidTransition, event, tc.Origin)
+		m.stats.rejected(err)
+		return err
+	}
+
+	permitted := m.permitted(goals)
+	if len(permitted) == 0 {
+		// let the transition say why the first isn't permitted
+		tc.Goal = goals[0]
+		return m.transition(tc)
+	}
+	goal, ok := m.choose(permitted)
+	if !ok {
+		err := fmt.Errorf("%w: event %q declined by the resolver in %s", ErrInvalidTransition, event, tc.Origin)
+		m.stats.rejected(err)
+		return err
+	}
+	tc.Goal = goal
+
+	return m.transition(tc)
+}
+
+// Fire fires event on the machine stored under key, like Transition.
+func (r *Registry) Fire(key, event string) error {
+	return r.Do(key, func(m Machine) error {
+		return m.Fire(event)
+	})
+}
@@ -0,0 +1,91 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestMachineFire(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "finished"},
+		fsm.T{"pending", "cancelled"},
+		fsm.T{"started", "cancelled"},
+	)
+	events := fsm.Events{}
+	events.AddEvent("start", fsm.T{"pending", "started"})
+	events.AddEvent("advance", fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+	events.AddEvent("cancel", fsm.FromAnyOf("pending", "started").To("cancelled"))
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEvents(events),
+		fsm.WithHistory(),
+	)
+
+	st.Expect(t, the_machine.Fire("start"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	err := the_machine.Fire("start")
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, errors.Is(the_machine.Fire("launch"), fsm.ErrUnknownEvent), true)
+
+	st.Expect(t, the_machine.FireAs("alice", "advance", nil), nil)
+	st.Expect(t, some_thing.State, fsm.State("finished"))
+
+	st.Expect(t, errors.Is(the_machine.Fire("cancel"), fsm.ErrInvalidTransition), true)
+
+	history := the_machine.History()
+	st.Expect(t, history[1].Event, "advance")
+	st.Expect(t, history[1].Actor, "alice")
+}
+
+func TestMachineFireChoosesByGuards(t *testing.T) {
+	approved := false
+	rules := fsm.Ruleset{}
+	rules.AddRule(fsm.T{"review", "approved"}, func(fsm.Stater, fsm.State) bool { return approved })
+	rules.AddTransition(fsm.T{"review", "rejected"})
+
+	events := fsm.Events{}
+	events.AddEvent("decide", fsm.T{"review", "approved"}, fsm.T{"review", "rejected"})
+
+	for i, want := range []fsm.State{"rejected", "approved"} {
+		approved = i == 1
+		some_thing := Thing{State: "review"}
+		the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithEvents(events))
+
+		st.Expect(t, the_machine.Fire("decide"), nil, i)
+		st.Expect(t, some_thing.State, want, i)
+	}
+}
+
+func TestMachineFireUsesResolver(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"review", "approved"}, fsm.T{"review", "rejected"})
+	events := fsm.Events{}
+	events.AddEvent("decide", fsm.T{"review", "approved"}, fsm.T{"review", "rejected"})
+
+	some_thing := Thing{State: "review"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEvents(events),
+		fsm.WithResolver(fsm.Priority("rejected")),
+	)
+	st.Expect(t, the_machine.Fire("decide"), nil)
+	st.Expect(t, some_thing.State, fsm.State("rejected"))
+
+	some_thing.State = "review"
+	declining := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEvents(events),
+		fsm.WithResolver(func(fsm.Stater, []fsm.State) (fsm.State, bool) { return "", false }),
+	)
+	st.Expect(t, errors.Is(declining.Fire("decide"), fsm.ErrInvalidTransition), true)
+	st.Expect(t, some_thing.State, fsm.State("review"))
+}
@@ -0,0 +1,411 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReplayMismatch = errors.New("event does not follow current state")
+	ErrNoEventStore   = errors.New("machine has no event store")
+
+	// ErrSnapshotFailed wraps the error of an EventStore that couldn't save
+	// a snapshot. Unlike other transition errors it doesn't mean the
+	// transition was refused: it was made and recorded, and the events
+	// since the last snapshot are replayed on Restore as usual.
+	ErrSnapshotFailed = errors.New("snapshot failed")
+
+	// ErrNotCompactable is returned by Compact for a store that wraps one
+	// that can't discard events.
+	ErrNotCompactable = errors.New("event store can't discard events")
+)
+
+// TransitionEvent records a transition that has been applied.
+type TransitionEvent struct {
+	Seq     uint64            `json:"seq"`
+	Origin  State             `json:"origin"`
+	Goal    State             `json:"goal"`
+	Event   string            `json:"event,omitempty"`
+	Actor   string            `json:"actor,omitempty"`
+	Cause   string            `json:"cause,omitempty"` // why the machine was moved to an error state
+	Time    time.Time         `json:"time"`
+	Payload interface{}       `json:"payload,omitempty"`
+	Variant string            `json:"variant,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Schema  int               `json:"schema,omitempty"` // version of the Schema its states belong to
+	Forced  bool              `json:"forced,omitempty"`
+	Reason  string            `json:"reason,omitempty"` // why the transition was forced
+
+	// Hash and Prev chain the events of a machine created WithHashChain.
+	Hash string `json:"hash,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Replay rebuilds the Subject's state from a stream of events. Events were
+// permitted when they were recorded, so guards are not consulted and hooks
+// are not fired; each event must however start where the previous one
+// ended. Events are consumed one at a time, so the history never has to
+// fit in memory.
+func (m Machine) Replay(events iter.Seq[TransitionEvent]) error {
+	for e := range events {
+		if _, err := m.apply(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReplayJSON is like Replay but reads newline-delimited JSON events from r.
+func (m Machine) ReplayJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		var e TransitionEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := m.apply(e); err != nil {
+			return err
+		}
+	}
+}
+
+// apply moves the subject as e did, once e has been migrated to the
+// machine's Schema, and returns the migrated event.
+func (m Machine) apply(e TransitionEvent) (TransitionEvent, error) {
+	current := m.Subject.CurrentState()
+	e = m.schema.MigrateEvent(e, current)
+	if current != e.Origin {
+		return e, fmt.Errorf("%w: event %d moves %s -> %s but subject is %s",
+			ErrReplayMismatch, e.Seq, e.Origin, e.Goal, current)
+	}
+
+	m.Subject.SetState(Intern(e.Goal))
+	m.recall.observe(e.Goal)
+	m.stats.enter(e.Time)
+	return e, nil
+}
+
+// Snapshot captures a subject's state as of the event numbered Seq, so
+// restoring doesn't have to replay everything before it. Its Summary
+// tallies every event up to Seq, which keeps an audit trail of sorts even
+// once those events have been compacted away.
+type Snapshot struct {
+	Seq     uint64            `json:"seq"`
+	State   State             `json:"state"`
+	Time    time.Time         `json:"time"`
+	Hash    string            `json:"hash,omitempty"` // of the event at Seq, when hash chained
+	Schema  int               `json:"schema,omitempty"`
+	Summary []TransitionCount `json:"summary,omitempty"`
+
+	// Sealed holds the rest of a snapshot saved by an EncryptedStore,
+	// encrypted, or by a CompressedStore, compressed; only Seq is kept in
+	// the clear.
+	Sealed []byte `json:"sealed,omitempty"`
+}
+
+// TransitionCount tallies the occurrences of one transition.
+type TransitionCount struct {
+	Origin State     `json:"origin"`
+	Goal   State     `json:"goal"`
+	Count  uint64    `json:"count"`
+	First  time.Time `json:"first"`
+	Last   time.Time `json:"last"`
+}
+
+type tally map[T]TransitionCount
+
+func newTally(summary []TransitionCount) tally {
+	t := tally{}
+	for _, c := range summary {
+		t[T{c.Origin, c.Goal}] = c
+	}
+	return t
+}
+
+func (t tally) add(e TransitionEvent) {
+	key := T{e.Origin, e.Goal}
+	c, ok := t[key]
+	if !ok {
+		c = TransitionCount{Origin: e.Origin, Goal: e.Goal, First: e.Time}
+	}
+	c.Count++
+	c.Last = e.Time
+	t[key] = c
+}
+
+func (t tally) summary() []TransitionCount {
+	s := make([]TransitionCount, 0, len(t))
+	for _, c := range t {
+		s = append(s, c)
+	}
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Origin != s[j].Origin {
+			return s[i].Origin < s[j].Origin
+		}
+		return s[i].Goal < s[j].Goal
+	})
+	return s
+}
+
+// EventStore persists the events and snapshots of a single machine.
+type EventStore interface {
+	// Append stores an event. Events are appended in Seq order.
+	Append(e TransitionEvent) error
+
+	// Events yields the stored events with a Seq greater than after.
+	Events(after uint64) iter.Seq2[TransitionEvent, error]
+
+	SaveSnapshot(s Snapshot) error
+
+	// LatestSnapshot returns the snapshot with the highest Seq, if any.
+	LatestSnapshot() (Snapshot, bool, error)
+}
+
+// ContextAppender is implemented by EventStores that can make use of the
+// context of the transition whose event they append, such as one that
+// writes to a database. Machines call AppendContext instead of Append on
+// them.
+type ContextAppender interface {
+	AppendContext(ctx context.Context, e TransitionEvent) error
+}
+
+type eventLog struct {
+	store EventStore
+	every uint64
+
+	mu        sync.Mutex
+	seq       uint64
+	tally     tally
+	compacted uint64 // through which Retention compacted the store
+}
+
+// WithEventStore is intended to be passed to New to record every transition
+// in store. When every is positive, a snapshot is saved after each every
+// events.
+func WithEventStore(store EventStore, every int) func(*Machine) {
+	return func(m *Machine) {
+		m.log = &eventLog{store: store, every: uint64(max(every, 0)), tally: tally{}}
+	}
+}
+
+// newEvent describes the transition tc, made at now, with its payload
+// redacted.
+func (m Machine) newEvent(tc TransitionContext, now time.Time) TransitionEvent {
+	return TransitionEvent{
+		Origin:  tc.Origin,
+		Goal:    tc.Goal,
+		Event:   tc.Event,
+		Actor:   tc.Actor,
+		Time:    now,
+		Payload: m.Redact(tc.Payload),
+		Variant: tc.Variant,
+		Labels:  tc.Labels,
+		Schema:  m.schema.Version(),
+		Forced:  tc.Forced,
+		Reason:  tc.Reason,
+	}
+}
+
+// record numbers and appends e. It runs before the subject changes state,
+// so a failure leaves the machine where it was.
+func (l *eventLog) record(ctx context.Context, e TransitionEvent, chain *hashChain) (TransitionEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	chain.link(&e)
+	var err error
+	if a, ok := l.store.(ContextAppender); ok {
+		err = a.AppendContext(ctx, e)
+	} else {
+		err = l.store.Append(e)
+	}
+	if err != nil {
+		return e, err
+	}
+
+	l.seq = e.Seq
+	chain.advance(e)
+	l.tally.add(e)
+	return e, nil
+}
+
+// snapshot saves a snapshot if e completes a batch of every events.
+func (l *eventLog) snapshot(e TransitionEvent) error {
+	if l.every == 0 || e.Seq%l.every != 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	summary := l.tally.summary()
+	l.mu.Unlock()
+
+	snap := Snapshot{Seq: e.Seq, State: e.Goal, Time: e.Time, Hash: e.Hash, Schema: e.Schema, Summary: summary}
+	if err := l.store.SaveSnapshot(snap); err != nil {
+		return fmt.Errorf("%w: %w", ErrSnapshotFailed, err)
+	}
+	return nil
+}
+
+// Restore rebuilds the Subject's state from the machine's EventStore,
+// starting at the latest snapshot and replaying only the events after it.
+func (m Machine) Restore() error {
+	if m.log == nil {
+		return ErrNoEventStore
+	}
+
+	m.log.mu.Lock()
+	defer m.log.mu.Unlock()
+
+	snap, ok, err := m.log.store.LatestSnapshot()
+	if err != nil {
+		return err
+	}
+	m.log.seq, m.log.tally = 0, tally{}
+	m.chain.restore(snap.Hash)
+	if ok {
+		snap = m.schema.MigrateSnapshot(snap)
+		m.Subject.SetState(Intern(snap.State))
+		m.stats.enter(snap.Time)
+		m.log.seq, m.log.tally = snap.Seq, newTally(snap.Summary)
+	}
+
+	for e, err := range m.log.store.Events(m.log.seq) {
+		if err != nil {
+			return err
+		}
+		if e, err = m.apply(e); err != nil {
+			return err
+		}
+		m.log.seq = e.Seq
+		m.log.tally.add(e)
+		m.chain.advance(e)
+	}
+
+	return nil
+}
+
+// Compacter is implemented by EventStores that can discard old events.
+type Compacter interface {
+	EventStore
+
+	// Truncate removes every event with a Seq up to and including through.
+	Truncate(through uint64) error
+}
+
+// compactable reports whether store can discard events. Stores that wrap
+// another, such as EncryptedStore, are Compacters only when it is one.
+func compactable(store EventStore) bool {
+	if w, ok := store.(interface{ compactable() bool }); ok {
+		return w.compactable()
+	}
+	_, ok := store.(Compacter)
+	return ok
+}
+
+// Compact discards the events in store up to and including through, first
+// saving a snapshot at through unless a later one already exists. The
+// snapshot's Summary keeps a tally of the discarded events.
+func Compact(store Compacter, through uint64) error {
+	if !compactable(store) {
+		return ErrNotCompactable
+	}
+	snap, _, err := store.LatestSnapshot()
+	if err != nil {
+		return err
+	}
+
+	if snap.Seq < through {
+		t := newTally(snap.Summary)
+		folded := false
+		for e, err := range store.Events(snap.Seq) {
+			if err != nil {
+				return err
+			}
+			if e.Seq > through {
+				break
+			}
+			t.add(e)
+			snap.Seq, snap.State, snap.Time, snap.Hash, snap.Schema = e.Seq, e.Goal, e.Time, e.Hash, e.Schema
+			folded = true
+		}
+		snap.Summary = t.summary()
+
+		// with nothing to fold in, the snapshot would say nothing new
+		if folded {
+			if err := store.SaveSnapshot(snap); err != nil {
+				return err
+			}
+		}
+	}
+
+	return store.Truncate(through)
+}
+
+// MemoryEventStore is an EventStore that keeps everything in memory.
+type MemoryEventStore struct {
+	mu        sync.RWMutex
+	events    []TransitionEvent
+	snapshots []Snapshot
+}
+
+func (s *MemoryEventStore) Append(e TransitionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *MemoryEventStore) Events(after uint64) iter.Seq2[TransitionEvent, error] {
+	return func(yield func(TransitionEvent, error) bool) {
+		s.mu.RLock()
+		events := s.events
+		s.mu.RUnlock()
+
+		for _, e := range events {
+			if e.Seq > after && !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *MemoryEventStore) SaveSnapshot(snap Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots = append(s.snapshots, snap)
+	return nil
+}
+
+func (s *MemoryEventStore) LatestSnapshot() (Snapshot, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.snapshots) == 0 {
+		return Snapshot{}, false, nil
+	}
+	return s.snapshots[len(s.snapshots)-1], true, nil
+}
+
+func (s *MemoryEventStore) Truncate(through uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Seq > through })
+	s.events = append([]TransitionEvent(nil), s.events[i:]...)
+	return nil
+}
@@ -0,0 +1,144 @@
+package fsm_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestMachineReplay(t *testing.T) {
+	events := []fsm.TransitionEvent{
+		{Seq: 1, Origin: "pending", Goal: "started"},
+		{Seq: 2, Origin: "started", Goal: "finished"},
+	}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(&some_thing))
+
+	st.Expect(t, the_machine.Replay(slices.Values(events)), nil)
+	st.Expect(t, some_thing.State, fsm.State("finished"))
+
+	some_thing.State = "pending"
+	err := the_machine.Replay(slices.Values(events[1:]))
+	st.Expect(t, errors.Is(err, fsm.ErrReplayMismatch), true)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+}
+
+func TestMachineReplayJSON(t *testing.T) {
+	stream := strings.NewReader(`{"seq":1,"origin":"pending","goal":"started"}
+{"seq":2,"origin":"started","goal":"finished"}
+`)
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(&some_thing))
+
+	st.Expect(t, the_machine.ReplayJSON(stream), nil)
+	st.Expect(t, some_thing.State, fsm.State("finished"))
+}
+
+func TestMachineRestoreFromSnapshot(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	store := &fsm.MemoryEventStore{}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithEventStore(store, 2))
+
+	for _, goal := range []fsm.State{"started", "pending", "started", "pending", "started"} {
+		st.Expect(t, the_machine.Transition(goal), nil)
+	}
+
+	snap, ok, err := store.LatestSnapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	st.Expect(t, snap.Seq, uint64(4))
+	st.Expect(t, snap.State, fsm.State("pending"))
+
+	other_thing := Thing{}
+	restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing), fsm.WithEventStore(store, 2))
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, other_thing.State, fsm.State("started"))
+
+	// numbering carries on from the restored history
+	st.Expect(t, restored.Transition("pending"), nil)
+	snap, _, _ = store.LatestSnapshot()
+	st.Expect(t, snap.Seq, uint64(6))
+}
+
+type snapshotlessStore struct {
+	fsm.MemoryEventStore
+}
+
+func (s *snapshotlessStore) SaveSnapshot(fsm.Snapshot) error {
+	return errStoreDown
+}
+
+func TestMachineSnapshotFailure(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	store := &snapshotlessStore{}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithEventStore(store, 1))
+
+	// the transition stands, and the error says so
+	err := the_machine.Transition("started")
+	st.Expect(t, errors.Is(err, fsm.ErrSnapshotFailed), true)
+	st.Expect(t, errors.Is(err, errStoreDown), true)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), false)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+}
+
+func TestCompact(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	store := &fsm.MemoryEventStore{}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithEventStore(store, 0))
+
+	for _, goal := range []fsm.State{"started", "pending", "started", "pending", "started"} {
+		st.Expect(t, the_machine.Transition(goal), nil)
+	}
+
+	st.Expect(t, fsm.Compact(store, 3), nil)
+
+	var remaining []uint64
+	for e := range store.Events(0) {
+		remaining = append(remaining, e.Seq)
+	}
+	st.Expect(t, remaining, []uint64{4, 5})
+
+	snap, _, _ := store.LatestSnapshot()
+	st.Expect(t, snap.Seq, uint64(3))
+	st.Expect(t, snap.State, fsm.State("started"))
+	st.Expect(t, len(snap.Summary), 2)
+	st.Expect(t, snap.Summary[0].Origin, fsm.State("pending"))
+	st.Expect(t, snap.Summary[0].Count, uint64(2))
+	st.Expect(t, snap.Summary[1].Count, uint64(1))
+
+	other_thing := Thing{}
+	restored := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&other_thing), fsm.WithEventStore(store, 0))
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, other_thing.State, fsm.State("started"))
+}
+
+func TestCompactEmptyStore(t *testing.T) {
+	store := &fsm.MemoryEventStore{}
+	st.Expect(t, fsm.Compact(store, 3), nil)
+
+	_, ok, _ := store.LatestSnapshot()
+	st.Expect(t, ok, false)
+
+	some_thing := Thing{State: "pending"}
+	restored := fsm.New(fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})), fsm.WithSubject(&some_thing), fsm.WithEventStore(store, 0))
+	st.Expect(t, restored.Restore(), nil)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+}
@@ -0,0 +1,54 @@
+package fsm
+
+import "hash/fnv"
+
+// Variants an Experiment assigns machines to.
+const (
+	VariantStable    = "stable"
+	VariantCandidate = "candidate"
+)
+
+// Experiment routes a share of machines to a candidate ruleset while the
+// rest keep the stable one. Assignment is by hashing the machine's ID with
+// the experiment's Name, so a machine keeps its variant across restarts and
+// different experiments split machines independently.
+type Experiment struct {
+	Name      string
+	Stable    Permitter
+	Candidate Permitter
+	Percent   int // share of machines, 0-100, given the Candidate
+}
+
+// Variant returns the variant the machine with the given ID belongs to.
+func (e Experiment) Variant(id string) string {
+	h := fnv.New32a()
+	h.Write([]byte(e.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+
+	if int(h.Sum32()%100) < e.Percent {
+		return VariantCandidate
+	}
+	return VariantStable
+}
+
+// WithExperiment is intended to be passed to New to set the Rules to the
+// variant of e that the machine with the given ID belongs to. The variant
+// is reported by Machine.Variant, handed to callbacks in the
+// TransitionContext, and recorded with every TransitionEvent.
+func WithExperiment(e Experiment, id string) func(*Machine) {
+	return func(m *Machine) {
+		m.variant = e.Variant(id)
+		if m.variant == VariantCandidate {
+			m.setRules(e.Candidate)
+		} else {
+			m.setRules(e.Stable)
+		}
+	}
+}
+
+// Variant returns the experiment variant the machine belongs to, or "" if
+// it isn't part of one.
+func (m Machine) Variant() string {
+	return m.variant
+}
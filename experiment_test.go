@@ -0,0 +1,61 @@
+package fsm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestExperiment(t *testing.T) {
+	experiment := fsm.Experiment{
+		Name:      "skip-review",
+		Stable:    fsm.CreateRuleset(fsm.T{"draft", "review"}),
+		Candidate: fsm.CreateRuleset(fsm.T{"draft", "published"}),
+		Percent:   25,
+	}
+
+	registry := fsm.NewRegistry()
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprint("doc-", i)
+		registry.Put(id, fsm.New(fsm.WithExperiment(experiment, id), fsm.WithSubject(&Thing{State: "draft"})))
+
+		st.Expect(t, experiment.Variant(id), experiment.Variant(id), i)
+	}
+
+	registry.Range(func(id string, m fsm.Machine) bool {
+		if m.Variant() == fsm.VariantCandidate {
+			st.Expect(t, m.Transition("published"), nil)
+		} else {
+			st.Expect(t, m.Transition("review"), nil)
+		}
+		return true
+	})
+
+	stats := registry.Stats()
+	candidates := stats.Variants[fsm.VariantCandidate].Transitions
+	st.Expect(t, candidates+stats.Variants[fsm.VariantStable].Transitions, uint64(1000))
+	st.Expect(t, candidates > 200 && candidates < 300, true)
+	st.Expect(t, uint64(stats.States["published"]), candidates)
+}
+
+func TestExperimentVariantIsRecorded(t *testing.T) {
+	experiment := fsm.Experiment{
+		Stable:    fsm.CreateRuleset(fsm.T{"draft", "review"}),
+		Candidate: fsm.CreateRuleset(fsm.T{"draft", "review"}),
+		Percent:   100,
+	}
+	store := &fsm.MemoryEventStore{}
+
+	the_machine := fsm.New(
+		fsm.WithExperiment(experiment, "doc-1"),
+		fsm.WithSubject(&Thing{State: "draft"}),
+		fsm.WithEventStore(store, 0),
+	)
+	st.Expect(t, the_machine.Transition("review"), nil)
+
+	for e := range store.Events(0) {
+		st.Expect(t, e.Variant, fsm.VariantCandidate)
+	}
+}
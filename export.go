@@ -0,0 +1,182 @@
+package fsm
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// StatsReport is a flat rendering of RegistryStats for analysts: how many
+// machines are in each state, how often each transition was made, and how
+// long machines spent in each state.
+type StatsReport struct {
+	Time        time.Time         `json:"time"`
+	Machines    int               `json:"machines"`
+	Populations []StatePopulation `json:"populations"`
+	Transitions []TransitionTotal `json:"transitions"`
+	Durations   []StateDuration   `json:"durations"`
+}
+
+// StatePopulation is the number of machines in a state.
+type StatePopulation struct {
+	State    State `json:"state"`
+	Machines int   `json:"machines"`
+}
+
+// TransitionTotal is the number of times a transition was made.
+type TransitionTotal struct {
+	Origin State  `json:"origin"`
+	Goal   State  `json:"goal"`
+	Count  uint64 `json:"count"`
+}
+
+// StateDuration describes how long machines stayed in a state before
+// leaving it. Durations are in seconds.
+type StateDuration struct {
+	State State   `json:"state"`
+	Count uint64  `json:"count"`
+	Min   float64 `json:"min"`
+	Mean  float64 `json:"mean"`
+	Max   float64 `json:"max"`
+	Total float64 `json:"total"`
+}
+
+// NewStatsReport renders rs, as taken at now. Its rows are sorted so that
+// consecutive reports line up.
+func NewStatsReport(rs RegistryStats, now time.Time) StatsReport {
+	report := StatsReport{Time: now, Machines: rs.Machines}
+
+	for state, n := range rs.States {
+		report.Populations = append(report.Populations, StatePopulation{State: state, Machines: n})
+	}
+	sort.Slice(report.Populations, func(i, j int) bool {
+		return report.Populations[i].State < report.Populations[j].State
+	})
+
+	for t, n := range rs.Counts {
+		report.Transitions = append(report.Transitions, TransitionTotal{Origin: t.O, Goal: t.E, Count: n})
+	}
+	sort.Slice(report.Transitions, func(i, j int) bool {
+		a, b := report.Transitions[i], report.Transitions[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		return a.Goal < b.Goal
+	})
+
+	for state, l := range rs.Durations {
+		report.Durations = append(report.Durations, StateDuration{
+			State: state,
+			Count: l.Count,
+			Min:   l.Min.Seconds(),
+			Mean:  l.Mean().Seconds(),
+			Max:   l.Max.Seconds(),
+			Total: l.Total.Seconds(),
+		})
+	}
+	sort.Slice(report.Durations, func(i, j int) bool {
+		return report.Durations[i].State < report.Durations[j].State
+	})
+
+	return report
+}
+
+// WriteJSON writes the report to w as a single line of JSON, so that
+// reports can be appended to the same file.
+func (r StatsReport) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// CSVHeader is the header row of the CSV written by StatsReport.WriteCSV.
+var CSVHeader = []string{"time", "kind", "state", "origin", "goal", "count", "min", "mean", "max", "total"}
+
+// WriteCSV writes the report to w as CSV rows, one per population,
+// transition and duration, without a header so that reports can be
+// appended to the same file; see CSVHeader. The kind column tells the rows
+// apart and columns that don't apply to a kind are empty.
+func (r StatsReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	at := r.Time.UTC().Format(time.RFC3339)
+	seconds := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+	for _, p := range r.Populations {
+		cw.Write([]string{at, "population", string(p.State), "", "", strconv.Itoa(p.Machines), "", "", "", ""})
+	}
+	for _, t := range r.Transitions {
+		cw.Write([]string{at, "transition", "", string(t.Origin), string(t.Goal), strconv.FormatUint(t.Count, 10), "", "", "", ""})
+	}
+	for _, d := range r.Durations {
+		cw.Write([]string{at, "duration", string(d.State), "", "", strconv.FormatUint(d.Count, 10),
+			seconds(d.Min), seconds(d.Mean), seconds(d.Max), seconds(d.Total)})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportFormat is the format a StatsExporter writes.
+type ExportFormat int
+
+const (
+	ExportJSON ExportFormat = iota
+	ExportCSV
+)
+
+// StatsExporter periodically writes a StatsReport of a Registry, for
+// loading into BI tools.
+type StatsExporter struct {
+	Registry *Registry
+	Format   ExportFormat
+	Clock    Clock // defaults to SystemClock
+
+	// Open returns where to write the report taken at now, such as a file
+	// named after it. It is closed once the report has been written.
+	Open func(now time.Time) (io.WriteCloser, error)
+}
+
+// Export writes a single report.
+func (e *StatsExporter) Export() error {
+	clock := e.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+	report := NewStatsReport(e.Registry.Stats(), now)
+
+	w, err := e.Open(now)
+	if err != nil {
+		return err
+	}
+
+	if e.Format == ExportCSV {
+		err = report.WriteCSV(w)
+	} else {
+		err = report.WriteJSON(w)
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Run exports every interval until ctx is done or an export fails, and
+// returns the error that stopped it.
+func (e *StatsExporter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.Export(); err != nil {
+				return err
+			}
+		}
+	}
+}
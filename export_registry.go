@@ -0,0 +1,112 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+var ErrExportVersion = errors.New("unsupported registry export version")
+
+// ExportVersion is the version of the format written by Registry.Export.
+const ExportVersion = 1
+
+// ExportHeader starts a registry export.
+type ExportHeader struct {
+	Version  int       `json:"version"`
+	Time     time.Time `json:"time"`
+	Machines int       `json:"machines"`
+}
+
+// ExportedMachine is a machine as written by Registry.Export.
+type ExportedMachine struct {
+	Key     string            `json:"key"`
+	State   State             `json:"state"`
+	Since   time.Time         `json:"since"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Variant string            `json:"variant,omitempty"`
+	History []TransitionEvent `json:"history,omitempty"` // of machines kept WithHistory
+	Schema  int               `json:"schema,omitempty"`  // version of the Schema State belongs to
+	Chain   string            `json:"chain,omitempty"`   // hash of the last event, when hash chained
+}
+
+// Export writes every machine in the registry to w, as newline-delimited
+// JSON: an ExportHeader followed by an ExportedMachine per machine, in key
+// order. Rules and subjects aren't exported; Import is given a way to make
+// them.
+func (r *Registry) Export(w io.Writer) error {
+	var machines []ExportedMachine
+	for key, m := range r.All() {
+		machines = append(machines, ExportedMachine{
+			Key:     key,
+			State:   m.Subject.CurrentState(),
+			Since:   m.Since(),
+			Labels:  m.Labels(),
+			Variant: m.Variant(),
+			History: m.History(),
+			Schema:  m.schema.Version(),
+			Chain:   m.chain.head(),
+		})
+	}
+	sort.Slice(machines, func(i, j int) bool { return machines[i].Key < machines[j].Key })
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(ExportHeader{Version: ExportVersion, Time: time.Now(), Machines: len(machines)}); err != nil {
+		return err
+	}
+	for _, m := range machines {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import reads an export written by Export and puts a machine in the
+// registry for each exported one, replacing any under the same key. build
+// makes the machine, with its rules and a subject, from what was exported;
+// Import then restores the subject's state, when it entered it, the
+// machine's History if it keeps one, and the head of its hash chain. The
+// state and History are migrated to the machine's Schema, as they are
+// when restored from an EventStore.
+func (r *Registry) Import(rd io.Reader, build func(e ExportedMachine) (Machine, error)) error {
+	dec := json.NewDecoder(rd)
+
+	var header ExportHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("fsm: registry export: %w", err)
+	}
+	if header.Version != ExportVersion {
+		return fmt.Errorf("%w: %d", ErrExportVersion, header.Version)
+	}
+
+	for {
+		var e ExportedMachine
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("fsm: registry export: %w", err)
+		}
+
+		m, err := build(e)
+		if err != nil {
+			return fmt.Errorf("fsm: importing %q: %w", e.Key, err)
+		}
+		state, _ := m.schema.state(e.Schema, e.State, &TransitionEvent{Goal: e.State, Time: e.Since})
+		m.Subject.SetState(Intern(state))
+		m.recall.observe(state)
+		m.stats.enter(e.Since)
+		m.history.replace(m.schema.migrateAll(e.History))
+
+		chain := e.Chain
+		if chain == "" && len(e.History) > 0 {
+			chain = e.History[len(e.History)-1].Hash
+		}
+		m.chain.restore(chain)
+
+		r.Put(e.Key, m)
+	}
+}
@@ -0,0 +1,98 @@
+package fsm_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistryExportImport(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := fsm.ClockFunc(func() time.Time { return now })
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+
+	source := fsm.NewRegistry()
+	for _, key := range []string{"b", "a"} {
+		source.Put(key, fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithClock(clock),
+			fsm.WithHistory(),
+			fsm.WithLabels(map[string]string{"tenant": "acme"}),
+		))
+	}
+	now = now.Add(time.Hour)
+	st.Expect(t, source.Transition("a", "started"), nil)
+
+	var out bytes.Buffer
+	st.Expect(t, source.Export(&out), nil)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	st.Expect(t, len(lines), 3)
+	st.Expect(t, strings.HasPrefix(lines[0], `{"version":1,`), true)
+	st.Expect(t, strings.HasPrefix(lines[1], `{"key":"a","state":"started"`), true)
+
+	target := fsm.NewRegistry()
+	err := target.Import(&out, func(e fsm.ExportedMachine) (fsm.Machine, error) {
+		return fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{}),
+			fsm.WithHistory(),
+			fsm.WithLabels(e.Labels),
+		), nil
+	})
+	st.Expect(t, err, nil)
+	st.Expect(t, target.Len(), 2)
+
+	a, _ := target.Get("a")
+	st.Expect(t, a.Subject.CurrentState(), fsm.State("started"))
+	st.Expect(t, a.Since(), now)
+	st.Expect(t, a.Labels(), map[string]string{"tenant": "acme"})
+	st.Expect(t, len(a.History()), 1)
+	st.Expect(t, target.Transition("a", "finished"), nil)
+
+	err = target.Import(strings.NewReader(`{"version":99}`+"\n"), nil)
+	st.Expect(t, errors.Is(err, fsm.ErrExportVersion), true)
+}
+
+func TestRegistryImportMigrates(t *testing.T) {
+	// exported before "started" was renamed "running"
+	source := fsm.NewRegistry()
+	source.Put("a", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithHistory(),
+		fsm.WithHashChain(),
+	))
+	st.Expect(t, source.Transition("a", "started"), nil)
+	var out bytes.Buffer
+	st.Expect(t, source.Export(&out), nil)
+
+	schema := fsm.Schema{{Version: 1, Changes: []fsm.StateChange{fsm.RenameState("started", "running")}}}
+	target := fsm.NewRegistry()
+	err := target.Import(&out, func(e fsm.ExportedMachine) (fsm.Machine, error) {
+		return fsm.New(
+			fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "running"}, fsm.T{"running", "finished"})),
+			fsm.WithSubject(&Thing{}),
+			fsm.WithSchema(schema),
+			fsm.WithHistory(),
+			fsm.WithHashChain(),
+		), nil
+	})
+	st.Expect(t, err, nil)
+
+	a, _ := target.Get("a")
+	st.Expect(t, a.Subject.CurrentState(), fsm.State("running"))
+	st.Expect(t, a.History()[0].Goal, fsm.State("running"))
+
+	// the next event carries on the exported chain
+	exported, _ := source.Get("a")
+	st.Expect(t, target.Transition("a", "finished"), nil)
+	history := a.History()
+	st.Expect(t, len(history), 2)
+	st.Expect(t, history[1].Prev, exported.History()[0].Hash)
+}
@@ -0,0 +1,61 @@
+package fsm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestStatsExporter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := fsm.ClockFunc(func() time.Time { return now })
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+
+	registry := fsm.NewRegistry()
+	for _, key := range []string{"a", "b", "c"} {
+		registry.Put(key, fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"}), fsm.WithClock(clock)))
+	}
+
+	now = now.Add(time.Minute)
+	st.Expect(t, registry.Transition("a", "started"), nil)
+	now = now.Add(2 * time.Minute)
+	st.Expect(t, registry.Transition("b", "started"), nil)
+	st.Expect(t, registry.Transition("b", "finished"), nil)
+
+	var out bytes.Buffer
+	exporter := &fsm.StatsExporter{
+		Registry: registry,
+		Format:   fsm.ExportCSV,
+		Clock:    clock,
+		Open:     func(time.Time) (io.WriteCloser, error) { return nopCloser{&out}, nil },
+	}
+
+	st.Expect(t, exporter.Export(), nil)
+	st.Expect(t, out.String(), ""+
+		"2024-03-01T09:03:00Z,population,finished,,,1,,,,\n"+
+		"2024-03-01T09:03:00Z,population,pending,,,1,,,,\n"+
+		"2024-03-01T09:03:00Z,population,started,,,1,,,,\n"+
+		"2024-03-01T09:03:00Z,transition,,pending,started,2,,,,\n"+
+		"2024-03-01T09:03:00Z,transition,,started,finished,1,,,,\n"+
+		"2024-03-01T09:03:00Z,duration,pending,,,2,60,120,180,240\n"+
+		"2024-03-01T09:03:00Z,duration,started,,,1,0,0,0,0\n")
+
+	out.Reset()
+	exporter.Format = fsm.ExportJSON
+	st.Expect(t, exporter.Export(), nil)
+
+	var report fsm.StatsReport
+	st.Expect(t, json.Unmarshal(out.Bytes(), &report), nil)
+	st.Expect(t, report.Machines, 3)
+	st.Expect(t, report.Transitions[0], fsm.TransitionTotal{Origin: "pending", Goal: "started", Count: 2})
+	st.Expect(t, report.Durations[0].Mean, 120.0)
+}
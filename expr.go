@@ -0,0 +1,567 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Limits of an ExprEngine whose own are zero.
+const (
+	DefaultExprSize  = 4096
+	DefaultExprSteps = 10000
+)
+
+// ExprEngine is a ScriptEngine for a small expression language, enough for
+// the conditions operators most often need to adjust without a deploy:
+//
+//	payload.amount < 1000 && labels.tenant != "acme"
+//	actor in ["alice", "bob"] || !forced
+//
+// An expression reads the transition and nothing else: origin, goal,
+// event, actor, variant, reason and forced; labels.<name>; and the fields
+// of the payload and subject, as payload.<name> and subject.<Name>, through
+// maps with string keys and exported struct fields. Missing fields are
+// null. Values are strings, numbers, booleans, null and lists; == and !=
+// compare any two of them, the other comparisons only two numbers or two
+// strings, and "in" looks for a value in a list.
+//
+// The language has no loops, calls or assignments, so a script can't
+// reach beyond the transition it is given, and each run is cut off after
+// MaxSteps steps with ErrScriptLimit.
+type ExprEngine struct {
+	MaxSize  int // longest source accepted, in bytes; 0 is DefaultExprSize
+	MaxSteps int // most steps a run may take; 0 is DefaultExprSteps
+}
+
+// Compile parses src.
+func (e ExprEngine) Compile(name, src string) (Script, error) {
+	size := e.MaxSize
+	if size <= 0 {
+		size = DefaultExprSize
+	}
+	if len(src) > size {
+		return nil, fmt.Errorf("%w: %d bytes of at most %d", ErrScriptLimit, len(src), size)
+	}
+
+	p := &exprParser{src: src}
+	p.next()
+	root, err := p.or()
+	if err == nil && p.tok != "" {
+		err = p.errorf("unexpected %q", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	steps := e.MaxSteps
+	if steps <= 0 {
+		steps = DefaultExprSteps
+	}
+	return exprScript{root: root, steps: steps}, nil
+}
+
+type exprScript struct {
+	root  exprNode
+	steps int
+}
+
+// Run evaluates the expression, which must come out true or false.
+func (s exprScript) Run(ctx context.Context, tc TransitionContext) (bool, error) {
+	r := &exprRun{ctx: ctx, tc: tc, left: s.steps}
+	v, err := s.root.eval(r)
+	if err != nil {
+		return false, err
+	}
+	allow, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("result is %s, not a boolean", exprKind(v))
+	}
+	return allow, nil
+}
+
+// exprRun is the state of a single run.
+type exprRun struct {
+	ctx  context.Context
+	tc   TransitionContext
+	left int // steps
+}
+
+func (r *exprRun) step() error {
+	if r.left--; r.left < 0 {
+		return fmt.Errorf("%w: out of steps", ErrScriptLimit)
+	}
+	if r.left%64 == 0 {
+		return r.ctx.Err()
+	}
+	return nil
+}
+
+type exprNode interface {
+	eval(r *exprRun) (any, error)
+}
+
+type (
+	exprLit  struct{ v any }
+	exprPath []string
+	exprList []exprNode
+	exprNot  struct{ x exprNode }
+	exprOp   struct {
+		op   string
+		x, y exprNode
+	}
+)
+
+func (n exprLit) eval(r *exprRun) (any, error) {
+	return n.v, r.step()
+}
+
+func (n exprList) eval(r *exprRun) (any, error) {
+	if err := r.step(); err != nil {
+		return nil, err
+	}
+	list := make([]any, len(n))
+	for i, x := range n {
+		v, err := x.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (n exprNot) eval(r *exprRun) (any, error) {
+	v, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! of %s", exprKind(v))
+	}
+	return !b, r.step()
+}
+
+func (n exprPath) eval(r *exprRun) (any, error) {
+	if err := r.step(); err != nil {
+		return nil, err
+	}
+	tc := r.tc
+	switch n[0] {
+	case "origin":
+		return string(tc.Origin), nil
+	case "goal":
+		return string(tc.Goal), nil
+	case "event":
+		return tc.Event, nil
+	case "actor":
+		return tc.Actor, nil
+	case "variant":
+		return tc.Variant, nil
+	case "reason":
+		return tc.Reason, nil
+	case "forced":
+		return tc.Forced, nil
+	case "labels":
+		if v, ok := tc.Labels[n[1]]; ok {
+			return v, nil
+		}
+		return nil, nil
+	case "payload":
+		return exprField(r, reflect.ValueOf(tc.Payload), n[1:])
+	default: // "subject", as the parser only lets these through
+		return exprField(r, reflect.ValueOf(tc.Subject), n[1:])
+	}
+}
+
+// exprField looks up the field at path within v.
+func exprField(r *exprRun, v reflect.Value, path []string) (any, error) {
+	for _, name := range path {
+		if err := r.step(); err != nil {
+			return nil, err
+		}
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, nil
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || !f.IsExported() {
+				return nil, nil
+			}
+			field, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				// promoted through an embedded pointer that is nil
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			v = field
+		default:
+			return nil, nil
+		}
+	}
+	return exprValue(v), nil
+}
+
+// exprValue converts v to one of the values of the language.
+func exprValue(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = exprValue(v.Index(i))
+		}
+		return list
+	}
+	return nil
+}
+
+func (n exprOp) eval(r *exprRun) (any, error) {
+	x, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.step(); err != nil {
+		return nil, err
+	}
+
+	if n.op == "&&" || n.op == "||" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s of %s", n.op, exprKind(x))
+		}
+		if b == (n.op == "||") {
+			return b, nil
+		}
+		y, err := n.y.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok = y.(bool); !ok {
+			return nil, fmt.Errorf("%s of %s", n.op, exprKind(y))
+		}
+		return b, nil
+	}
+
+	y, err := n.y.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return exprEqual(x, y), nil
+	case "!=":
+		return !exprEqual(x, y), nil
+	case "in":
+		list, ok := y.([]any)
+		if !ok {
+			return nil, fmt.Errorf("in %s", exprKind(y))
+		}
+		for _, v := range list {
+			if err := r.step(); err != nil {
+				return nil, err
+			}
+			if exprEqual(x, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	var c int
+	switch xv := x.(type) {
+	case float64:
+		yv, ok := y.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s %s %s", exprKind(x), n.op, exprKind(y))
+		}
+		c = compareFloat(xv, yv)
+	case string:
+		yv, ok := y.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s %s %s", exprKind(x), n.op, exprKind(y))
+		}
+		c = strings.Compare(xv, yv)
+	default:
+		return nil, fmt.Errorf("%s %s %s", exprKind(x), n.op, exprKind(y))
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default: // ">="
+		return c >= 0, nil
+	}
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func exprEqual(x, y any) bool {
+	xs, ok := x.([]any)
+	if !ok {
+		_, isList := y.([]any)
+		return !isList && x == y
+	}
+	ys, ok := y.([]any)
+	if !ok || len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !exprEqual(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func exprKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	default:
+		return "a list"
+	}
+}
+
+// exprParser parses expressions by recursive descent, from the loosest
+// binding operator down:
+//
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) operand ]
+//	operand = string | [ "-" ] number | "true" | "false" | "null" | path
+//	        | "(" or ")" | "[" [ or { "," or } ] "]"
+type exprParser struct {
+	src string
+	pos int    // of the end of tok
+	tok string // the current token, empty at the end
+	at  int    // where tok starts
+	err error  // of scanning tok
+}
+
+var errExprSyntax = errors.New("syntax error")
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at %d: %s", errExprSyntax, p.at, fmt.Sprintf(format, args...))
+}
+
+// next scans the following token.
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	p.at = p.pos
+	if p.pos == len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	src, start := p.src, p.pos
+	switch c := src[p.pos]; {
+	case c == '"':
+		for p.pos++; p.pos < len(src) && src[p.pos] != '"'; p.pos++ {
+			if src[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		if p.pos >= len(src) {
+			p.err = p.errorf("unterminated string")
+		}
+		p.pos = min(p.pos+1, len(src))
+	case c >= '0' && c <= '9':
+		for p.pos < len(src) && (src[p.pos] >= '0' && src[p.pos] <= '9' || src[p.pos] == '.') {
+			p.pos++
+		}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(src) && (src[p.pos] == '_' || src[p.pos] >= 'a' && src[p.pos] <= 'z' ||
+			src[p.pos] >= 'A' && src[p.pos] <= 'Z' || src[p.pos] >= '0' && src[p.pos] <= '9') {
+			p.pos++
+		}
+	default:
+		p.pos++
+		if p.pos < len(src) {
+			switch two := src[start : p.pos+1]; two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				p.pos++
+			}
+		}
+	}
+	p.tok = src[start:p.pos]
+}
+
+func (p *exprParser) or() (exprNode, error) {
+	x, err := p.and()
+	for err == nil && p.tok == "||" {
+		p.next()
+		var y exprNode
+		y, err = p.and()
+		x = exprOp{op: "||", x: x, y: y}
+	}
+	return x, err
+}
+
+func (p *exprParser) and() (exprNode, error) {
+	x, err := p.not()
+	for err == nil && p.tok == "&&" {
+		p.next()
+		var y exprNode
+		y, err = p.not()
+		x = exprOp{op: "&&", x: x, y: y}
+	}
+	return x, err
+}
+
+func (p *exprParser) not() (exprNode, error) {
+	if p.tok == "!" {
+		p.next()
+		x, err := p.not()
+		return exprNot{x}, err
+	}
+	return p.compare()
+}
+
+func (p *exprParser) compare() (exprNode, error) {
+	x, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.tok; op {
+	case "==", "!=", "<", "<=", ">", ">=", "in":
+		p.next()
+		y, err := p.operand()
+		return exprOp{op: op, x: x, y: y}, err
+	}
+	return x, nil
+}
+
+func (p *exprParser) operand() (exprNode, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, p.errorf("unexpected end")
+	case tok == "(":
+		p.next()
+		x, err := p.or()
+		if err == nil && p.tok != ")" {
+			err = p.errorf("expected )")
+		}
+		p.next()
+		return x, err
+	case tok == "[":
+		var list exprList
+		for p.next(); p.tok != "]"; {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, x)
+			if p.tok == "," {
+				p.next()
+			} else if p.tok != "]" {
+				return nil, p.errorf("expected , or ]")
+			}
+		}
+		p.next()
+		return list, nil
+	case tok[0] == '"':
+		s, err := strconv.Unquote(tok)
+		if err != nil {
+			return nil, p.errorf("bad string %s", tok)
+		}
+		p.next()
+		return exprLit{s}, nil
+	case tok[0] >= '0' && tok[0] <= '9':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("bad number %s", tok)
+		}
+		p.next()
+		return exprLit{f}, nil
+	case tok == "-":
+		p.next()
+		x, err := p.operand()
+		if lit, ok := x.(exprLit); ok && err == nil {
+			if f, ok := lit.v.(float64); ok {
+				return exprLit{-f}, nil
+			}
+		}
+		if err == nil {
+			err = p.errorf("- of something other than a number")
+		}
+		return nil, err
+	case tok == "true" || tok == "false":
+		p.next()
+		return exprLit{tok == "true"}, nil
+	case tok == "null":
+		p.next()
+		return exprLit{nil}, nil
+	}
+	return p.path()
+}
+
+func (p *exprParser) path() (exprNode, error) {
+	path := exprPath{p.tok}
+	switch p.tok {
+	case "origin", "goal", "event", "actor", "variant", "reason", "forced":
+		p.next()
+		return path, nil
+	case "labels", "payload", "subject":
+	default:
+		return nil, p.errorf("unknown name %q", p.tok)
+	}
+
+	for p.next(); p.tok == "."; {
+		p.next()
+		if p.tok == "" || !(p.tok[0] == '_' || p.tok[0] >= 'a' && p.tok[0] <= 'z' || p.tok[0] >= 'A' && p.tok[0] <= 'Z') {
+			return nil, p.errorf("expected a field name after .")
+		}
+		path = append(path, p.tok)
+		p.next()
+	}
+	if path[0] == "labels" && len(path) != 2 {
+		return nil, p.errorf("labels take one name, as in labels.tenant")
+	}
+	return path, nil
+}
@@ -0,0 +1,109 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type order struct {
+	State  fsm.State
+	Amount int
+	Tags   []string
+	secret string
+}
+
+func (o *order) CurrentState() fsm.State  { return o.State }
+func (o *order) SetState(state fsm.State) { o.State = state }
+
+func TestExprEngine(t *testing.T) {
+	tc := fsm.TransitionContext{
+		Subject: &order{State: "pending", Amount: 250, Tags: []string{"gift"}, secret: "x"},
+		Origin:  "pending",
+		Goal:    "approved",
+		Actor:   "alice",
+		Labels:  map[string]string{"tenant": "acme"},
+		Payload: map[string]interface{}{"note": map[string]interface{}{"urgent": true}},
+	}
+
+	for i, ex := range []struct {
+		src  string
+		want bool
+	}{
+		{`goal == "approved" && origin != "approved"`, true},
+		{`subject.Amount < 100 || labels.tenant == "acme"`, true},
+		{`subject.Amount >= 250 && !(actor in ["bob", "carol"])`, true},
+		{`"gift" in subject.Tags`, true},
+		{`payload.note.urgent`, true},
+		{`payload.missing == null && subject.secret == null`, true},
+		{`subject.Amount > -1 && "b" > "a"`, true},
+		{`labels.region == "eu"`, false},
+		{`forced`, false},
+	} {
+		script, err := fsm.ExprEngine{}.Compile("example", ex.src)
+		st.Expect(t, err, nil, i)
+		allow, err := script.Run(context.Background(), tc)
+		st.Expect(t, err, nil, i)
+		st.Expect(t, allow, ex.want, i)
+	}
+
+	for i, src := range []string{
+		``,
+		`goal ==`,
+		`goal == "approved" extra`,
+		`os.Exit(1)`,
+		`"unterminated`,
+		`labels.a.b`,
+		`(goal == "x"`,
+	} {
+		_, err := fsm.ExprEngine{}.Compile("broken", src)
+		st.Expect(t, err != nil, true, i)
+	}
+
+	for i, src := range []string{
+		`subject.Amount < "many"`,
+		`goal`,
+		`!actor`,
+	} {
+		script, err := fsm.ExprEngine{}.Compile("mistyped", src)
+		st.Expect(t, err, nil, i)
+		_, err = script.Run(context.Background(), tc)
+		st.Expect(t, err != nil, true, i)
+	}
+
+	// the resources a script takes are limited
+	_, err := fsm.ExprEngine{MaxSize: 8}.Compile("long", `goal == "approved"`)
+	st.Expect(t, errors.Is(err, fsm.ErrScriptLimit), true)
+
+	script, err := fsm.ExprEngine{MaxSteps: 5}.Compile("slow", `actor in ["a", "b", "c", "d", "e", "f"]`)
+	st.Expect(t, err, nil)
+	_, err = script.Run(context.Background(), tc)
+	st.Expect(t, errors.Is(err, fsm.ErrScriptLimit), true)
+}
+
+type Customer struct{ Tier string }
+
+// referred has fields promoted through an embedded pointer, which may be nil.
+type referred struct {
+	State fsm.State
+	*Customer
+}
+
+func (r *referred) CurrentState() fsm.State  { return r.State }
+func (r *referred) SetState(state fsm.State) { r.State = state }
+
+func TestExprNilEmbedded(t *testing.T) {
+	check, err := fsm.ScriptCheck(fsm.ExprEngine{}, "gold", `subject.Tier == "gold"`, 0)
+	st.Expect(t, err, nil)
+
+	tc := fsm.TransitionContext{Subject: &referred{State: "pending"}, Origin: "pending", Goal: "approved"}
+	err = check(tc)
+	st.Expect(t, err != nil, true)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), false)
+
+	tc.Subject = &referred{State: "pending", Customer: &Customer{Tier: "gold"}}
+	st.Expect(t, check(tc), nil)
+}
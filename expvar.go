@@ -0,0 +1,65 @@
+package fsm
+
+import "expvar"
+
+// Metrics names the counters of s after the style of runtime/metrics, as
+// "/fsm/<what>:<unit>":
+//
+//	/fsm/transitions:transitions
+//	/fsm/transitions/<origin>-><goal>:transitions
+//	/fsm/rejections/<reason>:rejections
+//	/fsm/guards:seconds
+//	/fsm/states/<state>/time:seconds
+//
+// Times are totals, so rates and means can be derived from successive
+// readings.
+func (s Stats) Metrics() map[string]any {
+	metrics := map[string]any{
+		"/fsm/transitions:transitions": s.Transitions,
+		"/fsm/guards:seconds":          s.Guards.Total.Seconds(),
+	}
+	for t, n := range s.Counts {
+		metrics["/fsm/transitions/"+string(t.O)+"->"+string(t.E)+":transitions"] = n
+	}
+	for reason, n := range s.Rejections {
+		metrics["/fsm/rejections/"+reason+":rejections"] = n
+	}
+	for state, l := range s.Durations {
+		metrics["/fsm/states/"+string(state)+"/time:seconds"] = l.Total.Seconds()
+	}
+	return metrics
+}
+
+// Metrics is like Stats.Metrics, adding the population of the registry:
+//
+//	/fsm/machines:machines
+//	/fsm/states/<state>:machines
+func (rs RegistryStats) Metrics() map[string]any {
+	metrics := rs.Stats.Metrics()
+	metrics["/fsm/machines:machines"] = rs.Machines
+	for state, n := range rs.States {
+		metrics["/fsm/states/"+string(state)+":machines"] = n
+	}
+	return metrics
+}
+
+// WithExpvar is intended to be passed to New to publish the machine's
+// Metrics through expvar, and so on /debug/vars, under name. Names must be
+// unique within the process; expvar panics otherwise.
+func WithExpvar(name string) func(*Machine) {
+	return func(m *Machine) {
+		expvar.Publish(name, expvar.Func(func() any {
+			return m.Stats().Metrics()
+		}))
+	}
+}
+
+// WithRegistryExpvar is intended to be passed to NewRegistry to publish
+// the registry's Metrics through expvar under name, like WithExpvar.
+func WithRegistryExpvar(name string) func(*Registry) {
+	return func(r *Registry) {
+		expvar.Publish(name, expvar.Func(func() any {
+			return r.Stats().Metrics()
+		}))
+	}
+}
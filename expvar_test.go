@@ -0,0 +1,40 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestWithExpvar(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithExpvar("fsm_test_machine"),
+	)
+	registry := fsm.NewRegistry(fsm.WithRegistryExpvar("fsm_test_registry"))
+	registry.Put("some_thing", the_machine)
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+
+	read := func(name string) map[string]float64 {
+		var metrics map[string]float64
+		st.Expect(t, json.Unmarshal([]byte(expvar.Get(name).String()), &metrics), nil)
+		return metrics
+	}
+
+	metrics := read("fsm_test_machine")
+	st.Expect(t, metrics["/fsm/transitions:transitions"], float64(1))
+	st.Expect(t, metrics["/fsm/transitions/pending->started:transitions"], float64(1))
+	st.Expect(t, metrics["/fsm/rejections/invalid:rejections"], float64(1))
+
+	metrics = read("fsm_test_registry")
+	st.Expect(t, metrics["/fsm/machines:machines"], float64(1))
+	st.Expect(t, metrics["/fsm/states/started:machines"], float64(1))
+	st.Expect(t, metrics["/fsm/transitions:transitions"], float64(1))
+}
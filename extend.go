@@ -0,0 +1,79 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrOrphanedState = errors.New("state left unreachable")
+
+// Extension is a Ruleset derived from a parent, as created by Extend.
+type Extension struct {
+	parent  Ruleset
+	overlay Overlay
+}
+
+// Extend starts a ruleset that inherits every transition of parent, for
+// specializing a base lifecycle. Call Build for the result; parent is never
+// changed.
+func Extend(parent Ruleset) *Extension {
+	return &Extension{
+		parent: parent,
+		overlay: Overlay{
+			Add:      Ruleset{},
+			Restrict: Ruleset{},
+		},
+	}
+}
+
+// AddTransition adds a transition, possibly to a new state, protected by
+// guards.
+func (e *Extension) AddTransition(t Transition, guards ...Guard) *Extension {
+	e.overlay.Add.AddTransition(t)
+	e.overlay.Add.AddRule(t, guards...)
+	return e
+}
+
+// AddGuards protects an inherited or added transition with more guards.
+func (e *Extension) AddGuards(t Transition, guards ...Guard) *Extension {
+	e.overlay.Restrict.AddRule(t, guards...)
+	return e
+}
+
+// Remove drops inherited transitions.
+func (e *Extension) Remove(ts ...Transition) *Extension {
+	e.overlay.Remove = append(e.overlay.Remove, ts...)
+	return e
+}
+
+// Build returns the child ruleset. It fails if the extension guards or
+// removes transitions that don't exist, or if removals leave a state with
+// ways out but, unlike in the parent, no way in. States whose transitions
+// are all removed are simply gone.
+func (e *Extension) Build() (Ruleset, error) {
+	if err := e.overlay.validate(e.parent); err != nil {
+		return nil, err
+	}
+
+	child := e.overlay.apply(e.parent)
+
+	entered, exited := map[State]bool{}, map[State]bool{}
+	for t := range child {
+		entered[t.Exit()] = true
+		exited[t.Origin()] = true
+	}
+	var errs []error
+	for _, t := range e.overlay.Remove {
+		for _, t := range expand(t) {
+			if s := t.Exit(); exited[s] && !entered[s] {
+				entered[s] = true // report each state once
+				errs = append(errs, fmt.Errorf("%w: %s", ErrOrphanedState, s))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return child, nil
+}
@@ -0,0 +1,65 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+var documentLifecycle = fsm.CreateRuleset(
+	fsm.T{"draft", "review"},
+	fsm.T{"review", "draft"},
+	fsm.T{"review", "published"},
+	fsm.T{"published", "archived"},
+)
+
+func TestExtend(t *testing.T) {
+	deny := func(subject fsm.Stater, goal fsm.State) bool { return false }
+
+	contracts, err := fsm.Extend(documentLifecycle).
+		AddTransition(fsm.T{"review", "legal"}).
+		AddTransition(fsm.T{"legal", "published"}).
+		AddGuards(fsm.T{"review", "published"}, deny).
+		Remove(fsm.T{"published", "archived"}).
+		Build()
+	st.Expect(t, err, nil)
+
+	examples := []struct {
+		rules  fsm.Ruleset
+		origin fsm.State
+		goal   fsm.State
+		ok     bool
+	}{
+		{contracts, "draft", "review", true},
+		{contracts, "review", "legal", true},
+		{contracts, "legal", "published", true},
+		{contracts, "review", "published", false},
+		{contracts, "published", "archived", false},
+		{documentLifecycle, "review", "published", true},
+		{documentLifecycle, "review", "legal", false},
+		{documentLifecycle, "published", "archived", true},
+	}
+
+	for i, ex := range examples {
+		st.Expect(t, ex.rules.Permitted(&Thing{State: ex.origin}, ex.goal), ex.ok, i)
+	}
+}
+
+func TestExtendRejectsOrphans(t *testing.T) {
+	_, err := fsm.Extend(documentLifecycle).
+		Remove(fsm.T{"review", "published"}).
+		Build()
+	st.Expect(t, errors.Is(err, fsm.ErrOrphanedState), true)
+
+	_, err = fsm.Extend(documentLifecycle).
+		Remove(fsm.T{"review", "published"}, fsm.T{"published", "archived"}).
+		Build()
+	st.Expect(t, err, nil)
+
+	_, err = fsm.Extend(documentLifecycle).
+		AddGuards(fsm.T{"draft", "archived"}).
+		Build()
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownTransition), true)
+}
@@ -0,0 +1,108 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrFailpoint is returned by failpoints enabled without an error of their
+// own.
+var ErrFailpoint = errors.New("failpoint")
+
+// Step names a point of a transition where a failpoint can fail it.
+type Step string
+
+const (
+	StepGuards      Step = "guards"      // after the guards passed
+	StepPersistence Step = "persistence" // instead of recording the event in the EventStore
+	StepActions     Step = "actions"     // instead of running the actions
+	StepHooks       Step = "hooks"       // hooks panic
+)
+
+// Failpoints fails chosen transitions of chosen machines at a chosen Step,
+// so tests can reproduce failure scenarios exactly. Machines opt in with
+// WithFailpoints, under a name.
+type Failpoints struct {
+	mu       sync.Mutex
+	points   map[failpointKey]error
+	attempts map[string]int
+}
+
+type failpointKey struct {
+	machine string
+	step    Step
+	nth     int
+}
+
+// NewFailpoints returns Failpoints with none enabled.
+func NewFailpoints() *Failpoints {
+	return &Failpoints{points: map[failpointKey]error{}, attempts: map[string]int{}}
+}
+
+// Enable fails the nth transition attempted by machine, counting from 1, at
+// step with err, or ErrFailpoint if err is nil. An nth of 0 fails every
+// transition.
+func (f *Failpoints) Enable(machine string, step Step, nth int, err error) {
+	if err == nil {
+		err = ErrFailpoint
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.points[failpointKey{machine, step, nth}] = err
+}
+
+// Disable removes the failpoint set by Enable.
+func (f *Failpoints) Disable(machine string, step Step, nth int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.points, failpointKey{machine, step, nth})
+}
+
+// Reset removes every failpoint and restarts the count of attempts.
+func (f *Failpoints) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.points)
+	clear(f.attempts)
+}
+
+type machineFailpoints struct {
+	*Failpoints
+	machine string
+}
+
+// WithFailpoints is intended to be passed to New to subject the machine to
+// the failpoints of f enabled for machine.
+func WithFailpoints(f *Failpoints, machine string) func(*Machine) {
+	return func(m *Machine) {
+		m.failpoints = &machineFailpoints{f, machine}
+	}
+}
+
+// attempt counts a transition attempt, returning its number.
+func (f *machineFailpoints) attempt() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts[f.machine]++
+	return f.attempts[f.machine]
+}
+
+// at returns the error of a failpoint at step of the nth attempt, if any.
+func (f *machineFailpoints) at(step Step, nth int) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, n := range []int{nth, 0} {
+		if err, ok := f.points[failpointKey{f.machine, step, n}]; ok {
+			return fmt.Errorf("%s %s of transition %d: %w", f.machine, step, nth, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestFailpoints(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	errDiskFull := errors.New("disk full")
+
+	failpoints := fsm.NewFailpoints()
+	failpoints.Enable("order-1", fsm.StepPersistence, 3, errDiskFull)
+	failpoints.Enable("order-1", fsm.StepGuards, 5, nil)
+	failpoints.Enable("order-2", fsm.StepActions, 0, nil)
+
+	newMachine := func(name string, s *Thing) fsm.Machine {
+		return fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(s),
+			fsm.WithEventStore(&fsm.MemoryEventStore{}, 0),
+			fsm.WithFailpoints(failpoints, name),
+		)
+	}
+
+	order1 := Thing{State: "pending"}
+	the_machine := newMachine("order-1", &order1)
+	goals := []fsm.State{"started", "pending", "started", "started", "pending", "pending"}
+	errs := make([]error, len(goals))
+	for i, goal := range goals {
+		errs[i] = the_machine.Transition(goal)
+	}
+	st.Expect(t, errs[0], nil)
+	st.Expect(t, errs[1], nil)
+	st.Expect(t, errors.Is(errs[2], errDiskFull), true)
+	st.Expect(t, errs[3], nil)
+	st.Expect(t, errors.Is(errs[4], fsm.ErrFailpoint), true)
+	st.Expect(t, errs[5], nil)
+	st.Expect(t, order1.State, fsm.State("pending"))
+
+	order2 := Thing{State: "pending"}
+	other_machine := newMachine("order-2", &order2)
+	st.Expect(t, errors.Is(other_machine.Transition("started"), fsm.ErrFailpoint), true)
+
+	failpoints.Reset()
+	st.Expect(t, other_machine.Transition("pending"), nil)
+}
@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Action does the work of a transition once the subject has entered the
+// goal state. Unlike a Hook it can fail, which sends the machine to its
+// error state if it has one.
+type Action func(tc TransitionContext) error
+
+// WithActions is intended to be passed to New to run actions, in order,
+// after each transition t. The first error stops them.
+func WithActions(t Transition, actions ...Action) func(*Machine) {
+	return func(m *Machine) {
+		if m.actions == nil {
+			m.actions = map[T][]Action{}
+		}
+		for _, t := range expand(t) {
+			key := T{t.Origin(), t.Exit()}
+			m.actions[key] = append(m.actions[key], actions...)
+		}
+	}
+}
+
+func (m Machine) act(tc TransitionContext) error {
+	if len(m.actions) == 0 {
+		return nil
+	}
+	// those of the closest state with any, as with guards
+	for _, s := range m.hierarchy.lineage(tc.Origin) {
+		actions, ok := m.actions[T{s, tc.Goal}]
+		if !ok {
+			continue
+		}
+		for _, action := range actions {
+			if err := action(tc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// WithErrorState is intended to be passed to New to move the subject to
+// state whenever a transition fails after its guards and checks have
+// passed: when its event can't be recorded, or one of its Actions fails.
+// The move is forced, so state needn't be reachable in the Rules, and is
+// recorded with the cause of the failure. Its hooks are called, and it is
+// snapshotted and retained, like any other transition.
+//
+// A transition whose Actions fail has moved the subject already, so
+// without an error state the subject stays in the goal: the transition's
+// hooks are still called and its error returned.
+func WithErrorState(state State) func(*Machine) {
+	return func(m *Machine) {
+		m.errorState = state
+	}
+}
+
+// WithErrorStateFor is like WithErrorState but only for failures of t,
+// taking precedence over the machine's error state.
+func WithErrorStateFor(t Transition, state State) func(*Machine) {
+	return func(m *Machine) {
+		if m.errorStates == nil {
+			m.errorStates = map[T]State{}
+		}
+		for _, t := range expand(t) {
+			m.errorStates[T{t.Origin(), t.Exit()}] = state
+		}
+	}
+}
+
+// FailedTransitionError reports a transition that failed after it was
+// permitted, and the error state the subject was moved to because of it.
+type FailedTransitionError struct {
+	Origin, Goal State
+	ErrorState   State
+	Err          error
+}
+
+func (e *FailedTransitionError) Error() string {
+	return fmt.Sprintf("%s -> %s failed, moved to %s: %v", e.Origin, e.Goal, e.ErrorState, e.Err)
+}
+
+func (e *FailedTransitionError) Unwrap() error { return e.Err }
+
+// fail routes the subject from its current state, from, to the error state
+// for tc. Without one, err is returned as it is.
+func (m Machine) fail(tc TransitionContext, from State, err error) error {
+	to := m.errorState
+	for _, s := range m.hierarchy.lineage(tc.Origin) {
+		if state, ok := m.errorStates[T{s, tc.Goal}]; ok {
+			to = state
+			break
+		}
+	}
+	if to == "" {
+		return err
+	}
+	to = m.recall.resolve(to)
+
+	e := m.newEvent(tc, m.now())
+	e.Origin, e.Goal, e.Cause = from, to, err.Error()
+	if m.log != nil {
+		// best effort; the store may well be what failed
+		if logged, lerr := m.log.record(tc.Context(), e, m.chain); lerr == nil {
+			e = logged
+		}
+	}
+
+	m.Subject.SetState(to)
+	m.recall.observe(to)
+	m.stats.enter(e.Time)
+	m.history.record(e, m.chain)
+	m.approvals.reset()
+	m.timers.reset()
+
+	// the move to the error state is a change like any other
+	moved := tc
+	moved.Origin, moved.Goal = from, to
+	m.callHooks(moved)
+
+	failure := &FailedTransitionError{Origin: tc.Origin, Goal: tc.Goal, ErrorState: to, Err: err}
+	if serr := m.settle(moved, e); serr != nil {
+		return errors.Join(failure, serr)
+	}
+	return failure
+}
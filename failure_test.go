@@ -0,0 +1,110 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type brokenStore struct {
+	fsm.MemoryEventStore
+	broken bool
+}
+
+var errStoreDown = errors.New("store down")
+
+func (s *brokenStore) Append(e fsm.TransitionEvent) error {
+	if s.broken {
+		return errStoreDown
+	}
+	return s.MemoryEventStore.Append(e)
+}
+
+func TestErrorStateOnFailedAction(t *testing.T) {
+	errDeclined := errors.New("card declined")
+	var hooked []string
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "charged"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHistory(),
+		fsm.WithActions(fsm.T{"pending", "charged"}, func(tc fsm.TransitionContext) error { return errDeclined }),
+		fsm.WithErrorState("failed"),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { hooked = append(hooked, string(tc.Origin)+"->"+string(tc.Goal)) }),
+	)
+
+	err := the_machine.Transition("charged")
+	var failed *fsm.FailedTransitionError
+	st.Expect(t, errors.As(err, &failed), true)
+	st.Expect(t, failed.ErrorState, fsm.State("failed"))
+	st.Expect(t, errors.Is(err, errDeclined), true)
+	st.Expect(t, some_thing.State, fsm.State("failed"))
+	st.Expect(t, hooked, []string{"pending->charged", "charged->failed"})
+
+	history := the_machine.History()
+	st.Expect(t, len(history), 2)
+	st.Expect(t, history[1].Origin, fsm.State("charged"))
+	st.Expect(t, history[1].Goal, fsm.State("failed"))
+	st.Expect(t, history[1].Cause, "card declined")
+}
+
+func TestErrorStateOnFailedPersistence(t *testing.T) {
+	store := &brokenStore{broken: true}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 0),
+		fsm.WithErrorState("failed"),
+		fsm.WithErrorStateFor(fsm.T{"pending", "started"}, "stalled"),
+	)
+
+	err := the_machine.Transition("started")
+	st.Expect(t, errors.Is(err, errStoreDown), true)
+	st.Expect(t, some_thing.State, fsm.State("stalled"))
+}
+
+func TestNoErrorState(t *testing.T) {
+	store := &brokenStore{broken: true}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 0),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), errStoreDown)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+}
+
+func TestFailedActionWithoutErrorState(t *testing.T) {
+	// The subject has moved by the time its actions run, so a failed
+	// action leaves it in the goal, counted and hooked like any other.
+	errDeclined := errors.New("card declined")
+	var hooked int
+
+	store := &fsm.MemoryEventStore{}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "charged"}, fsm.T{"charged", "pending"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 1),
+		fsm.WithActions(fsm.T{"pending", "charged"}, func(tc fsm.TransitionContext) error { return errDeclined }),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { hooked++ }),
+		fsm.Limit(fsm.T{"pending", "charged"}, 1),
+	)
+
+	st.Expect(t, the_machine.Transition("charged"), errDeclined)
+	st.Expect(t, some_thing.State, fsm.State("charged"))
+	st.Expect(t, hooked, 1)
+
+	snap, ok, _ := store.LatestSnapshot()
+	st.Expect(t, ok, true)
+	st.Expect(t, snap.State, fsm.State("charged"))
+
+	st.Expect(t, the_machine.Transition("pending"), nil)
+	st.Expect(t, errors.Is(the_machine.Transition("charged"), fsm.ErrLimitExceeded), true)
+}
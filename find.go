@@ -0,0 +1,179 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrQuery = errors.New("bad registry query")
+
+// Filter selects machines of a Registry for Find. The zero Filter selects
+// every machine; each method returns a copy that also requires something
+// more, so filters compose:
+//
+//	registry.Find(fsm.InState("pending").OlderThan(time.Hour).WithLabel("tenant", "acme"))
+type Filter struct {
+	preds []func(key string, m Machine, now time.Time) bool
+	clock Clock
+}
+
+func (f Filter) where(pred func(key string, m Machine, now time.Time) bool) Filter {
+	f.preds = append(append([]func(string, Machine, time.Time) bool(nil), f.preds...), pred)
+	return f
+}
+
+// InState selects machines in any of states.
+func InState(states ...State) Filter { return Filter{}.InState(states...) }
+
+// OlderThan selects machines that entered their state more than d ago.
+func OlderThan(d time.Duration) Filter { return Filter{}.OlderThan(d) }
+
+// Labelled selects machines labelled name=value.
+func Labelled(name, value string) Filter { return Filter{}.WithLabel(name, value) }
+
+// InState narrows f to machines in any of states.
+func (f Filter) InState(states ...State) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		return StateSet(states).Contains(m.Subject.CurrentState())
+	})
+}
+
+// OlderThan narrows f to machines that entered their state more than d
+// ago, that is, that haven't transitioned in that long.
+func (f Filter) OlderThan(d time.Duration) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		return now.Sub(m.Since()) > d
+	})
+}
+
+// YoungerThan narrows f to machines that entered their state less than d
+// ago.
+func (f Filter) YoungerThan(d time.Duration) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		return now.Sub(m.Since()) < d
+	})
+}
+
+// WithLabel narrows f to machines labelled name=value.
+func (f Filter) WithLabel(name, value string) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		return m.HasLabels(map[string]string{name: value})
+	})
+}
+
+// KeyMatches narrows f to machines whose key matches pattern, in the
+// syntax of path.Match.
+func (f Filter) KeyMatches(pattern string) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	})
+}
+
+// Where narrows f to machines for which pred returns true.
+func (f Filter) Where(pred func(key string, m Machine) bool) Filter {
+	return f.where(func(key string, m Machine, now time.Time) bool {
+		return pred(key, m)
+	})
+}
+
+// At sets the clock that ages are measured with. It defaults to
+// SystemClock.
+func (f Filter) At(c Clock) Filter {
+	f.clock = c
+	return f
+}
+
+// Match reports whether f selects the machine m stored under key.
+func (f Filter) Match(key string, m Machine) bool {
+	clock := f.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return f.match(key, m, clock.Now())
+}
+
+func (f Filter) match(key string, m Machine, now time.Time) bool {
+	for _, pred := range f.preds {
+		if !pred(key, m, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the keys of the machines f selects, sorted.
+func (r *Registry) Find(f Filter) []string {
+	clock := f.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+
+	var keys []string
+	r.Range(func(key string, m Machine) bool {
+		if f.match(key, m, now) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ParseFilter reads a Filter from a query of space separated terms, all
+// of which must hold:
+//
+//	state=pending,retrying   in any of the states
+//	age>1h                   in the state for more than an hour
+//	age<5m                   in the state for less than five minutes
+//	label.tenant=acme        labelled tenant=acme
+//	key=order-*              key matches the pattern
+func ParseFilter(query string) (Filter, error) {
+	var f Filter
+	for _, term := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(term, "state="):
+			var states []State
+			for _, s := range strings.Split(strings.TrimPrefix(term, "state="), ",") {
+				states = append(states, State(s))
+			}
+			f = f.InState(states...)
+
+		case strings.HasPrefix(term, "age>"), strings.HasPrefix(term, "age<"):
+			d, err := time.ParseDuration(term[len("age>"):])
+			if err != nil {
+				return Filter{}, fmt.Errorf("%w: %q: %w", ErrQuery, term, err)
+			}
+			if term[3] == '>' {
+				f = f.OlderThan(d)
+			} else {
+				f = f.YoungerThan(d)
+			}
+
+		case strings.HasPrefix(term, "label."):
+			name, value, ok := strings.Cut(strings.TrimPrefix(term, "label."), "=")
+			if !ok || name == "" {
+				return Filter{}, fmt.Errorf("%w: %q", ErrQuery, term)
+			}
+			f = f.WithLabel(name, value)
+
+		case strings.HasPrefix(term, "key="):
+			pattern := strings.TrimPrefix(term, "key=")
+			if _, err := path.Match(pattern, ""); err != nil {
+				return Filter{}, fmt.Errorf("%w: %q: %w", ErrQuery, term, err)
+			}
+			f = f.KeyMatches(pattern)
+
+		default:
+			return Filter{}, fmt.Errorf("%w: %q", ErrQuery, term)
+		}
+	}
+
+	return f, nil
+}
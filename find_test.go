@@ -0,0 +1,59 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistryFind(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := fsm.ClockFunc(func() time.Time { return now })
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+
+	registry := fsm.NewRegistry()
+	put := func(key, tenant string) {
+		registry.Put(key, fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithClock(clock),
+			fsm.WithLabels(map[string]string{"tenant": tenant}),
+		))
+	}
+
+	put("order-1", "acme")
+	put("order-2", "globex")
+	now = now.Add(2 * time.Hour)
+	put("order-3", "acme")
+	put("invoice-1", "acme")
+	st.Expect(t, registry.Transition("order-3", "started"), nil)
+
+	examples := []struct {
+		filter fsm.Filter
+		query  string
+		keys   []string
+	}{
+		{fsm.Filter{}, "", []string{"invoice-1", "order-1", "order-2", "order-3"}},
+		{fsm.InState("pending"), "state=pending", []string{"invoice-1", "order-1", "order-2"}},
+		{fsm.InState("pending").OlderThan(time.Hour), "state=pending age>1h", []string{"order-1", "order-2"}},
+		{fsm.InState("pending").OlderThan(time.Hour).WithLabel("tenant", "acme"), "state=pending age>1h label.tenant=acme", []string{"order-1"}},
+		{fsm.Labelled("tenant", "acme").KeyMatches("order-*"), "label.tenant=acme key=order-*", []string{"order-1", "order-3"}},
+		{fsm.InState("started", "pending").YoungerThan(time.Hour), "state=started,pending age<1h", []string{"invoice-1", "order-3"}},
+	}
+
+	for i, ex := range examples {
+		st.Expect(t, registry.Find(ex.filter.At(clock)), ex.keys, i)
+
+		parsed, err := fsm.ParseFilter(ex.query)
+		st.Expect(t, err, nil, i)
+		st.Expect(t, registry.Find(parsed.At(clock)), ex.keys, i)
+	}
+
+	for i, query := range []string{"status=pending", "age>soon", "label.=x", "key=[", "label.tenant"} {
+		_, err := fsm.ParseFilter(query)
+		st.Expect(t, errors.Is(err, fsm.ErrQuery), true, i)
+	}
+}
@@ -0,0 +1,61 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrFlagDisabled = errors.New("feature flag disabled")
+
+// FlagProvider answers whether a feature flag is enabled for a subject.
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string, subject Stater) bool
+}
+
+// FlagGuardCtx creates a GuardCtx that only permits its transition while
+// flag is enabled for the subject, so transitions can be rolled out behind
+// feature flags. The provider is asked with the context of the transition,
+// e.g.
+//
+//	fsm.WithGuardsCtx(fsm.T{"cart", "express_checkout"}, fsm.FlagGuardCtx(flags, "express-checkout"))
+//
+// and a refusal is reported as a *GuardError wrapping ErrFlagDisabled.
+func FlagGuardCtx(p FlagProvider, flag string) GuardCtx {
+	return func(ctx context.Context, subject Stater, goal State) error {
+		if !p.Enabled(ctx, flag, subject) {
+			return fmt.Errorf("%w: %q", ErrFlagDisabled, flag)
+		}
+		return nil
+	}
+}
+
+// FlagGuard creates a Guard for rules, which are consulted without a
+// context, that only permits its transition while flag is enabled for the
+// subject. The provider is asked with context.Background, so prefer
+// FlagGuardCtx unless the flag has to be part of the Rules themselves, e.g.
+//
+//	rules.AddRule(fsm.T{"cart", "express_checkout"}, fsm.FlagGuard(flags, "express-checkout"))
+func FlagGuard(p FlagProvider, flag string) Guard {
+	return func(subject Stater, goal State) bool {
+		return p.Enabled(context.Background(), flag, subject)
+	}
+}
+
+// Flags lets the transitions of the builder, and of the definitions it
+// loads, be gated by the flags of p: a transition protected by the guard
+// "flag:express-checkout", or defined with "flag": "express-checkout", is
+// only permitted while that flag is enabled.
+func (b *Builder) Flags(p FlagProvider) *Builder {
+	return b.GuardFactory("flag", func(flag string) (Guard, error) {
+		return FlagGuard(p, flag), nil
+	})
+}
+
+// StaticFlags is a FlagProvider backed by a fixed set of flags that are
+// enabled for every subject.
+type StaticFlags map[string]bool
+
+func (f StaticFlags) Enabled(ctx context.Context, flag string, subject Stater) bool {
+	return f[flag]
+}
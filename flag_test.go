@@ -0,0 +1,77 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestFlagGuard(t *testing.T) {
+	flags := fsm.StaticFlags{}
+
+	rules := fsm.CreateRuleset(fsm.T{"cart", "express_checkout"})
+	rules.AddRule(fsm.T{"cart", "express_checkout"}, fsm.FlagGuard(flags, "express-checkout"))
+
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "express_checkout"), false)
+
+	flags["express-checkout"] = true
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "express_checkout"), true)
+}
+
+func TestFlagsInDefinition(t *testing.T) {
+	flags := fsm.StaticFlags{}
+	rules, err := fsm.NewBuilder().
+		Flags(flags).
+		LoadJSONWithParams(strings.NewReader(`{
+		  "params": {"rollout": {"default": "express-checkout"}},
+		  "transitions": [
+		    {"from": "cart", "to": "checkout"},
+		    {"from": "cart", "to": "express_checkout", "flag": "${rollout}"}
+		  ]
+		}`), nil).
+		Build()
+	st.Expect(t, err, nil)
+
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "checkout"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "express_checkout"), false)
+
+	flags["express-checkout"] = true
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "express_checkout"), true)
+
+	// without a FlagProvider the flag can't be resolved
+	_, err = fsm.NewBuilder().
+		LoadJSON(strings.NewReader(`{"transitions": [{"from": "cart", "to": "express_checkout", "flag": "express-checkout"}]}`)).
+		Build()
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownGuard), true)
+}
+
+// contextFlags enables the flags named in the context it is asked with.
+type contextFlags struct{}
+
+type flagKey struct{}
+
+func (contextFlags) Enabled(ctx context.Context, flag string, subject fsm.Stater) bool {
+	return ctx.Value(flagKey{}) == flag
+}
+
+func TestFlagGuardCtx(t *testing.T) {
+	some_thing := Thing{State: "cart"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "cart", E: "express_checkout"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithGuardsCtx(fsm.T{O: "cart", E: "express_checkout"}, fsm.FlagGuardCtx(contextFlags{}, "express-checkout")),
+	)
+
+	err := the_machine.TransitionCtx(context.Background(), "express_checkout")
+	var guard *fsm.GuardError
+	st.Expect(t, errors.As(err, &guard), true)
+	st.Expect(t, errors.Is(err, fsm.ErrFlagDisabled), true)
+
+	ctx := context.WithValue(context.Background(), flagKey{}, "express-checkout")
+	st.Expect(t, the_machine.TransitionCtx(ctx, "express_checkout"), nil)
+	st.Expect(t, some_thing.State, fsm.State("express_checkout"))
+}
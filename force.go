@@ -0,0 +1,96 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoReason   = errors.New("forced transition needs a reason and an actor")
+	ErrFinalState = errors.New("subject is in a final state")
+)
+
+// Override lifts one of the protections ForceTransition keeps in place.
+type Override int
+
+const (
+	// OverrideFinal lets ForceTransition move a subject out of a final
+	// state.
+	OverrideFinal Override = iota + 1
+)
+
+// WithFinalStates is intended to be passed to New to declare states the
+// subject must never leave. The Rules already keep a subject in them;
+// declaring them also stops ForceTransition, unless OverrideFinal is given.
+func WithFinalStates(states ...State) func(*Machine) {
+	return func(m *Machine) {
+		m.finals = append(m.finals, states...)
+	}
+}
+
+// WithForceHooks is intended to be passed to New to register Hooks that are
+// only called for transitions made by ForceTransition, so that operators
+// can be alerted to them. The machine's other hooks are called as well.
+func WithForceHooks(hooks ...Hook) func(*Machine) {
+	return func(m *Machine) {
+		m.forceHooks = append(m.forceHooks, hooks...)
+	}
+}
+
+// ForceTransition moves the subject to goal whether or not the Rules permit
+// it, as an operator repairing a stuck machine would. It skips guards,
+// approvals, checks and actions, but is recorded like any other transition
+// with Forced set and the reason and actor it was given, both of which are
+// required. A subject in one of the machine's final states is left there
+// unless OverrideFinal is passed.
+func (m Machine) ForceTransition(goal State, reason, actor string, overrides ...Override) error {
+	if reason == "" || actor == "" {
+		return ErrNoReason
+	}
+	origin := m.Subject.CurrentState()
+	auth := AuthRequest{Operation: OpForce, Actor: actor, Origin: origin, Goal: goal}
+	if err := m.authorizer.authorize(auth); err != nil {
+		return err
+	}
+	if StateSet(m.finals).Contains(origin) && !overridden(overrides, OverrideFinal) {
+		return fmt.Errorf("%w: cannot force %s -> %s", ErrFinalState, origin, goal)
+	}
+
+	tc := m.context(m.recall.resolve(goal), nil)
+	tc.Actor, tc.Reason, tc.Forced = actor, reason, true
+	if err := unheld(tc); err != nil {
+		return err
+	}
+
+	event := m.newEvent(tc, m.now())
+	if m.log != nil {
+		var err error
+		if event, err = m.log.record(tc.Context(), event, m.chain); err != nil {
+			return err
+		}
+	}
+
+	m.Subject.SetState(tc.Goal)
+	m.recall.observe(tc.Goal)
+	m.history.record(event, m.chain)
+	m.properties.observe(event)
+	m.stats.transitioned(event)
+	m.approvals.reset()
+	m.timers.reset()
+
+	for _, hook := range m.forceHooks {
+		hook(tc)
+	}
+	m.callHooks(tc)
+
+	return m.settle(tc, event)
+}
+
+func overridden(overrides []Override, o Override) bool {
+	for _, override := range overrides {
+		if override == o {
+			return true
+		}
+	}
+	return false
+}
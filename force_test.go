@@ -0,0 +1,49 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestForceTransition(t *testing.T) {
+	rules := fsm.Ruleset{}
+	rules.AddRule(fsm.T{"pending", "started"}, func(fsm.Stater, fsm.State) bool { return false })
+	rules.AddTransition(fsm.T{"started", "cancelled"})
+
+	var hooked, forced []fsm.TransitionContext
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHistory(),
+		fsm.WithFinalStates("cancelled"),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { hooked = append(hooked, tc) }),
+		fsm.WithForceHooks(func(tc fsm.TransitionContext) { forced = append(forced, tc) }),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), fsm.ErrInvalidTransition)
+	st.Expect(t, the_machine.ForceTransition("started", "", "ops"), fsm.ErrNoReason)
+	st.Expect(t, the_machine.ForceTransition("started", "guard bug, see incident 42", "alice"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	st.Expect(t, the_machine.Transition("cancelled"), nil)
+	err := the_machine.ForceTransition("started", "customer changed their mind", "bob")
+	st.Expect(t, errors.Is(err, fsm.ErrFinalState), true)
+	st.Expect(t, some_thing.State, fsm.State("cancelled"))
+	st.Expect(t, the_machine.ForceTransition("started", "customer changed their mind", "bob", fsm.OverrideFinal), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	st.Expect(t, len(hooked), 3)
+	st.Expect(t, len(forced), 2)
+	st.Expect(t, forced[0].Reason, "guard bug, see incident 42")
+
+	history := the_machine.History()
+	st.Expect(t, len(history), 3)
+	st.Expect(t, history[0].Forced, true)
+	st.Expect(t, history[0].Actor, "alice")
+	st.Expect(t, history[0].Reason, "guard bug, see incident 42")
+	st.Expect(t, history[1].Forced, false)
+}
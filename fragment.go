@@ -0,0 +1,41 @@
+package fsm
+
+import "strings"
+
+// Here stands for the state a Fragment is stamped around. It can be used
+// on its own or within a longer name, as in Here + ".failed".
+const Here State = "${state}"
+
+// Fragment is a reusable piece of a ruleset, such as an error and retry
+// loop, written around the placeholder state Here.
+type Fragment Ruleset
+
+// Stamp adds a copy of f to r around each of states, with Here replaced by
+// the state. Guards are shared between the copies.
+func (r Ruleset) Stamp(f Fragment, states ...State) {
+	for _, s := range states {
+		place := func(p State) State {
+			return State(strings.ReplaceAll(string(p), string(Here), string(s)))
+		}
+		for t, guards := range f {
+			t := T{place(t.Origin()), place(t.Exit())}
+			r.AddTransition(t)
+			r.AddRule(t, guards...)
+		}
+	}
+}
+
+// RetryLoop is the standard failure loop around a state: Here can fail,
+// a failed state can be retried, and a retry either returns to Here,
+// subject to guards such as a retry limit, or fails again. The states are
+// named Here + ".failed" and Here + ".retrying".
+func RetryLoop(guards ...Guard) Fragment {
+	failed, retrying := Here+".failed", Here+".retrying"
+
+	return Fragment{
+		T{Here, failed}:     nil,
+		T{failed, retrying}: nil,
+		T{retrying, failed}: nil,
+		T{retrying, Here}:   guards,
+	}
+}
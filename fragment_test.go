@@ -0,0 +1,38 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestStamp(t *testing.T) {
+	retries := 0
+	underLimit := func(subject fsm.Stater, goal fsm.State) bool {
+		retries++
+		return retries <= 2
+	}
+
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "charging"},
+		fsm.T{"charging", "shipping"},
+		fsm.T{"shipping", "delivered"},
+	)
+	rules.Stamp(fsm.RetryLoop(underLimit), "charging", "shipping")
+
+	st.Expect(t, rules.ExitsFrom("charging"), []fsm.State{"charging.failed", "shipping"})
+	st.Expect(t, rules.ExitsFrom("shipping.retrying"), []fsm.State{"shipping", "shipping.failed"})
+
+	some_thing := Thing{State: "charging"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing))
+
+	for i := 0; i < 2; i++ {
+		st.Expect(t, the_machine.Transition("charging.failed"), nil, i)
+		st.Expect(t, the_machine.Transition("charging.retrying"), nil, i)
+		st.Expect(t, the_machine.Transition("charging"), nil, i)
+	}
+	st.Expect(t, the_machine.Transition("charging.failed"), nil)
+	st.Expect(t, the_machine.Transition("charging.retrying"), nil)
+	st.Expect(t, the_machine.Transition("charging"), fsm.ErrInvalidTransition)
+}
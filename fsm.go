@@ -1,6 +1,13 @@
 package fsm
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"sort"
+	"time"
+)
 
 type State string
 
@@ -8,7 +15,11 @@ type State string
 // Returning true/false indicates if the transition is permitted or not.
 type Guard func(subject Stater, goal State) bool
 
-var ErrInvalidTransition = errors.New("invalid transition")
+var (
+	ErrInvalidTransition = errors.New("invalid transition")
+	ErrNoRules           = errors.New("machine has no rules")
+	ErrNoSubject         = errors.New("machine has no subject")
+)
 
 // Transition is the change between States
 type Transition interface {
@@ -30,23 +41,73 @@ type Ruleset map[Transition][]Guard
 
 // AddRule adds Guards for the given Transition
 func (r Ruleset) AddRule(t Transition, guards ...Guard) {
-	r[t] = append(r[t], guards...)
+	for _, t := range expand(t) {
+		mustBeHashable(t)
+		r[t] = append(r[t], guards...)
+	}
 }
 
-// AddTransition adds a transition with a default rule
+// AddTransition adds a transition with a default rule. Permitted only ever
+// looks up transitions that start at the subject's current state, so the
+// default rule needs no guard of its own.
 func (r Ruleset) AddTransition(t Transition) {
-	r.AddRule(t, func(subject Stater, goal State) bool {
-		return subject.CurrentState() == t.Origin()
-	})
+	for _, t := range expand(t) {
+		mustBeHashable(t)
+		if _, ok := r[t]; !ok {
+			r[t] = nil
+		}
+	}
+}
+
+// AddTransitions adds several transitions with default rules.
+func (r Ruleset) AddTransitions(ts ...Transition) {
+	for _, t := range ts {
+		r.AddTransition(t)
+	}
+}
+
+// NewRuleset creates an empty Ruleset with room for about size transitions,
+// which saves growing it one transition at a time when loading many.
+func NewRuleset(size int) Ruleset {
+	return make(Ruleset, size)
+}
+
+// Clone returns a copy of r that can be changed without affecting r.
+func (r Ruleset) Clone() Ruleset {
+	c := make(Ruleset, len(r))
+	for t, guards := range r {
+		c[t] = append([]Guard(nil), guards...)
+	}
+
+	return c
 }
 
 // CreateRuleset will establish a ruleset with the provided transitions.
 // This eases initialization when storing within another structure.
 func CreateRuleset(transitions ...Transition) Ruleset {
-	r := Ruleset{}
+	r := NewRuleset(len(transitions))
+	r.AddTransitions(transitions...)
+
+	return r
+}
 
+// CreateRulesetE is like CreateRuleset but reports duplicate transitions
+// instead of silently merging them.
+func CreateRulesetE(transitions ...Transition) (Ruleset, error) {
+	b := NewBuilder()
 	for _, t := range transitions {
-		r.AddTransition(t)
+		b.Transition(t)
+	}
+
+	return b.Build()
+}
+
+// MustCreateRuleset is like CreateRulesetE but panics on error. It is
+// intended for package-level variables and examples.
+func MustCreateRuleset(transitions ...Transition) Ruleset {
+	r, err := CreateRulesetE(transitions...)
+	if err != nil {
+		panic(err)
 	}
 
 	return r
@@ -54,6 +115,9 @@ func CreateRuleset(transitions ...Transition) Ruleset {
 
 // Permitted determines if a transition is allowed.
 func (r Ruleset) Permitted(subject Stater, goal State) bool {
+	// The attempt is only ever used as a lookup key and never stored, which
+	// keeps its conversion to a Transition on the stack. Checking an invalid
+	// or guard-free transition therefore doesn't allocate.
 	attempt := T{subject.CurrentState(), goal}
 
 	if guards, ok := r[attempt]; ok {
@@ -68,6 +132,59 @@ func (r Ruleset) Permitted(subject Stater, goal State) bool {
 	return false // No rule found for the transition
 }
 
+// States returns every State the ruleset mentions, sorted.
+func (r Ruleset) States() []State {
+	var states []State
+	seen := map[State]bool{}
+
+	for t := range r {
+		for _, s := range []State{t.Origin(), t.Exit()} {
+			if !seen[s] {
+				seen[s] = true
+				states = append(states, s)
+			}
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+
+	return states
+}
+
+// ExitsFrom returns the goal of every transition out of origin, sorted.
+// It has to look at every transition in the ruleset; prefer the
+// CompiledRuleset equivalent for large rulesets.
+func (r Ruleset) ExitsFrom(origin State) []State {
+	var exits []State
+	seen := map[State]bool{}
+
+	for t := range r {
+		if t.Origin() == origin && !seen[t.Exit()] {
+			seen[t.Exit()] = true
+			exits = append(exits, t.Exit())
+		}
+	}
+	sort.Slice(exits, func(i, j int) bool { return exits[i] < exits[j] })
+
+	return exits
+}
+
+// All yields every transition in the ruleset, in no particular order.
+func (r Ruleset) All() iter.Seq[Transition] {
+	return maps.Keys(r)
+}
+
+// From yields every transition out of origin, in no particular order. Like
+// ExitsFrom it has to look at every transition in the ruleset.
+func (r Ruleset) From(origin State) iter.Seq[Transition] {
+	return func(yield func(Transition) bool) {
+		for t := range r {
+			if t.Origin() == origin && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
 // Stater can be passed into the FSM. The Stater is reponsible for setting
 // its own default state. Behavior of a Stater without a State is undefined.
 type Stater interface {
@@ -77,29 +194,295 @@ type Stater interface {
 
 // Machine is a pairing of Rules and a Subject.
 // The subject or rules may be changed at any time within
-// the machine's lifecycle.
+// the machine's lifecycle. Rules set with WithPermitter, such as a
+// CompiledRuleset, take the place of the Rules field.
 type Machine struct {
 	Rules   *Ruleset
 	Subject Stater
+
+	hooks      []Hook
+	enterHooks map[State][]Hook
+	exitHooks  map[State][]Hook
+	log        *eventLog
+	stats      *machineStats
+	profile    *profileLabels
+	tracer     *machineTracer
+	approvals  *approvals
+	timers     *machineClock
+	checks     map[T][]Check
+	policies   []Check
+	clock      Clock
+	limits     *limits
+	quotas     []quota
+	variant    string
+	permitter  Permitter
+	shadow     *shadow
+	history    *history
+	chain      *hashChain
+	redactors  []Redactor
+	retention  *Retention
+
+	actions     map[T][]Action
+	errorState  State
+	errorStates map[T]State
+	invariants  *invariants
+	properties  *machineProperties
+	resolver    Resolver
+	chaos       *chaos
+	failpoints  *machineFailpoints
+	finals      []State
+	forceHooks  []Hook
+	authorizer  Authorizer
+	initial     func(subject Stater) State
+	labels      map[string]string
+	schema      Schema
+	events      Events
+
+	rejectionErrors bool
+	hierarchy       Hierarchy
+	recall          *recall
 }
 
 // Transition attempts to move the Subject to the Goal state.
 func (m Machine) Transition(goal State) error {
-	if m.Rules.Permitted(m.Subject, goal) {
-		m.Subject.SetState(goal)
-		return nil
+	return m.TransitionWith(goal, nil)
+}
+
+// TransitionWith is like Transition but hands payload to any hooks.
+func (m Machine) TransitionWith(goal State, payload interface{}) error {
+	return m.transition(m.context(goal, payload))
+}
+
+// TransitionCtx is like Transition but hands ctx to every callback through
+// TransitionContext.Context, and to an EventStore that implements
+// ContextAppender. If ctx is done before the transition has been recorded,
+// it is abandoned with a TimeoutError and the subject left where it was.
+func (m Machine) TransitionCtx(ctx context.Context, goal State) error {
+	return m.transition(m.context(goal, nil).WithContext(ctx))
+}
+
+// TransitionAs is like TransitionWith but attributes the transition to
+// actor, such as a user ID or service name, in the machine's History and
+// events.
+func (m Machine) TransitionAs(actor string, goal State, payload interface{}) error {
+	tc := m.context(goal, payload)
+	tc.Actor = actor
+	return m.transition(tc)
+}
+
+// TransitionAsCtx combines TransitionAs and TransitionCtx.
+func (m Machine) TransitionAsCtx(ctx context.Context, actor string, goal State, payload interface{}) error {
+	tc := m.context(goal, payload).WithContext(ctx)
+	tc.Actor = actor
+	return m.transition(tc)
+}
+
+func (m Machine) context(goal State, payload interface{}) TransitionContext {
+	m.defaultState()
+	return TransitionContext{
+		Subject: m.Subject,
+		Origin:  m.Subject.CurrentState(),
+		Goal:    goal,
+		Payload: payload,
+		Variant: m.variant,
+		Labels:  m.labels,
+	}
+}
+
+func (m Machine) transition(tc TransitionContext) (err error) {
+	if m.tracer != nil {
+		defer m.tracer.transition(tc, time.Now(), &err)
+	}
+	auth := AuthRequest{Operation: OpTransition, Actor: tc.Actor, Origin: tc.Origin, Goal: tc.Goal}
+	if err := m.authorizer.authorize(auth); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+	attempt := m.failpoints.attempt()
+	if err := stateless(tc); err != nil {
+		m.stats.rejected(err)
+		return err
 	}
+	if err := expired(tc, StepGuards); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	var denied error
+	m.stats.guarded(m.phase(tc, "guards", func() {
+		m.chaos.delay()
+		denied = m.consult(tc)
+	}))
+	m.shadow.compare(tc, denied == nil)
+
+	if denied != nil {
+		m.stats.rejected(denied)
+		return denied
+	}
+	if err := errors.Join(m.chaos.guard(tc), m.failpoints.at(StepGuards, attempt)); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	if err := m.approvals.check(tc, m.hierarchy); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	if err := m.check(tc); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	tc.Goal = m.recall.resolve(tc.Goal)
+	if err := unheld(tc); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	// Past this point the transition is committed to and sees it through
+	// even if its context is done; before it, the subject is unchanged.
+	if err := expired(tc, StepPersistence); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+	if err := m.reserve(tc); err != nil {
+		m.stats.rejected(err)
+		return err
+	}
+
+	event := m.newEvent(tc, m.now())
+	if m.log != nil {
+		err := errors.Join(m.chaos.persistence(), m.failpoints.at(StepPersistence, attempt))
+		if err == nil {
+			event, err = m.log.record(tc.Context(), event, m.chain)
+		}
+		if err != nil && expired(tc, StepPersistence) != nil {
+			// most likely failed for want of time, so not worth an error state
+			err = expired(tc, StepPersistence)
+			m.stats.rejected(err)
+			return err
+		}
+		if err != nil {
+			m.stats.rejected(err)
+			return m.fail(tc, tc.Origin, err)
+		}
+	}
+
+	m.Subject.SetState(tc.Goal)
+	m.recall.observe(tc.Goal)
+	m.history.record(event, m.chain)
+	m.properties.observe(event)
+	m.stats.transitioned(event)
+	m.approvals.reset()
+	m.timers.reset()
+
+	failed := m.failpoints.at(StepActions, attempt)
+	if failed == nil {
+		m.phase(tc, "actions", func() {
+			failed = m.act(tc)
+		})
+	}
+	var violated error
+	if failed == nil {
+		failed, violated = m.verify(tc)
+	}
+
+	// The subject is in the goal whether or not its actions succeeded, so
+	// the change is seen through before any failure is dealt with.
+	m.phase(tc, "hooks", func() {
+		m.chaos.hook()
+		if err := m.failpoints.at(StepHooks, attempt); err != nil {
+			panic(err)
+		}
+		m.callHooks(tc)
+	})
+
+	return m.concluded(tc, failed, violated, m.settle(tc, event))
+}
+
+// settle sees a committed state change through once its hooks have been
+// called: it is counted by any Quota, its event is snapshotted, if it was
+// recorded, and retention applied. The change stands whatever fails.
+func (m Machine) settle(tc TransitionContext, event TransitionEvent) error {
+	counted := m.count(tc)
+	if m.log != nil && event.Seq > 0 {
+		if err := m.log.snapshot(event); err != nil {
+			return errors.Join(counted, err)
+		}
+	}
+	return errors.Join(counted, m.retain(event.Time))
+}
 
-	return InvalidTransition
+// concluded returns the outcome of a transition that was committed and
+// settled. failed, the error of an action or of an invariant that calls
+// for it, moves the subject on to the error state; a violated invariant is
+// returned as it is, as is any error settling the transition.
+func (m Machine) concluded(tc TransitionContext, failed, violated, settled error) error {
+	var err error
+	switch {
+	case failed != nil:
+		err = m.fail(tc, m.Subject.CurrentState(), failed)
+	case violated != nil:
+		err = violated
+	default:
+		return settled
+	}
+	if settled != nil {
+		return errors.Join(err, settled)
+	}
+	return err
 }
 
 // New initializes a machine
 func New(opts ...func(*Machine)) Machine {
-	var m Machine
+	m := Machine{stats: newMachineStats()}
 
 	for _, opt := range opts {
 		opt(&m)
 	}
+	if m.initial != nil && m.Subject != nil {
+		m.Subject.SetState(m.initial(m.Subject))
+	}
+	if n, ok := m.rules().(NestedRuleset); ok {
+		m.hierarchy = n.Hierarchy
+		m.recall = newRecall(n.Hierarchy)
+		if m.Subject != nil {
+			m.recall.observe(m.Subject.CurrentState())
+		}
+	}
+	m.stats.enter(m.now())
+
+	return m
+}
+
+// NewE initializes a machine and validates that it has both rules and a
+// subject, and that the state computed by WithInitialStateFunc is one the
+// rules know.
+func NewE(opts ...func(*Machine)) (Machine, error) {
+	m := New(opts...)
+
+	var errs []error
+	if m.rules() == nil {
+		errs = append(errs, ErrNoRules)
+	}
+	if m.Subject == nil {
+		errs = append(errs, ErrNoSubject)
+	}
+	if m.initial != nil && m.rules() != nil && m.Subject != nil {
+		errs = append(errs, m.known(m.Subject.CurrentState()))
+	}
+
+	return m, errors.Join(errs...)
+}
+
+// MustNew is like NewE but panics on error. It is intended for
+// package-level variables and examples.
+func MustNew(opts ...func(*Machine)) Machine {
+	m, err := NewE(opts...)
+	if err != nil {
+		panic(err)
+	}
 
 	return m
 }
@@ -115,5 +498,37 @@ func WithSubject(s Stater) func(*Machine) {
 func WithRules(r Ruleset) func(*Machine) {
 	return func(m *Machine) {
 		m.Rules = &r
+		m.permitter = nil
+	}
+}
+
+// WithPermitter is intended to be passed to New to set the Rules to
+// something other than a plain Ruleset, such as a CompiledRuleset. The
+// Rules field is left nil unless p is a *Ruleset.
+func WithPermitter(p Permitter) func(*Machine) {
+	return func(m *Machine) {
+		m.setRules(p)
+	}
+}
+
+// setRules makes p decide the machine's transitions, keeping a *Ruleset in
+// the Rules field.
+func (m *Machine) setRules(p Permitter) {
+	if r, ok := p.(*Ruleset); ok {
+		m.Rules, m.permitter = r, nil
+		return
+	}
+	m.Rules, m.permitter = nil, p
+}
+
+// rules returns what decides the machine's transitions: the Permitter set
+// with WithPermitter, or else the Rules.
+func (m Machine) rules() Permitter {
+	if m.permitter != nil {
+		return m.permitter
+	}
+	if m.Rules != nil {
+		return m.Rules
 	}
+	return nil
 }
@@ -1,10 +1,12 @@
 package fsm_test
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/nbio/st"
-	"github.com/ryanfaerman/fsm"
+	"github.com/ryanfaerman/fsm/v3"
 )
 
 // Thing is a minimal struct that is an fsm.Stater
@@ -82,6 +84,7 @@ func BenchmarkRulesetTransitionPermitted(b *testing.B) {
 
 	some_thing := &Thing{State: "started"}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -101,6 +104,7 @@ func BenchmarkRulesetTransitionInvalid(b *testing.B) {
 
 	some_thing := &Thing{State: "pending"}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -124,9 +128,67 @@ func BenchmarkRulesetRuleForbids(b *testing.B) {
 
 	some_thing := &Thing{State: "started"}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
 		rules.Permitted(some_thing, "finished")
 	}
 }
+
+func TestNewE(t *testing.T) {
+	_, err := fsm.NewE()
+	st.Expect(t, errors.Is(err, fsm.ErrNoRules), true)
+	st.Expect(t, errors.Is(err, fsm.ErrNoSubject), true)
+
+	_, err = fsm.NewE(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(&Thing{}))
+	st.Expect(t, err, nil)
+}
+
+func TestMustCreateRuleset(t *testing.T) {
+	defer func() {
+		st.Expect(t, recover() != nil, true)
+	}()
+
+	fsm.MustCreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"pending", "started"},
+	)
+}
+
+func BenchmarkRulesetLoad(b *testing.B) {
+	// Rulesets generated from product catalogs can run to 100k transitions.
+	// Loading in bulk into a presized ruleset avoids rehashing as it grows.
+	transitions := make([]fsm.Transition, 100000)
+	for i := range transitions {
+		transitions[i] = fsm.T{fsm.State(fmt.Sprint(i)), fsm.State(fmt.Sprint(i + 1))}
+	}
+
+	b.Run("AddTransition", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rules := fsm.Ruleset{}
+			for _, t := range transitions {
+				rules.AddTransition(t)
+			}
+		}
+	})
+
+	b.Run("AddTransitions", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rules := fsm.NewRuleset(len(transitions))
+			rules.AddTransitions(transitions...)
+		}
+	})
+
+	b.Run("Compile", func(b *testing.B) {
+		rules := fsm.CreateRuleset(transitions...)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			rules.Compile()
+		}
+	})
+}
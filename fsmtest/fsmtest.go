@@ -0,0 +1,228 @@
+// Package fsmtest describes the behavior of rulesets in tests as
+// scenarios, which read as the workflow they check:
+//
+//	fsmtest.Given(t, rules).
+//		Hook("notify", notify).
+//		StartingIn("pending").
+//		When(fsmtest.TransitionTo("started")).
+//		Then().StateIs("started").
+//		And().HookFired("notify")
+//
+// When a transition is unexpectedly rejected, failures say why: whether
+// the ruleset has no such transition, or which of its guards refused it.
+package fsmtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// Scenario sets up a machine for a test.
+type Scenario struct {
+	t     testing.TB
+	rules fsm.Permitter
+	opts  []func(*fsm.Machine)
+	start fsm.State
+	hooks []namedHook
+}
+
+type namedHook struct {
+	name string
+	hook fsm.Hook
+}
+
+// Given starts a scenario of a machine following rules, configured further
+// by opts.
+func Given(t testing.TB, rules fsm.Permitter, opts ...func(*fsm.Machine)) *Scenario {
+	return &Scenario{t: t, rules: rules, opts: opts}
+}
+
+// StartingIn puts the subject in state before anything happens.
+func (s *Scenario) StartingIn(state fsm.State) *Scenario {
+	s.start = state
+	return s
+}
+
+// Hook registers hook under name, so that Result.HookFired can tell
+// whether it was called. A nil hook only records that it was.
+func (s *Scenario) Hook(name string, hook fsm.Hook) *Scenario {
+	s.hooks = append(s.hooks, namedHook{name, hook})
+	return s
+}
+
+// Step is something that happens to the machine of a Scenario.
+type Step struct {
+	name string
+	goal fsm.State // of a transition
+	do   func(m fsm.Machine) error
+}
+
+// TransitionTo attempts to move the subject to goal.
+func TransitionTo(goal fsm.State) Step {
+	return Step{fmt.Sprintf("transition to %q", goal), goal, func(m fsm.Machine) error {
+		return m.Transition(goal)
+	}}
+}
+
+// TransitionAs attempts to move the subject to goal on behalf of actor.
+func TransitionAs(actor string, goal fsm.State, payload interface{}) Step {
+	return Step{fmt.Sprintf("transition to %q as %q", goal, actor), goal, func(m fsm.Machine) error {
+		return m.TransitionAs(actor, goal, payload)
+	}}
+}
+
+// Fire fires event, which the scenario's machine must have been given
+// WithEvents.
+func Fire(event string) Step {
+	return Step{name: fmt.Sprintf("event %q", event), do: func(m fsm.Machine) error {
+		return m.Fire(event)
+	}}
+}
+
+// When creates the machine and takes steps, stopping at the first that
+// fails.
+func (s *Scenario) When(steps ...Step) *Result {
+	r := &Result{t: s.t, subject: &subject{s.start}, fired: map[string]int{}}
+
+	opts := []func(*fsm.Machine){fsm.WithPermitter(s.rules), fsm.WithSubject(r.subject)}
+	for _, h := range s.hooks {
+		opts = append(opts, fsm.WithHooks(func(tc fsm.TransitionContext) {
+			r.fired[h.name]++
+			if h.hook != nil {
+				h.hook(tc)
+			}
+		}))
+	}
+	r.Machine = fsm.New(append(opts, s.opts...)...)
+
+	for _, step := range steps {
+		origin := r.subject.state
+		if r.err = step.do(r.Machine); r.err != nil {
+			r.failed = fmt.Sprintf("%s from %q failed: %v", step.name, origin, r.err)
+			if why := explain(s.rules, origin, r.subject.state, step.goal); why != "" {
+				r.failed += " (" + why + ")"
+			}
+			break
+		}
+	}
+
+	return r
+}
+
+// Result is what happened in a Scenario, for making assertions about.
+// Every assertion returns the Result, so they can be chained.
+type Result struct {
+	Machine fsm.Machine
+
+	t       testing.TB
+	subject *subject
+	err     error
+	failed  string // describes the step that failed, if one did
+	fired   map[string]int
+}
+
+// Then returns r. It only makes scenarios read better.
+func (r *Result) Then() *Result { return r }
+
+// And returns r. It only makes scenarios read better.
+func (r *Result) And() *Result { return r }
+
+// Err returns the error of the step that failed, if any.
+func (r *Result) Err() error { return r.err }
+
+func (r *Result) errorf(format string, args ...interface{}) {
+	r.t.Helper()
+	msg := fmt.Sprintf(format, args...)
+	if r.failed != "" {
+		msg += "\n\t" + r.failed
+	}
+	r.t.Errorf("%s", msg)
+}
+
+// Succeeded asserts that every step succeeded.
+func (r *Result) Succeeded() *Result {
+	r.t.Helper()
+	if r.err != nil {
+		r.errorf("expected every step to succeed")
+	}
+	return r
+}
+
+// FailedWith asserts that a step failed with an error matching target.
+func (r *Result) FailedWith(target error) *Result {
+	r.t.Helper()
+	switch {
+	case r.err == nil:
+		r.errorf("expected a step to fail with %q, but every step succeeded", target)
+	case !errors.Is(r.err, target):
+		r.errorf("expected a step to fail with %q", target)
+	}
+	return r
+}
+
+// StateIs asserts that the subject ended up in state.
+func (r *Result) StateIs(state fsm.State) *Result {
+	r.t.Helper()
+	if r.subject.state != state {
+		r.errorf("state is %q, want %q", r.subject.state, state)
+	}
+	return r
+}
+
+// HookFired asserts that the hook registered under name was called.
+func (r *Result) HookFired(name string) *Result {
+	r.t.Helper()
+	if r.fired[name] == 0 {
+		r.errorf("hook %q was not fired", name)
+	}
+	return r
+}
+
+// HookNotFired asserts that the hook registered under name was not called.
+func (r *Result) HookNotFired(name string) *Result {
+	r.t.Helper()
+	if n := r.fired[name]; n > 0 {
+		r.errorf("hook %q was fired %d times", name, n)
+	}
+	return r
+}
+
+type subject struct{ state fsm.State }
+
+func (s *subject) CurrentState() fsm.State  { return s.state }
+func (s *subject) SetState(state fsm.State) { s.state = state }
+
+// explain says why the transition from origin to goal was rejected by
+// rules, when they are a Ruleset or CompiledRuleset: because they have no
+// such transition, or because one of its guards refuses it. Guards are
+// consulted again to find out, with a subject of their own.
+func explain(rules fsm.Permitter, origin, current, goal fsm.State) string {
+	r, ok := rules.(interface {
+		PermittedE(fsm.Stater, fsm.State) error
+		ExitsFrom(fsm.State) []fsm.State
+	})
+	if !ok || goal == "" || current != origin {
+		return ""
+	}
+
+	switch err := r.PermittedE(&subject{origin}, goal).(type) {
+	case *fsm.NoRuleError:
+		exits := r.ExitsFrom(origin)
+		if len(exits) == 0 {
+			return fmt.Sprintf("no transitions out of %q", origin)
+		}
+		quoted := make([]string, len(exits))
+		for i, e := range exits {
+			quoted[i] = fmt.Sprintf("%q", e)
+		}
+		return fmt.Sprintf("no rule %q -> %q; exits are %s", origin, goal, strings.Join(quoted, ", "))
+	case *fsm.GuardError:
+		return fmt.Sprintf("guard %d on %q -> %q refused", err.Index+1, origin, goal)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,76 @@
+package fsmtest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/fsmtest"
+)
+
+// recorder collects the failures of assertions that are meant to fail.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func rules() fsm.Ruleset {
+	r := fsm.CreateRuleset(fsm.T{O: "pending", E: "started"})
+	r.AddRule(fsm.T{O: "started", E: "finished"}, func(fsm.Stater, fsm.State) bool { return true })
+	r.AddRule(fsm.T{O: "started", E: "finished"}, func(fsm.Stater, fsm.State) bool { return false })
+	return r
+}
+
+func TestScenario(t *testing.T) {
+	fsmtest.Given(t, rules()).
+		Hook("started", nil).
+		StartingIn("pending").
+		When(fsmtest.TransitionTo("started")).
+		Then().StateIs("started").
+		And().HookFired("started").
+		And().Succeeded()
+
+	fsmtest.Given(t, rules()).
+		StartingIn("started").
+		When(fsmtest.TransitionAs("alice", "finished", nil)).
+		Then().StateIs("started").
+		And().FailedWith(fsm.ErrInvalidTransition)
+
+	events := fsm.Events{}
+	events.AddEvent("start", fsm.T{O: "pending", E: "started"})
+	fsmtest.Given(t, rules(), fsm.WithEvents(events)).
+		StartingIn("pending").
+		When(fsmtest.Fire("start")).
+		Then().StateIs("started")
+}
+
+func TestScenarioFailuresSayWhy(t *testing.T) {
+	rec := &recorder{TB: t}
+
+	fsmtest.Given(rec, rules()).
+		Hook("notify", nil).
+		StartingIn("pending").
+		When(fsmtest.TransitionTo("started"), fsmtest.TransitionTo("finished")).
+		Then().StateIs("finished").
+		And().HookNotFired("notify")
+
+	fsmtest.Given(rec, rules()).
+		StartingIn("pending").
+		When(fsmtest.TransitionTo("finished")).
+		Then().Succeeded()
+
+	st.Expect(t, rec.failures, []string{
+		`state is "started", want "finished"` +
+			"\n\t" + `transition to "finished" from "started" failed: invalid transition (guard 2 on "started" -> "finished" refused)`,
+		`hook "notify" was fired 1 times` +
+			"\n\t" + `transition to "finished" from "started" failed: invalid transition (guard 2 on "started" -> "finished" refused)`,
+		`expected every step to succeed` +
+			"\n\t" + `transition to "finished" from "pending" failed: invalid transition (no rule "pending" -> "finished"; exits are "started")`,
+	})
+}
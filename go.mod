@@ -1,5 +1,8 @@
 module github.com/ryanfaerman/fsm/v3
 
-go 1.21.6
+go 1.23
 
-require github.com/nbio/st v0.0.0-20140626010706-e9e8d9816f32
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/nbio/st v0.0.0-20140626010706-e9e8d9816f32
+)
@@ -0,0 +1,63 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// GuardCtx is a Guard for transitions that have to wait on something, such
+// as a database or another service. It is handed the transition's context,
+// which it should give up on once done, and explains a refusal with an
+// error rather than a bare false.
+type GuardCtx func(ctx context.Context, subject Stater, goal State) error
+
+// GuardWithCtx adapts g to a GuardCtx that refuses with
+// ErrInvalidTransition.
+func GuardWithCtx(g Guard) GuardCtx {
+	return func(ctx context.Context, subject Stater, goal State) error {
+		if !g(subject, goal) {
+			return ErrInvalidTransition
+		}
+		return nil
+	}
+}
+
+// WithGuardsCtx is intended to be passed to New to protect the transition t
+// with guards that take the context of TransitionCtx. They run as Checks:
+// in order, once the Rules have permitted t. A guard that fails once the
+// context is done has the transition abandoned with a TimeoutError; other
+// failures are reported by a *GuardError, whose Index counts the guards
+// passed to WithGuardsCtx.
+func WithGuardsCtx(t Transition, guards ...GuardCtx) func(*Machine) {
+	return func(m *Machine) {
+		checks := make([]Check, len(guards))
+		for i, g := range guards {
+			checks[i] = func(tc TransitionContext) error {
+				if err := expired(tc, StepGuards); err != nil {
+					return err
+				}
+				start := time.Now()
+				err := guardCtx(tc, g(tc.Context(), tc.Subject, tc.Goal), i)
+				m.tracer.guard(tc, i, start, err)
+				return err
+			}
+		}
+
+		WithChecks(t, checks...)(m)
+	}
+}
+
+// guardCtx makes err, as returned by the index-th GuardCtx of tc, the
+// error of its check.
+func guardCtx(tc TransitionContext, err error, index int) error {
+	switch {
+	case err == nil:
+		return nil
+	case tc.Context().Err() != nil:
+		return expired(tc, StepGuards)
+	case err == ErrInvalidTransition:
+		return &GuardError{From: tc.Origin, To: tc.Goal, Index: index}
+	default:
+		return &GuardError{From: tc.Origin, To: tc.Goal, Index: index, Err: err}
+	}
+}
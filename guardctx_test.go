@@ -0,0 +1,55 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+var errOutOfStock = errors.New("out of stock")
+
+func TestWithGuardsCtx(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	inStock := fsm.GuardCtx(func(ctx context.Context, subject fsm.Stater, goal fsm.State) error {
+		select {
+		case <-time.After(10 * time.Millisecond):
+			return errOutOfStock
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	open := fsm.GuardWithCtx(func(subject fsm.Stater, goal fsm.State) bool { return true })
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithGuardsCtx(fsm.T{"pending", "started"}, open, inStock),
+	)
+
+	err := the_machine.Transition("started")
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, errors.Is(err, errOutOfStock), true)
+	st.Expect(t, err.(*fsm.GuardError).Index, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = the_machine.TransitionCtx(ctx, "started")
+	st.Expect(t, errors.Is(err, fsm.ErrTimeout), true)
+	st.Expect(t, errors.Is(err, context.DeadlineExceeded), true)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+
+	closed := fsm.GuardWithCtx(func(subject fsm.Stater, goal fsm.State) bool { return false })
+	the_machine = fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithGuardsCtx(fsm.T{"pending", "started"}, closed),
+	)
+	err = the_machine.Transition("started")
+	st.Expect(t, err, error(&fsm.GuardError{From: "pending", To: "started"}))
+	st.Expect(t, err.Error(), "invalid transition: guard 1 of pending -> started refused")
+}
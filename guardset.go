@@ -0,0 +1,24 @@
+package fsm
+
+import "fmt"
+
+// GuardSets keeps bundles of guards by name, so that a bundle commonly
+// needed together, such as authentication, a tenant check and an audit
+// precondition, can be added to many transitions without missing one.
+// Builder.GuardSet does the same for named guards.
+type GuardSets map[string][]Guard
+
+// Add registers guards as the set name, adding to it if it exists.
+func (s GuardSets) Add(name string, guards ...Guard) {
+	s[name] = append(s[name], guards...)
+}
+
+// Apply adds the guards of the set name to t in r.
+func (s GuardSets) Apply(r Ruleset, t Transition, name string) error {
+	guards, ok := s[name]
+	if !ok {
+		return fmt.Errorf("%w: set %q", ErrUnknownGuard, name)
+	}
+	r.AddRule(t, guards...)
+	return nil
+}
@@ -0,0 +1,63 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestGuardSets(t *testing.T) {
+	var ran []string
+	guard := func(name string) fsm.Guard {
+		return func(subject fsm.Stater, goal fsm.State) bool {
+			ran = append(ran, name)
+			return true
+		}
+	}
+
+	sets := fsm.GuardSets{}
+	sets.Add("admin", guard("authenticated"), guard("tenant"))
+	sets.Add("admin", guard("audited"))
+
+	rules := fsm.Ruleset{}
+	st.Expect(t, sets.Apply(rules, fsm.T{"pending", "approved"}, "admin"), nil)
+	st.Expect(t, errors.Is(sets.Apply(rules, fsm.T{"pending", "rejected"}, "missing"), fsm.ErrUnknownGuard), true)
+
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "approved"), true)
+	st.Expect(t, ran, []string{"authenticated", "tenant", "audited"})
+}
+
+func TestBuilderGuardSet(t *testing.T) {
+	var ran []string
+	guard := func(name string) fsm.Guard {
+		return func(subject fsm.Stater, goal fsm.State) bool {
+			ran = append(ran, name)
+			return true
+		}
+	}
+
+	rules, err := fsm.NewBuilder().
+		Guard("authenticated", guard("authenticated")).
+		Guard("tenant", guard("tenant")).
+		Guard("in-stock", guard("in-stock")).
+		GuardSet("customer", "authenticated", "tenant").
+		Transition(fsm.T{"cart", "ordered"}, "customer", "in-stock").
+		Transition(fsm.T{"ordered", "cancelled"}, "customer").
+		Build()
+
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "cart"}, "ordered"), true)
+	st.Expect(t, ran, []string{"authenticated", "tenant", "in-stock"})
+
+	_, err = fsm.NewBuilder().
+		Guard("tenant", guard("tenant")).
+		GuardSet("tenant", "tenant").
+		GuardSet("customer", "missing").
+		Transition(fsm.T{"cart", "ordered"}, "customer").
+		Build()
+
+	st.Expect(t, errors.Is(err, fsm.ErrDuplicateGuard), true)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownGuard), true)
+}
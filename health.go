@@ -0,0 +1,80 @@
+package fsm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Health summarizes whether the machines of a Registry are flowing through
+// their states or backing up in them.
+type Health struct {
+	At       time.Time
+	States   map[State]StateHealth
+	Breaches []Breach // oldest first
+}
+
+// StateHealth describes the machines in one state.
+type StateHealth struct {
+	Machines int
+	Oldest   string    // key of the machine that has been in the state longest
+	Since    time.Time // when Oldest entered the state
+}
+
+// Breach is a machine that has been in its state for longer than the SLA
+// of the state allows.
+type Breach struct {
+	Key   string
+	State State
+	Since time.Time
+	SLA   time.Duration
+}
+
+// Health summarizes the registry as of now. sla gives the longest a
+// machine should stay in each state; states without one have no limit.
+func (r *Registry) Health(now time.Time, sla map[State]time.Duration) Health {
+	h := Health{At: now, States: map[State]StateHealth{}}
+
+	r.Range(func(key string, m Machine) bool {
+		state, since := m.Subject.CurrentState(), m.Since()
+
+		s := h.States[state]
+		s.Machines++
+		if s.Oldest == "" || since.Before(s.Since) || since.Equal(s.Since) && key < s.Oldest {
+			s.Oldest, s.Since = key, since
+		}
+		h.States[state] = s
+
+		if limit, ok := sla[state]; ok && now.Sub(since) > limit {
+			h.Breaches = append(h.Breaches, Breach{Key: key, State: state, Since: since, SLA: limit})
+		}
+		return true
+	})
+
+	sortBreaches(h.Breaches)
+	return h
+}
+
+// sortBreaches puts the oldest breaches first.
+func sortBreaches(breaches []Breach) {
+	sort.Slice(breaches, func(i, j int) bool {
+		a, b := breaches[i], breaches[j]
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		return a.Key < b.Key
+	})
+}
+
+// HealthHandler serves the registry's Health as JSON, as of the time given
+// by clock, or the system clock if it is nil.
+func (r *Registry) HealthHandler(sla map[State]time.Duration, clock Clock) http.Handler {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(r.Health(clock.Now(), sla))
+	})
+}
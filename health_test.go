@@ -0,0 +1,51 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistryHealth(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	clock := fsm.ClockFunc(func() time.Time { return now })
+	rules := fsm.CreateRuleset(fsm.T{"queued", "processing"})
+
+	registry := fsm.NewRegistry()
+	for i := range 4 {
+		registry.Put(fmt.Sprint("job-", i), fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "queued"}),
+			fsm.WithClock(clock),
+		))
+		now = now.Add(time.Hour)
+	}
+	st.Expect(t, registry.Transition("job-2", "processing"), nil)
+	now = now.Add(time.Hour)
+	st.Expect(t, registry.Transition("job-3", "processing"), nil)
+
+	now = start.Add(10 * time.Hour)
+	sla := map[fsm.State]time.Duration{"processing": 5 * time.Hour}
+	health := registry.Health(now, sla)
+
+	st.Expect(t, health.States, map[fsm.State]fsm.StateHealth{
+		"queued":     {Machines: 2, Oldest: "job-0", Since: start},
+		"processing": {Machines: 2, Oldest: "job-2", Since: start.Add(4 * time.Hour)},
+	})
+	st.Expect(t, health.Breaches, []fsm.Breach{
+		{Key: "job-2", State: "processing", Since: start.Add(4 * time.Hour), SLA: 5 * time.Hour},
+	})
+
+	rec := httptest.NewRecorder()
+	registry.HealthHandler(sla, clock).ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	var served fsm.Health
+	st.Expect(t, json.Unmarshal(rec.Body.Bytes(), &served), nil)
+	st.Expect(t, served.States["queued"].Machines, 2)
+	st.Expect(t, len(served.Breaches), 1)
+}
@@ -0,0 +1,154 @@
+package fsm
+
+import (
+	"slices"
+	"sort"
+)
+
+// Hierarchy nests states within others, mapping each child to its parent,
+// so that a transition out of a parent applies to every state within it:
+// "any active state -> cancelled" is one rule rather than one per state.
+//
+// A State is a plain string, compared and stored as one throughout, so it
+// can't carry its parent itself; the Hierarchy keeps that on the side. A
+// machine created with a NestedRuleset as its Rules looks up everything
+// else keyed by transition or state through the hierarchy too:
+//
+//   - the checks, and so Limits, and the approvals of every state the
+//     origin is nested within apply, closest first
+//   - the actions and the error state are those of the closest state that
+//     has any, as with guards
+//   - events defined on a parent may be fired from within it
+//   - leaving a state leaves every state it is nested within that the goal
+//     isn't, calling their exit hooks innermost first; entering one calls
+//     the enter hooks of the states entered outermost first
+type Hierarchy map[State]State
+
+// Nest makes children the children of parent.
+func (h Hierarchy) Nest(parent State, children ...State) {
+	for _, child := range children {
+		h[child] = parent
+	}
+}
+
+// Ancestors returns the parent of s, its parent's parent, and so on. The
+// list is cut short at len(h) states, so a cycle can't make it endless.
+func (h Hierarchy) Ancestors(s State) []State {
+	var ancestors []State
+	for parent, ok := h[s]; ok && len(ancestors) < len(h); parent, ok = h[parent] {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// lineage returns s followed by its Ancestors.
+func (h Hierarchy) lineage(s State) []State {
+	return append([]State{s}, h.Ancestors(s)...)
+}
+
+// exited returns the states a transition from origin to goal leaves,
+// innermost first: origin, and every state it is nested within that goal
+// isn't.
+func (h Hierarchy) exited(origin, goal State) []State {
+	states := []State{origin}
+	for _, a := range h.Ancestors(origin) {
+		if h.In(goal, a) {
+			break
+		}
+		states = append(states, a)
+	}
+	return states
+}
+
+// entered returns the states a transition from origin to goal enters,
+// outermost first: every state goal is nested within that origin isn't,
+// and goal.
+func (h Hierarchy) entered(origin, goal State) []State {
+	states := []State{goal}
+	for _, a := range h.Ancestors(goal) {
+		if h.In(origin, a) {
+			break
+		}
+		states = append(states, a)
+	}
+	slices.Reverse(states)
+	return states
+}
+
+// In reports whether s is state or nested anywhere within it.
+func (h Hierarchy) In(s, state State) bool {
+	if s == state {
+		return true
+	}
+	for _, a := range h.Ancestors(s) {
+		if a == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns r with the hierarchy applied: a Permitter for machines, as
+// in WithPermitter.
+func (h Hierarchy) Rules(r Ruleset) NestedRuleset {
+	return NestedRuleset{Rules: r, Hierarchy: h}
+}
+
+// NestedRuleset is a Ruleset whose transitions out of a state apply to the
+// states nested within it too. The rule closest to the subject's state
+// decides: a transition out of the state itself overrides one out of its
+// parent, guards and all.
+type NestedRuleset struct {
+	Rules     Ruleset
+	Hierarchy Hierarchy
+}
+
+// Permitted determines if a transition is allowed.
+func (n NestedRuleset) Permitted(subject Stater, goal State) bool {
+	return n.PermittedE(subject, goal) == nil
+}
+
+// PermittedE is like Permitted but says why a transition isn't allowed,
+// as Ruleset.PermittedE does. A GuardError names the state whose rule
+// refused.
+func (n NestedRuleset) PermittedE(subject Stater, goal State) error {
+	origin := subject.CurrentState()
+
+	for _, s := range n.Hierarchy.lineage(origin) {
+		guards, ok := n.Rules[T{s, goal}]
+		if !ok {
+			continue
+		}
+		for i, guard := range guards {
+			if !guard(subject, goal) {
+				return &GuardError{From: s, To: goal, Index: i}
+			}
+		}
+		return nil
+	}
+	return &NoRuleError{From: origin, To: goal}
+}
+
+// ExitsFrom returns the goal of every transition out of origin or the
+// states it is nested within, sorted.
+func (n NestedRuleset) ExitsFrom(origin State) []State {
+	seen := map[State]bool{}
+	var exits []State
+	for _, s := range n.Hierarchy.lineage(origin) {
+		for _, goal := range n.Rules.ExitsFrom(s) {
+			if !seen[goal] {
+				seen[goal] = true
+				exits = append(exits, goal)
+			}
+		}
+	}
+	sort.Slice(exits, func(i, j int) bool { return exits[i] < exits[j] })
+	return exits
+}
+
+// AvailableExits returns the goal of every transition out of the subject's
+// current state, or the states it is nested within, whose guards
+// currently pass, sorted.
+func (n NestedRuleset) AvailableExits(subject Stater) []State {
+	return available(n, subject)
+}
@@ -0,0 +1,128 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestNestedRuleset(t *testing.T) {
+	h := fsm.Hierarchy{}
+	h.Nest("active", "processing", "shipping")
+	h.Nest("processing", "picking", "packing")
+
+	st.Expect(t, h.Ancestors("packing"), []fsm.State{"processing", "active"})
+	st.Expect(t, h.In("packing", "active"), true)
+	st.Expect(t, h.In("shipping", "processing"), false)
+
+	refunds := false
+	rules := fsm.CreateRuleset(
+		fsm.T{"active", "cancelled"},
+		fsm.T{"picking", "packing"},
+		fsm.T{"packing", "shipping"},
+	)
+	rules.AddRule(fsm.T{"shipping", "cancelled"}, func(fsm.Stater, fsm.State) bool { return refunds })
+
+	some_thing := Thing{State: "picking"}
+	the_machine := fsm.New(fsm.WithPermitter(h.Rules(rules)), fsm.WithSubject(&some_thing), fsm.WithRejectionErrors())
+
+	st.Expect(t, the_machine.AvailableTransitions(), []fsm.State{"cancelled", "packing"})
+	st.Expect(t, the_machine.Transition("packing"), nil)
+	st.Expect(t, the_machine.Transition("cancelled"), nil) // inherited from active
+
+	// The rule of the state itself overrides that of its parent.
+	some_thing.State = "shipping"
+	err := the_machine.Transition("cancelled")
+	var guardErr *fsm.GuardError
+	st.Expect(t, errors.As(err, &guardErr), true)
+	st.Expect(t, guardErr.From, fsm.State("shipping"))
+
+	refunds = true
+	st.Expect(t, the_machine.Transition("cancelled"), nil)
+	st.Expect(t, errors.As(the_machine.Transition("shipping"), new(*fsm.NoRuleError)), true)
+}
+
+func TestNestedMachineLookups(t *testing.T) {
+	h := fsm.Hierarchy{}
+	h.Nest("active", "review", "approved")
+	h.Nest("review", "drafting")
+
+	rules := fsm.CreateRuleset(
+		fsm.T{"active", "cancelled"},
+		fsm.T{"active", "failed"},
+		fsm.T{"drafting", "approved"},
+		fsm.T{"approved", "review"},
+	)
+	events := fsm.Events{}
+	events.AddEvent("cancel", fsm.T{"active", "cancelled"})
+
+	var calls []string
+	record := func(name string) fsm.Hook {
+		return func(tc fsm.TransitionContext) { calls = append(calls, name) }
+	}
+	denied := errors.New("denied")
+	open := false
+
+	some_thing := Thing{State: "drafting"}
+	the_machine := fsm.New(
+		fsm.WithPermitter(h.Rules(rules)),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEvents(events),
+		fsm.WithChecks(fsm.T{"active", "cancelled"}, func(fsm.TransitionContext) error {
+			if !open {
+				return denied
+			}
+			return nil
+		}),
+		fsm.WithActions(fsm.T{"active", "failed"}, func(fsm.TransitionContext) error {
+			return errors.New("boom")
+		}),
+		fsm.WithErrorStateFor(fsm.T{"active", "failed"}, "broken"),
+		fsm.Limit(fsm.T{"active", "cancelled"}, 1),
+		fsm.WithExitHooks("drafting", record("exit drafting")),
+		fsm.WithExitHooks("review", record("exit review")),
+		fsm.WithExitHooks("active", record("exit active")),
+		fsm.WithEnterHooks("approved", record("enter approved")),
+		fsm.WithEnterHooks("review", record("enter review")),
+		fsm.WithEnterHooks("cancelled", record("enter cancelled")),
+	)
+
+	// The check on the parent's transition applies within it.
+	st.Expect(t, errors.Is(the_machine.Fire("cancel"), denied), true)
+	st.Expect(t, some_thing.State, fsm.State("drafting"))
+
+	// Only the states actually left and entered have their hooks called.
+	st.Expect(t, the_machine.Transition("approved"), nil)
+	st.Expect(t, calls, []string{"exit drafting", "exit review", "enter approved"})
+	calls = nil
+	st.Expect(t, the_machine.Transition("review"), nil)
+	st.Expect(t, calls, []string{"enter review"})
+
+	open = true
+	calls = nil
+	st.Expect(t, the_machine.Fire("cancel"), nil)
+	st.Expect(t, calls, []string{"exit review", "exit active", "enter cancelled"})
+
+	// Limits count the transitions out of nested states against the parent's.
+	some_thing.State = "approved"
+	st.Expect(t, errors.Is(the_machine.Transition("cancelled"), fsm.ErrLimitExceeded), true)
+
+	// Actions and error states are inherited like the rule.
+	var failed *fsm.FailedTransitionError
+	st.Expect(t, errors.As(the_machine.Transition("failed"), &failed), true)
+	st.Expect(t, failed.ErrorState, fsm.State("broken"))
+	st.Expect(t, some_thing.State, fsm.State("broken"))
+
+	// So are approvals.
+	some_thing.State = "drafting"
+	gated := fsm.New(
+		fsm.WithPermitter(h.Rules(rules)),
+		fsm.WithSubject(&some_thing),
+		fsm.RequireApprovals(fsm.T{"active", "cancelled"}, 1, "alice"),
+	)
+	st.Expect(t, errors.Is(gated.Transition("cancelled"), fsm.ErrApprovalRequired), true)
+	st.Expect(t, gated.Approve("alice"), nil)
+	st.Expect(t, gated.Transition("cancelled"), nil)
+}
@@ -0,0 +1,88 @@
+package fsm
+
+import (
+	"iter"
+	"sync"
+)
+
+// history keeps the transitions of a machine in memory. A nil *history, as
+// found in a Machine without WithHistory, records nothing.
+type history struct {
+	mu      sync.Mutex
+	entries []TransitionEvent
+	summary tally  // of entries dropped by Retention
+	seq     uint64 // of the latest entry, which Retention mustn't reset
+}
+
+// WithHistory is intended to be passed to New to keep every transition the
+// machine makes, so Machine.History can say who moved the subject where,
+// and when.
+func WithHistory() func(*Machine) {
+	return func(m *Machine) {
+		m.history = &history{}
+	}
+}
+
+// record keeps e. Events that weren't already numbered and chained by the
+// machine's EventStore are numbered and chained here.
+func (h *history) record(e TransitionEvent, chain *hashChain) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e.Seq == 0 {
+		e.Seq = h.seq + 1
+		chain.link(&e)
+		chain.advance(e)
+	}
+	h.seq = e.Seq
+	h.entries = append(h.entries, e)
+}
+
+// replace swaps the entries for events, as when importing a machine.
+func (h *history) replace(events []TransitionEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append([]TransitionEvent(nil), events...)
+	h.seq = 0
+	if len(events) > 0 {
+		h.seq = events[len(events)-1].Seq
+	}
+}
+
+// History returns the transitions made by the machine, oldest first. It is
+// empty unless the machine was created with WithHistory.
+func (m Machine) History() []TransitionEvent {
+	if m.history == nil {
+		return nil
+	}
+	m.history.mu.Lock()
+	defer m.history.mu.Unlock()
+
+	return append([]TransitionEvent(nil), m.history.entries...)
+}
+
+// HistorySeq is like History but yields the transitions instead of copying
+// them. Transitions made while iterating are not yielded.
+func (m Machine) HistorySeq() iter.Seq[TransitionEvent] {
+	return func(yield func(TransitionEvent) bool) {
+		if m.history == nil {
+			return
+		}
+		m.history.mu.Lock()
+		entries := m.history.entries
+		m.history.mu.Unlock()
+
+		for _, e := range entries {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
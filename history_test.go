@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestHistoryRecordsActor(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "cancelled"},
+	)
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithClock(fsm.ClockFunc(func() time.Time { return now })),
+		fsm.WithHistory(),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	now = now.Add(time.Hour)
+	st.Expect(t, the_machine.TransitionAs("alice", "cancelled", nil), nil)
+	st.Expect(t, the_machine.TransitionAs("bob", "started", nil), fsm.ErrInvalidTransition)
+
+	history := the_machine.History()
+	st.Expect(t, len(history), 2)
+	st.Expect(t, history[0].Actor, "")
+	st.Expect(t, history[1], fsm.TransitionEvent{
+		Seq:    2,
+		Origin: "started",
+		Goal:   "cancelled",
+		Actor:  "alice",
+		Time:   now,
+	})
+}
+
+func TestHistorySeq(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"}), fsm.WithHistory())
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("pending"), nil)
+
+	var goals []fsm.State
+	for e := range the_machine.HistorySeq() {
+		goals = append(goals, e.Goal)
+		the_machine.Transition(e.Goal) // not yielded
+	}
+	st.Expect(t, goals, []fsm.State{"started", "pending"})
+	st.Expect(t, len(the_machine.History()), 4)
+}
+
+func TestHistoryIsOptional(t *testing.T) {
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&Thing{State: "pending"}),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, len(the_machine.History()), 0)
+}
@@ -0,0 +1,112 @@
+package fsm
+
+import "context"
+
+// TransitionContext describes a single transition. Every callback the
+// machine invokes receives one, so they all share the same shape.
+type TransitionContext struct {
+	Subject Stater
+	Origin  State
+	Goal    State
+
+	// Event names what triggered the transition, if anything did.
+	Event   string
+	Payload interface{}
+
+	// Actor identifies who made the transition, if known.
+	Actor string
+
+	// Variant is the Experiment variant of the machine, if any.
+	Variant string
+
+	// Labels are the machine's labels. They must not be modified.
+	Labels map[string]string
+
+	// Forced is set for transitions made by ForceTransition, whose Reason
+	// says why.
+	Forced bool
+	Reason string
+
+	ctx context.Context
+}
+
+// Context returns the context the transition was made with, or
+// context.Background for transitions made without one. It carries the
+// caller's deadline, trace IDs and the like to every callback.
+func (tc TransitionContext) Context() context.Context {
+	if tc.ctx == nil {
+		return context.Background()
+	}
+	return tc.ctx
+}
+
+// WithContext returns a copy of tc that carries ctx.
+func (tc TransitionContext) WithContext(ctx context.Context) TransitionContext {
+	tc.ctx = ctx
+	return tc
+}
+
+// Hook is called by a Machine after a transition has been applied.
+type Hook func(TransitionContext)
+
+// WithHooks is intended to be passed to New to register Hooks
+func WithHooks(hooks ...Hook) func(*Machine) {
+	return func(m *Machine) {
+		m.hooks = append(m.hooks, hooks...)
+	}
+}
+
+// WithExitHooks is intended to be passed to New to register Hooks called
+// whenever the subject leaves state, before those of WithEnterHooks and
+// WithHooks.
+func WithExitHooks(state State, hooks ...Hook) func(*Machine) {
+	return func(m *Machine) {
+		if m.exitHooks == nil {
+			m.exitHooks = map[State][]Hook{}
+		}
+		m.exitHooks[state] = append(m.exitHooks[state], hooks...)
+	}
+}
+
+// WithEnterHooks is intended to be passed to New to register Hooks called
+// whenever the subject enters state, before those of WithHooks.
+//
+// A self-transition, such as T{"started", "started"} for retrying in
+// place, is a re-entry: it calls the exit and then the enter hooks of its
+// state, like any other transition.
+func WithEnterHooks(state State, hooks ...Hook) func(*Machine) {
+	return func(m *Machine) {
+		if m.enterHooks == nil {
+			m.enterHooks = map[State][]Hook{}
+		}
+		m.enterHooks[state] = append(m.enterHooks[state], hooks...)
+	}
+}
+
+// Reentry reports whether the transition is a self-transition, leaving and
+// re-entering the same state.
+func (tc TransitionContext) Reentry() bool {
+	return tc.Origin == tc.Goal
+}
+
+// callHooks calls the exit hooks of the states tc leaves, the enter hooks
+// of those it enters, and then the machine's hooks.
+func (m Machine) callHooks(tc TransitionContext) {
+	if len(m.exitHooks) > 0 {
+		for _, s := range m.hierarchy.exited(tc.Origin, tc.Goal) {
+			for _, hook := range m.exitHooks[s] {
+				hook(tc)
+			}
+		}
+	}
+	if len(m.enterHooks) > 0 {
+		for _, s := range m.hierarchy.entered(tc.Origin, tc.Goal) {
+			for _, hook := range m.enterHooks[s] {
+				hook(tc)
+			}
+		}
+	}
+	for _, hook := range m.hooks {
+		hook(tc)
+	}
+}
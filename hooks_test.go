@@ -0,0 +1,77 @@
+package fsm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestHooksReceiveTransitionContext(t *testing.T) {
+	var got []fsm.TransitionContext
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { got = append(got, tc) }),
+	)
+
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+	st.Expect(t, len(got), 0)
+
+	st.Expect(t, the_machine.TransitionWith("started", 42), nil)
+	st.Expect(t, len(got), 1)
+	st.Expect(t, got[0].Subject, fsm.Stater(&some_thing))
+	st.Expect(t, got[0].Origin, fsm.State("pending"))
+	st.Expect(t, got[0].Goal, fsm.State("started"))
+	st.Expect(t, got[0].Payload, 42)
+}
+
+func TestSelfTransitionReentersState(t *testing.T) {
+	var calls []string
+	record := func(name string) fsm.Hook {
+		return func(tc fsm.TransitionContext) {
+			calls = append(calls, fmt.Sprintf("%s %s->%s reentry=%v", name, tc.Origin, tc.Goal, tc.Reentry()))
+		}
+	}
+
+	attempts := 0
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	rules.AddRule(fsm.T{"started", "started"}, func(fsm.Stater, fsm.State) bool {
+		attempts++
+		return attempts < 3
+	})
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHistory(),
+		fsm.WithExitHooks("started", record("exit")),
+		fsm.WithEnterHooks("started", record("enter")),
+		fsm.WithHooks(record("hook")),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("started"), fsm.ErrInvalidTransition)
+
+	st.Expect(t, calls, []string{
+		"enter pending->started reentry=false",
+		"hook pending->started reentry=false",
+		"exit started->started reentry=true",
+		"enter started->started reentry=true",
+		"hook started->started reentry=true",
+		"exit started->started reentry=true",
+		"enter started->started reentry=true",
+		"hook started->started reentry=true",
+	})
+	st.Expect(t, len(the_machine.History()), 3)
+
+	// Self-transitions are only permitted when they are in the rules.
+	some_thing.State = "pending"
+	st.Expect(t, the_machine.Transition("pending"), fsm.ErrInvalidTransition)
+}
@@ -0,0 +1,224 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrDenied = errors.New("denied")
+
+// HTTPGuard delegates the decision about a transition to a remote service.
+// It POSTs the transition as JSON to URL:
+//
+//	{"origin": "pending", "goal": "approved", "event": "", "subject": {...}, "payload": {...}}
+//
+// and expects a JSON answer such as {"allow": false, "reason": "over budget"}.
+// Use its Check with WithChecks or WithPolicies.
+type HTTPGuard struct {
+	URL    string
+	Client *http.Client // http.DefaultClient when nil
+
+	// Timeout bounds each attempt; zero means no timeout.
+	Timeout time.Duration
+
+	// Retries is how many more attempts are made after one fails to reach
+	// the service or is answered with a 5xx status. Denials are answers,
+	// and other statuses mean the request itself is wrong, so neither is
+	// retried.
+	Retries int
+
+	// Backoff is how long to wait before the first retry, doubling with
+	// each one after it; 100ms when zero.
+	Backoff time.Duration
+
+	// CacheTTL is how long an answer is reused for an identical request.
+	// Answers aren't cached when it is zero.
+	CacheTTL time.Duration
+
+	// CacheSize bounds how many answers are cached at once; 1024 when
+	// zero. Expired answers are dropped first, then the oldest.
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[string]httpAnswer
+}
+
+type httpRequest struct {
+	Origin  State       `json:"origin"`
+	Goal    State       `json:"goal"`
+	Event   string      `json:"event"`
+	Subject interface{} `json:"subject"`
+	Payload interface{} `json:"payload"`
+}
+
+// httpStatusError is an attempt answered with a status other than 200.
+type httpStatusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("fsm: guard %s answered %s", e.url, e.status)
+}
+
+// retryable reports whether an attempt that failed with err is worth
+// making again.
+func retryable(err error) bool {
+	var status *httpStatusError
+	if errors.As(err, &status) {
+		return status.code >= 500
+	}
+	return true
+}
+
+type httpAnswer struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+
+	expires time.Time
+}
+
+// Check asks the remote service about tc. A denial is reported as an error
+// wrapping ErrDenied and ErrInvalidTransition.
+func (g *HTTPGuard) Check(tc TransitionContext) error {
+	body, err := json.Marshal(httpRequest{tc.Origin, tc.Goal, tc.Event, tc.Subject, tc.Payload})
+	if err != nil {
+		return err
+	}
+
+	answer, ok := g.cached(string(body))
+	if !ok {
+		if answer, err = g.askRetrying(tc.Context(), body); err != nil {
+			return err
+		}
+		g.store(string(body), answer)
+	}
+
+	if !answer.Allow {
+		return fmt.Errorf("%w: %w: %s -> %s: %s", ErrInvalidTransition, ErrDenied, tc.Origin, tc.Goal, answer.Reason)
+	}
+	return nil
+}
+
+// askRetrying asks the service, retrying with backoff while that may help.
+func (g *HTTPGuard) askRetrying(ctx context.Context, body []byte) (httpAnswer, error) {
+	backoff := g.Backoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		answer, err := g.ask(ctx, body)
+		if err == nil || attempt >= g.Retries || !retryable(err) {
+			return answer, err
+		}
+
+		wait := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return httpAnswer{}, errors.Join(err, ctx.Err())
+		case <-wait.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (g *HTTPGuard) ask(ctx context.Context, body []byte) (httpAnswer, error) {
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return httpAnswer{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return httpAnswer{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return httpAnswer{}, &httpStatusError{g.URL, resp.Status, resp.StatusCode}
+	}
+
+	var answer httpAnswer
+	err = json.NewDecoder(resp.Body).Decode(&answer)
+	return answer, err
+}
+
+func (g *HTTPGuard) cached(key string) (httpAnswer, bool) {
+	if g.CacheTTL <= 0 {
+		return httpAnswer{}, false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	answer, ok := g.cache[key]
+	if !ok || time.Now().After(answer.expires) {
+		return httpAnswer{}, false
+	}
+	return answer, true
+}
+
+func (g *HTTPGuard) store(key string, answer httpAnswer) {
+	if g.CacheTTL <= 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cache == nil {
+		g.cache = map[string]httpAnswer{}
+	}
+	size := g.CacheSize
+	if size <= 0 {
+		size = 1024
+	}
+	if _, ok := g.cache[key]; !ok && len(g.cache) >= size {
+		g.evict(size)
+	}
+
+	answer.expires = time.Now().Add(g.CacheTTL)
+	g.cache[key] = answer
+}
+
+// evict makes room for another answer in a cache of size entries, dropping
+// the expired ones and, when that isn't enough, the one expiring soonest,
+// which is the oldest since they all live for CacheTTL.
+func (g *HTTPGuard) evict(size int) {
+	now := time.Now()
+	var oldest string
+	var first time.Time
+	for key, answer := range g.cache {
+		if now.After(answer.expires) {
+			delete(g.cache, key)
+			continue
+		}
+		if first.IsZero() || answer.expires.Before(first) {
+			oldest, first = key, answer.expires
+		}
+	}
+	if len(g.cache) >= size {
+		delete(g.cache, oldest)
+	}
+}
@@ -0,0 +1,103 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestHTTPGuard(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first call of all fails, so it has to be retried
+		if atomic.AddInt64(&calls, 1) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req struct {
+			Goal    string
+			Payload struct{ Amount int }
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"allow":  req.Payload.Amount <= 100,
+			"reason": "over budget",
+		})
+	}))
+	defer server.Close()
+
+	guard := &fsm.HTTPGuard{URL: server.URL, Timeout: time.Second, Retries: 1, Backoff: time.Millisecond, CacheTTL: time.Minute}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "approved"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithChecks(fsm.T{"pending", "approved"}, guard.Check),
+	)
+
+	type Expense struct{ Amount int }
+
+	err := the_machine.TransitionWith("approved", Expense{500})
+	st.Expect(t, errors.Is(err, fsm.ErrDenied), true)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(2))
+
+	// the denial is cached
+	err = the_machine.TransitionWith("approved", Expense{500})
+	st.Expect(t, errors.Is(err, fsm.ErrDenied), true)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(2))
+
+	st.Expect(t, the_machine.TransitionWith("approved", Expense{50}), nil)
+	st.Expect(t, some_thing.State, fsm.State("approved"))
+}
+
+func TestHTTPGuardRetries(t *testing.T) {
+	var calls int64
+	status := int64(http.StatusBadRequest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		http.Error(w, "no", int(atomic.LoadInt64(&status)))
+	}))
+	defer server.Close()
+
+	guard := &fsm.HTTPGuard{URL: server.URL, Retries: 2, Backoff: time.Millisecond}
+	tc := fsm.TransitionContext{Origin: "pending", Goal: "approved"}
+
+	// a request the service rejects is wrong every time
+	st.Expect(t, guard.Check(tc) != nil, true)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(1))
+
+	atomic.StoreInt64(&status, http.StatusBadGateway)
+	st.Expect(t, guard.Check(tc) != nil, true)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(4))
+}
+
+func TestHTTPGuardCacheSize(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"allow": true})
+	}))
+	defer server.Close()
+
+	guard := &fsm.HTTPGuard{URL: server.URL, CacheTTL: time.Minute, CacheSize: 1}
+	first := fsm.TransitionContext{Origin: "pending", Goal: "approved"}
+	second := fsm.TransitionContext{Origin: "pending", Goal: "rejected"}
+
+	st.Expect(t, guard.Check(first), nil)
+	st.Expect(t, guard.Check(first), nil)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(1))
+
+	// the second answer pushes the first out
+	st.Expect(t, guard.Check(second), nil)
+	st.Expect(t, guard.Check(first), nil)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(3))
+}
@@ -0,0 +1,127 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// IdempotencyStore remembers which messages have been processed, so that
+// duplicates delivered by at-least-once sources can be absorbed.
+// Implementations backed by a shared store, such as Redis, absorb the
+// duplicates handled by other processes too.
+type IdempotencyStore interface {
+	// Claim records key as processed for ttl, or for good when ttl is zero.
+	// It reports false if key had already been claimed.
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release forgets key, so that a message whose processing failed can
+	// be processed again.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore for a single process.
+// Expired keys are swept out by Claim whenever the number held has doubled
+// since the last sweep, or by calling Sweep.
+type MemoryIdempotencyStore struct {
+	Clock Clock // defaults to SystemClock
+
+	mu      sync.Mutex
+	expires map[string]time.Time // zero for keys kept for good
+	sweepAt int                  // how many keys Claim sweeps at
+}
+
+func (s *MemoryIdempotencyStore) now() time.Time {
+	if s.Clock == nil {
+		return SystemClock{}.Now()
+	}
+	return s.Clock.Now()
+}
+
+func (s *MemoryIdempotencyStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expires == nil {
+		s.expires = map[string]time.Time{}
+	}
+	if len(s.expires) >= s.sweepAt {
+		s.sweep(now)
+	}
+	if expires, ok := s.expires[key]; ok && (expires.IsZero() || now.Before(expires)) {
+		return false, nil
+	}
+
+	s.expires[key] = time.Time{}
+	if ttl > 0 {
+		s.expires[key] = now.Add(ttl)
+	}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.expires, key)
+	return nil
+}
+
+// Sweep drops the keys whose claims have expired and reports how many
+// there were.
+func (s *MemoryIdempotencyStore) Sweep() int {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sweep(now)
+}
+
+// Len reports how many keys are held, expired or not.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.expires)
+}
+
+func (s *MemoryIdempotencyStore) sweep(now time.Time) int {
+	var swept int
+	for key, expires := range s.expires {
+		if !expires.IsZero() && !now.Before(expires) {
+			delete(s.expires, key)
+			swept++
+		}
+	}
+	s.sweepAt = max(2*len(s.expires), 64)
+	return swept
+}
+
+// RedisClient is the part of a Redis client RedisIdempotencyStore needs. It
+// is small enough to adapt any client library to.
+type RedisClient interface {
+	// SetNX sets key to value with an expiry of ttl, none when zero, unless
+	// key already exists, and reports whether it did.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Del(ctx context.Context, key string) error
+}
+
+// RedisIdempotencyStore is an IdempotencyStore kept in Redis, under keys
+// beginning with Prefix. It doesn't talk to Redis itself, so that this
+// module needn't depend on a client library: Client adapts whichever one
+// the program already uses. Redis expires the keys itself.
+type RedisIdempotencyStore struct {
+	Client RedisClient
+	Prefix string
+}
+
+func (s *RedisIdempotencyStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.Client.SetNX(ctx, s.Prefix+key, "1", ttl)
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.Client.Del(ctx, s.Prefix+key)
+}
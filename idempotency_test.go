@@ -0,0 +1,131 @@
+package fsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := &fsm.MemoryIdempotencyStore{Clock: fsm.ClockFunc(func() time.Time { return now })}
+	ctx := context.Background()
+
+	claimed, err := store.Claim(ctx, "a", time.Minute)
+	st.Expect(t, claimed, true)
+	st.Expect(t, err, nil)
+	claimed, _ = store.Claim(ctx, "a", time.Minute)
+	st.Expect(t, claimed, false)
+
+	now = now.Add(time.Minute)
+	claimed, _ = store.Claim(ctx, "a", 0)
+	st.Expect(t, claimed, true)
+	now = now.Add(24 * time.Hour)
+	claimed, _ = store.Claim(ctx, "a", 0)
+	st.Expect(t, claimed, false)
+
+	st.Expect(t, store.Release(ctx, "a"), nil)
+	claimed, _ = store.Claim(ctx, "a", 0)
+	st.Expect(t, claimed, true)
+}
+
+func TestDispatcherAbsorbsDuplicates(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "paid"}, fsm.T{"paid", "shipped"}, fsm.T{"shipped", "paid"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHistory(),
+	))
+	dispatcher := &fsm.Dispatcher{Registry: registry, Seen: &fsm.MemoryIdempotencyStore{}}
+	ctx := context.Background()
+
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "paid", ID: "m1"}), nil)
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "shipped", ID: "m2"}), nil)
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "paid", ID: "m1"}), nil)
+	st.Expect(t, some_thing.State, fsm.State("shipped"))
+
+	// lost deliveries may be retried
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-2", Goal: "paid", ID: "m3"}), fsm.ErrUnknownMachine)
+	registry.Put("order-2", fsm.New(fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "paid"})), fsm.WithSubject(&Thing{State: "pending"})))
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-2", Goal: "paid", ID: "m3"}), nil)
+
+	m, _ := registry.Get("order-1")
+	st.Expect(t, len(m.History()), 2)
+}
+
+func TestDispatcherForgetsFailedDeliveries(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+	registry := fsm.NewRegistry()
+	registry.Put("order-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "paid"}, fsm.T{"paid", "shipped"})),
+		fsm.WithSubject(&some_thing),
+	))
+	letters := &fsm.MemoryDeadLetters{}
+	seen := &fsm.MemoryIdempotencyStore{}
+	dispatcher := &fsm.Dispatcher{Registry: registry, DeadLetters: letters, Seen: seen}
+	ctx := context.Background()
+
+	// shipped before it was paid for, the delivery is dead lettered but
+	// not taken as seen, so replaying it once paid applies it
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "shipped", ID: "m1"}), fsm.ErrInvalidTransition)
+	st.Expect(t, len(letters.Letters()), 1)
+	st.Expect(t, dispatcher.Deliver(ctx, fsm.Delivery{Key: "order-1", Goal: "paid", ID: "m2"}), nil)
+	st.Expect(t, dispatcher.Deliver(ctx, letters.Letters()[0].Delivery), nil)
+	st.Expect(t, some_thing.State, fsm.State("shipped"))
+
+	claimed, _ := seen.Claim(ctx, "m1", 0)
+	st.Expect(t, claimed, false)
+}
+
+type fakeRedis map[string]string
+
+func (r fakeRedis) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if _, ok := r[key]; ok {
+		return false, nil
+	}
+	r[key] = value
+	return true, nil
+}
+
+func (r fakeRedis) Del(ctx context.Context, key string) error {
+	delete(r, key)
+	return nil
+}
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	redis := fakeRedis{}
+	store := &fsm.RedisIdempotencyStore{Client: redis, Prefix: "fsm:seen:"}
+	ctx := context.Background()
+
+	claimed, _ := store.Claim(ctx, "m1", time.Hour)
+	st.Expect(t, claimed, true)
+	claimed, _ = store.Claim(ctx, "m1", time.Hour)
+	st.Expect(t, claimed, false)
+	st.Expect(t, redis, fakeRedis{"fsm:seen:m1": "1"})
+	st.Expect(t, store.Release(ctx, "m1"), nil)
+	st.Expect(t, len(redis), 0)
+}
+
+func TestMemoryIdempotencyStoreSweeps(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := &fsm.MemoryIdempotencyStore{Clock: fsm.ClockFunc(func() time.Time { return now })}
+	ctx := context.Background()
+
+	store.Claim(ctx, "kept", 0)
+	for i := 0; i < 1000; i++ {
+		claimed, _ := store.Claim(ctx, fmt.Sprint(i), time.Second)
+		st.Expect(t, claimed, true, i)
+		now = now.Add(time.Second)
+	}
+	st.Expect(t, store.Len() < 200, true)
+
+	st.Expect(t, store.Sweep() > 0, true)
+	st.Expect(t, store.Len(), 1)
+	claimed, _ := store.Claim(ctx, "kept", 0)
+	st.Expect(t, claimed, false)
+}
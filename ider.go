@@ -0,0 +1,71 @@
+package fsm
+
+import "fmt"
+
+// IDer is implemented by values that identify a state, such as the
+// constants of an application's own state type. A State is an IDer of
+// itself, so rulesets and machines deal in States alone while subjects
+// are free to keep their state however they like.
+type IDer interface {
+	ID() string
+}
+
+// ID returns s as a string.
+func (s State) ID() string { return string(s) }
+
+// StateOf returns the State identified by id.
+func StateOf(id IDer) State {
+	if s, ok := id.(State); ok {
+		return s
+	}
+	return Intern(State(id.ID()))
+}
+
+// StaterOf adapts the state *p of a subject that keeps it as an S, rather
+// than as a State, to a Stater. states are the values *p may take. Setting
+// a State that identifies none of them leaves *p as it is; machines refuse
+// transitions to such a State with an ErrUnknownState before changing
+// anything, as it means the ruleset permits a state the subject cannot
+// hold.
+func StaterOf[S IDer](p *S, states ...S) Stater {
+	byID := make(map[State]S, len(states))
+	for _, s := range states {
+		byID[StateOf(s)] = s
+	}
+
+	return &idStater[S]{p, byID}
+}
+
+type idStater[S IDer] struct {
+	p    *S
+	byID map[State]S
+}
+
+func (s *idStater[S]) CurrentState() State { return StateOf(*s.p) }
+
+func (s *idStater[S]) SetState(state State) {
+	if v, ok := s.byID[state]; ok {
+		*s.p = v
+	}
+}
+
+// detach copies the state as well, which the Stater only points to.
+func (s *idStater[S]) detach() Stater {
+	v := *s.p
+	return &idStater[S]{&v, s.byID}
+}
+
+func (s *idStater[S]) holds(state State) bool {
+	_, ok := s.byID[state]
+	return ok
+}
+
+// unheld returns an error for transitions to a goal the subject can't
+// hold, such as one StaterOf wasn't given.
+func unheld(tc TransitionContext) error {
+	h, ok := tc.Subject.(interface{ holds(State) bool })
+	if !ok || h.holds(tc.Goal) {
+		return nil
+	}
+	return fmt.Errorf("%w: %w: %T has no state %q", ErrInvalidTransition, ErrUnknownState, tc.Subject, tc.Goal)
+}
@@ -0,0 +1,47 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type Status int
+
+const (
+	Draft Status = iota
+	Published
+)
+
+func (s Status) ID() string { return [...]string{"draft", "published"}[s] }
+
+type Post struct {
+	Status Status
+}
+
+func TestStaterOf(t *testing.T) {
+	st.Expect(t, fsm.StateOf(Published), fsm.State("published"))
+	st.Expect(t, fsm.StateOf(fsm.State("draft")), fsm.State("draft"))
+
+	some_post := Post{Status: Draft}
+	rules := fsm.CreateRuleset(
+		fsm.T{fsm.StateOf(Draft), fsm.StateOf(Published)},
+		fsm.T{fsm.StateOf(Published), "archived"},
+	)
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(fsm.StaterOf(&some_post.Status, Draft, Published)))
+
+	st.Expect(t, the_machine.Transition("published"), nil)
+	st.Expect(t, some_post.Status, Published)
+
+	// the rules permit a state the subject can't hold
+	err := the_machine.Transition("archived")
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownState), true)
+	st.Expect(t, some_post.Status, Published)
+
+	err = the_machine.ForceTransition("archived", "cleanup", "alice")
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownState), true)
+	st.Expect(t, some_post.Status, Published)
+}
@@ -0,0 +1,33 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownState = errors.New("state not in ruleset")
+
+// WithInitialStateFunc is intended to be passed to New to set the
+// subject's state to the one f derives from it, such as when imported
+// legacy records start in different states. New calls f once, after every
+// other option; NewE also checks the state is one the Rules know.
+func WithInitialStateFunc(f func(subject Stater) State) func(*Machine) {
+	return func(m *Machine) {
+		m.initial = f
+	}
+}
+
+// known returns ErrUnknownState unless the Rules mention state. Rules that
+// can't list their states know every state.
+func (m Machine) known(state State) error {
+	lister, ok := m.rules().(interface{ States() []State })
+	if !ok {
+		return nil
+	}
+	for _, s := range lister.States() {
+		if s == state {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownState, state)
+}
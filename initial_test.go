@@ -0,0 +1,55 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type LegacyThing struct {
+	Thing
+	Shipped bool
+}
+
+func TestWithInitialStateFunc(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "shipped"}, fsm.T{"shipped", "delivered"})
+	initial := func(subject fsm.Stater) fsm.State {
+		if subject.(*LegacyThing).Shipped {
+			return "shipped"
+		}
+		return "pending"
+	}
+
+	examples := []struct {
+		shipped bool
+		state   fsm.State
+	}{
+		{false, "pending"},
+		{true, "shipped"},
+	}
+
+	for i, ex := range examples {
+		some_thing := LegacyThing{Shipped: ex.shipped}
+		_, err := fsm.NewE(
+			fsm.WithInitialStateFunc(initial),
+			fsm.WithRules(rules),
+			fsm.WithSubject(&some_thing),
+		)
+		st.Expect(t, err, nil, i)
+		st.Expect(t, some_thing.State, ex.state, i)
+	}
+
+	_, err := fsm.NewE(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{}),
+		fsm.WithInitialStateFunc(func(fsm.Stater) fsm.State { return "lost" }),
+	)
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownState), true)
+}
+
+func TestRulesetStates(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})
+	st.Expect(t, rules.States(), []fsm.State{"finished", "pending", "started"})
+}
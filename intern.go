@@ -0,0 +1,21 @@
+package fsm
+
+import "unique"
+
+// Intern returns a canonical copy of s. Interned copies of an equal State
+// share the same memory, so holding many of them costs no more than holding
+// one. The canonical copy is only kept while a unique.Handle to it is held,
+// though: once none is, a later Intern makes a new one. A CompiledRuleset
+// holds a handle for each of its states, so interning any of those always
+// yields the ruleset's copy.
+//
+// Comparing two equal interned States is quick, since they share a
+// pointer, but telling unequal ones apart still compares bytes. Compare
+// the handles returned by CompiledRuleset.Handle, or by unique.Make, when
+// only a pointer comparison will do.
+//
+// States decoded while replaying or restoring are interned automatically, as
+// are the states of a CompiledRuleset.
+func Intern(s State) State {
+	return unique.Make(s).Value()
+}
@@ -0,0 +1,50 @@
+package fsm_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"unique"
+	"unsafe"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestIntern(t *testing.T) {
+	a := fsm.State(strings.Repeat("pending", 2))
+	b := fsm.State(strings.Repeat("pending", 2))
+	st.Expect(t, unsafe.StringData(string(a)) == unsafe.StringData(string(b)), false)
+
+	a, b = fsm.Intern(a), fsm.Intern(b)
+	st.Expect(t, a, b)
+	st.Expect(t, unsafe.StringData(string(a)) == unsafe.StringData(string(b)), true)
+}
+
+func TestReplayJSONInternsStates(t *testing.T) {
+	one, two := Thing{State: "pending"}, Thing{State: "pending"}
+	for _, subject := range []*Thing{&one, &two} {
+		the_machine := fsm.New(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(subject))
+		st.Expect(t, the_machine.ReplayJSON(strings.NewReader(`{"seq":1,"origin":"pending","goal":"started"}`)), nil)
+	}
+
+	st.Expect(t, unsafe.StringData(string(one.State)) == unsafe.StringData(string(two.State)), true)
+}
+
+func TestCompiledRulesetKeepsInterned(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{O: fsm.State(strings.Repeat("pending", 2)), E: "started"}).Compile()
+	runtime.GC()
+	runtime.GC()
+
+	// the ruleset's copy is still the canonical one
+	interned := fsm.Intern(fsm.State(strings.Repeat("pending", 2)))
+	held, ok := rules.Handle("pendingpending")
+	st.Expect(t, ok, true)
+	st.Expect(t, unsafe.StringData(string(interned)) == unsafe.StringData(string(held.Value())), true)
+	st.Expect(t, held == unique.Make(interned), true)
+
+	started, _ := rules.Handle("started")
+	st.Expect(t, held == started, false)
+	_, ok = rules.Handle("finished")
+	st.Expect(t, ok, false)
+}
@@ -0,0 +1,99 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvariantViolated = errors.New("invariant violated")
+
+// Invariant checks that a subject is sound for the state it is in.
+type Invariant func(subject Stater) error
+
+// ViolationPolicy says what a machine does when an Invariant fails.
+type ViolationPolicy int
+
+const (
+	// ViolationReturn returns the violation from the transition, which
+	// stands: the subject stays in the goal, and the transition's hooks
+	// are called, as when an Action fails without an error state.
+	ViolationReturn ViolationPolicy = iota
+
+	// ViolationErrorState handles the violation as if an Action had
+	// failed, moving the subject on to the machine's error state; see
+	// WithErrorState.
+	ViolationErrorState
+
+	// ViolationPanic panics, which suits development and tests.
+	ViolationPanic
+)
+
+// InvariantError reports a subject that broke an invariant of its state.
+type InvariantError struct {
+	State State
+	Err   error
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("%v in %s: %v", ErrInvariantViolated, e.State, e.Err)
+}
+
+func (e *InvariantError) Unwrap() []error { return []error{ErrInvariantViolated, e.Err} }
+
+type invariants struct {
+	byState map[State][]Invariant
+	policy  ViolationPolicy
+}
+
+// WithInvariants is intended to be passed to New to verify invariants
+// every time the subject enters state, after any Actions have run. The
+// subject is in state by then, so a violation doesn't undo the transition;
+// the ViolationPolicy says what becomes of it.
+func WithInvariants(state State, invariants ...Invariant) func(*Machine) {
+	return func(m *Machine) {
+		m.ensureInvariants()
+		m.invariants.byState[state] = append(m.invariants.byState[state], invariants...)
+	}
+}
+
+// WithViolationPolicy is intended to be passed to New to choose what
+// happens when an invariant fails. The default is ViolationReturn.
+func WithViolationPolicy(p ViolationPolicy) func(*Machine) {
+	return func(m *Machine) {
+		m.ensureInvariants()
+		m.invariants.policy = p
+	}
+}
+
+func (m *Machine) ensureInvariants() {
+	if m.invariants == nil {
+		m.invariants = &invariants{byState: map[State][]Invariant{}}
+	}
+}
+
+// verify checks the invariants of the goal of tc, which the subject has
+// just entered. A violation is returned as failed when the policy is to
+// treat it like a failed Action, and as violated when it is only to be
+// returned.
+func (m Machine) verify(tc TransitionContext) (failed, violated error) {
+	if m.invariants == nil {
+		return nil, nil
+	}
+	for _, invariant := range m.invariants.byState[tc.Goal] {
+		err := invariant(tc.Subject)
+		if err == nil {
+			continue
+		}
+
+		err = &InvariantError{State: tc.Goal, Err: err}
+		switch m.invariants.policy {
+		case ViolationErrorState:
+			return err, nil
+		case ViolationPanic:
+			panic(err)
+		default:
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type Invoice struct {
+	State fsm.State
+	Paid  int
+}
+
+func (i *Invoice) CurrentState() fsm.State  { return i.State }
+func (i *Invoice) SetState(state fsm.State) { i.State = state }
+
+var errUnpaid = errors.New("nothing paid")
+
+func mustBePaid(subject fsm.Stater) error {
+	if subject.(*Invoice).Paid == 0 {
+		return errUnpaid
+	}
+	return nil
+}
+
+func TestInvariants(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"open", "paid"})
+
+	examples := []struct {
+		policy fsm.ViolationPolicy
+		state  fsm.State
+	}{
+		{fsm.ViolationReturn, "paid"},
+		{fsm.ViolationErrorState, "corrupt"},
+	}
+
+	for i, ex := range examples {
+		var entered []fsm.State
+		invoice := &Invoice{State: "open"}
+		the_machine := fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(invoice),
+			fsm.WithInvariants("paid", mustBePaid),
+			fsm.WithViolationPolicy(ex.policy),
+			fsm.WithErrorState("corrupt"),
+			fsm.WithHooks(func(tc fsm.TransitionContext) { entered = append(entered, tc.Goal) }),
+		)
+
+		err := the_machine.Transition("paid")
+		st.Expect(t, errors.Is(err, fsm.ErrInvariantViolated), true, i)
+		st.Expect(t, errors.Is(err, errUnpaid), true, i)
+		st.Expect(t, invoice.State, ex.state, i)
+
+		// every state the subject entered was hooked, ending where it is
+		st.Expect(t, entered[len(entered)-1], ex.state, i)
+	}
+
+	invoice := &Invoice{State: "open", Paid: 10}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(invoice),
+		fsm.WithInvariants("paid", mustBePaid),
+	)
+	st.Expect(t, the_machine.Transition("paid"), nil)
+}
+
+func TestInvariantPanics(t *testing.T) {
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"open", "paid"})),
+		fsm.WithSubject(&Invoice{State: "open"}),
+		fsm.WithInvariants("paid", mustBePaid),
+		fsm.WithViolationPolicy(fsm.ViolationPanic),
+	)
+
+	defer func() {
+		err, _ := recover().(error)
+		st.Expect(t, errors.Is(err, errUnpaid), true)
+	}()
+	the_machine.Transition("paid")
+}
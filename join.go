@@ -0,0 +1,151 @@
+package fsm
+
+import "sync"
+
+// JoinPolicy decides when a Join stops waiting for its children.
+type JoinPolicy int
+
+const (
+	// WaitAll waits for every child to finish, then fails if any failed.
+	WaitAll JoinPolicy = iota
+
+	// FailFast fails as soon as any child fails.
+	FailFast
+)
+
+// Join fans a machine out into parallel child machines and back in again,
+// e.g. a CI pipeline that runs build, test, and lint stages at once.
+type Join struct {
+	State   State // entering this state spawns the children
+	Success State // the goal once every child has succeeded
+	Failure State // the goal once the Policy gives up on the children
+
+	Policy JoinPolicy
+
+	// Succeeded and Failed are the final states of the children.
+	Succeeded StateSet
+	Failed    StateSet
+
+	// Spawn creates the children, keyed by name.
+	Spawn func(tc TransitionContext) map[string]Machine
+
+	mu       sync.Mutex
+	parent   Machine
+	children map[string]Machine
+	waiting  bool
+	err      error
+}
+
+// Fork is intended to be passed to New to fan the machine out with j. Each
+// parent machine needs a Join of its own.
+func Fork(j *Join) func(*Machine) {
+	return func(parent *Machine) {
+		parent.hooks = append(parent.hooks, func(tc TransitionContext) {
+			if tc.Goal == j.State {
+				j.spawn(*parent, tc)
+			}
+		})
+	}
+}
+
+func (j *Join) spawn(parent Machine, tc TransitionContext) {
+	children := j.Spawn(tc)
+
+	j.mu.Lock()
+	j.parent = parent
+	j.children = map[string]Machine{}
+	for name, child := range children {
+		child.hooks = append(child.hooks, func(TransitionContext) { j.evaluate(parent) })
+		j.children[name] = child
+	}
+	j.waiting, j.err = true, nil
+	j.mu.Unlock()
+
+	j.evaluate(parent)
+}
+
+// evaluate moves the parent on once the children allow it. If the parent
+// refuses, the join keeps waiting and the refusal is kept for Err.
+func (j *Join) evaluate(parent Machine) error {
+	goal, ok := j.outcome()
+	if !ok {
+		return nil
+	}
+
+	err := parent.Transition(goal)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.err = err
+	if err != nil {
+		j.waiting = true
+	}
+	return err
+}
+
+// Err returns why the parent refused to move on once the children had
+// decided its goal, or nil if it hasn't. The join is then still waiting,
+// and tries again whenever a child transitions, or on Retry.
+func (j *Join) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.err
+}
+
+// Retry tries again to move the parent on, as after its refusal was
+// remedied, returning why it refused if it still does.
+func (j *Join) Retry() error {
+	j.mu.Lock()
+	parent := j.parent
+	j.mu.Unlock()
+
+	return j.evaluate(parent)
+}
+
+// outcome returns the parent's goal once the children have decided it. It
+// only does so once per fork, unless the parent refuses it.
+func (j *Join) outcome() (State, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.waiting {
+		return "", false
+	}
+
+	succeeded, failed := 0, 0
+	for _, child := range j.children {
+		switch state := child.Subject.CurrentState(); {
+		case j.Succeeded.Contains(state):
+			succeeded++
+		case j.Failed.Contains(state):
+			failed++
+		}
+	}
+
+	var goal State
+	switch {
+	case failed > 0 && (j.Policy == FailFast || succeeded+failed == len(j.children)):
+		goal = j.Failure
+	case succeeded == len(j.children):
+		goal = j.Success
+	default:
+		return "", false
+	}
+
+	j.waiting = false
+	return goal, true
+}
+
+// Children returns the machines spawned by the latest fork.
+func (j *Join) Children() map[string]Machine {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	children := make(map[string]Machine, len(j.children))
+	for name, child := range j.children {
+		children[name] = child
+	}
+	return children
+}
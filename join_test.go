@@ -0,0 +1,115 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func pipeline(policy fsm.JoinPolicy) (*Thing, *fsm.Join, fsm.Machine) {
+	stage := fsm.CreateRuleset(
+		fsm.T{"queued", "running"},
+		fsm.T{"running", "ok"},
+		fsm.T{"running", "failed"},
+	)
+
+	join := &fsm.Join{
+		State:     "running",
+		Success:   "passed",
+		Failure:   "failed",
+		Policy:    policy,
+		Succeeded: fsm.FromAnyOf("ok"),
+		Failed:    fsm.FromAnyOf("failed"),
+		Spawn: func(fsm.TransitionContext) map[string]fsm.Machine {
+			children := map[string]fsm.Machine{}
+			for _, name := range []string{"build", "test", "lint"} {
+				children[name] = fsm.New(fsm.WithRules(stage), fsm.WithSubject(&Thing{State: "queued"}))
+			}
+			return children
+		},
+	}
+
+	ci := &Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"pending", "running"},
+			fsm.T{"running", "passed"},
+			fsm.T{"running", "failed"},
+		)),
+		fsm.WithSubject(ci),
+		fsm.Fork(join),
+	)
+
+	return ci, join, the_machine
+}
+
+func TestJoinWaitAll(t *testing.T) {
+	ci, join, the_machine := pipeline(fsm.WaitAll)
+	st.Expect(t, the_machine.Transition("running"), nil)
+
+	children := join.Children()
+	st.Expect(t, len(children), 3)
+	for _, child := range children {
+		st.Expect(t, child.Transition("running"), nil)
+	}
+
+	st.Expect(t, children["lint"].Transition("failed"), nil)
+	st.Expect(t, ci.State, fsm.State("running"))
+	st.Expect(t, children["build"].Transition("ok"), nil)
+	st.Expect(t, ci.State, fsm.State("running"))
+	st.Expect(t, children["test"].Transition("ok"), nil)
+	st.Expect(t, ci.State, fsm.State("failed"))
+
+	_, join, the_machine = pipeline(fsm.WaitAll)
+	st.Expect(t, the_machine.Transition("running"), nil)
+	for _, child := range join.Children() {
+		st.Expect(t, child.Transition("running"), nil)
+		st.Expect(t, child.Transition("ok"), nil)
+	}
+	st.Expect(t, the_machine.Subject.CurrentState(), fsm.State("passed"))
+}
+
+func TestJoinFailFast(t *testing.T) {
+	ci, join, the_machine := pipeline(fsm.FailFast)
+	st.Expect(t, the_machine.Transition("running"), nil)
+
+	children := join.Children()
+	st.Expect(t, children["test"].Transition("running"), nil)
+	st.Expect(t, children["test"].Transition("failed"), nil)
+	st.Expect(t, ci.State, fsm.State("failed"))
+}
+
+func TestJoinRefused(t *testing.T) {
+	errFrozen := errors.New("frozen")
+	frozen := true
+
+	ci, join, _ := pipeline(fsm.FailFast)
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"pending", "running"},
+			fsm.T{"running", "failed"},
+		)),
+		fsm.WithSubject(ci),
+		fsm.WithChecks(fsm.T{"running", "failed"}, func(fsm.TransitionContext) error {
+			if frozen {
+				return errFrozen
+			}
+			return nil
+		}),
+		fsm.Fork(join),
+	)
+	st.Expect(t, the_machine.Transition("running"), nil)
+
+	children := join.Children()
+	st.Expect(t, children["test"].Transition("running"), nil)
+	st.Expect(t, children["test"].Transition("failed"), nil)
+	st.Expect(t, ci.State, fsm.State("running"))
+	st.Expect(t, errors.Is(join.Err(), errFrozen), true)
+
+	frozen = false
+	st.Expect(t, join.Retry(), nil)
+	st.Expect(t, join.Err(), nil)
+	st.Expect(t, ci.State, fsm.State("failed"))
+}
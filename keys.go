@@ -0,0 +1,40 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrUnhashableTransition = errors.New("transition cannot be used as a map key")
+
+// Keyer is implemented by Transitions that can't be used as map keys, such
+// as those holding slices or maps. Ruleset stores their rules under the
+// Transition returned by Key instead, which must be comparable. Permitted
+// looks rules up as T{origin, goal}, so Key should return
+// T{t.Origin(), t.Exit()}: rules stored under any other key are never
+// found.
+type Keyer interface {
+	Key() Transition
+}
+
+// key returns the Transition that t is stored under.
+func key(t Transition) Transition {
+	if k, ok := t.(Keyer); ok {
+		return k.Key()
+	}
+	return t
+}
+
+// hashable reports whether t can be a map key. Its value is checked rather
+// than its type, since a comparable struct may hold an interface whose
+// dynamic value isn't, and hashing that panics.
+func hashable(t Transition) bool {
+	return reflect.ValueOf(t).Comparable()
+}
+
+func mustBeHashable(t Transition) {
+	if !hashable(t) {
+		panic(fmt.Errorf("fsm: %w: %T; implement fsm.Keyer", ErrUnhashableTransition, t))
+	}
+}
@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// Route is a Transition that isn't comparable, so it can't be a map key.
+type Route struct {
+	Path []fsm.State
+}
+
+func (r Route) Origin() fsm.State { return r.Path[0] }
+func (r Route) Exit() fsm.State   { return r.Path[len(r.Path)-1] }
+
+// KeyedRoute tells the Ruleset how to store it.
+type KeyedRoute struct {
+	Route
+}
+
+func (r KeyedRoute) Key() fsm.Transition { return fsm.T{r.Origin(), r.Exit()} }
+
+func TestUnhashableTransitions(t *testing.T) {
+	route := Route{[]fsm.State{"pending", "queued", "started"}}
+
+	_, err := fsm.NewBuilder().Transition(route).Build()
+	st.Expect(t, errors.Is(err, fsm.ErrUnhashableTransition), true)
+
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			st.Expect(t, errors.Is(err, fsm.ErrUnhashableTransition), true)
+		}()
+		fsm.CreateRuleset(route)
+	}()
+
+	rules := fsm.CreateRuleset(KeyedRoute{route})
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "started"), true)
+
+	rules, err = fsm.NewBuilder().Transition(KeyedRoute{route}).Build()
+	st.Expect(t, err, nil)
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "started"), true)
+}
+
+// Tagged is comparable by type, but not when its Tag holds a slice.
+type Tagged struct {
+	fsm.T
+	Tag interface{}
+}
+
+func TestUnhashableTransitionValues(t *testing.T) {
+	tagged := Tagged{fsm.T{O: "pending", E: "started"}, []string{"urgent"}}
+
+	_, err := fsm.NewBuilder().Transition(tagged).Build()
+	st.Expect(t, errors.Is(err, fsm.ErrUnhashableTransition), true)
+
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			st.Expect(t, errors.Is(err, fsm.ErrUnhashableTransition), true)
+		}()
+		fsm.CreateRuleset(tagged)
+	}()
+
+	rules := fsm.CreateRuleset(Tagged{fsm.T{O: "pending", E: "started"}, "urgent"})
+	st.Expect(t, len(rules), 1)
+}
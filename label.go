@@ -0,0 +1,44 @@
+package fsm
+
+import "maps"
+
+// WithLabels is intended to be passed to New to attach labels, such as the
+// tenant, workflow or environment, to the machine. They are handed to
+// hooks and recorded on events, profiler labels and traces, and break
+// down the Stats of a Registry.
+func WithLabels(labels map[string]string) func(*Machine) {
+	return func(m *Machine) {
+		if m.labels == nil {
+			m.labels = map[string]string{}
+		}
+		maps.Copy(m.labels, labels)
+	}
+}
+
+// Labels returns a copy of the machine's labels.
+func (m Machine) Labels() map[string]string {
+	return maps.Clone(m.labels)
+}
+
+// HasLabels reports whether the machine carries every label in selector.
+func (m Machine) HasLabels(selector map[string]string) bool {
+	for name, value := range selector {
+		if v, ok := m.labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Label is one label of a machine.
+type Label struct {
+	Name, Value string
+}
+
+// RangeLabelled is like Range but only visits the machines carrying every
+// label in selector.
+func (r *Registry) RangeLabelled(selector map[string]string, fn func(key string, m Machine) bool) {
+	r.Range(func(key string, m Machine) bool {
+		return !m.HasLabels(selector) || fn(key, m)
+	})
+}
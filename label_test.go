@@ -0,0 +1,44 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestLabels(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	registry := fsm.NewRegistry()
+
+	tenants := map[string]string{"a": "acme", "b": "acme", "c": "globex"}
+	for key, tenant := range tenants {
+		registry.Put(key, fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithHistory(),
+			fsm.WithLabels(map[string]string{"tenant": tenant}),
+			fsm.WithLabels(map[string]string{"env": "prod"}),
+		))
+	}
+
+	st.Expect(t, registry.Transition("a", "started"), nil)
+	st.Expect(t, registry.Transition("c", "started"), nil)
+	st.Expect(t, registry.Transition("c", "started"), fsm.ErrInvalidTransition)
+
+	a, _ := registry.Get("a")
+	st.Expect(t, a.Labels(), map[string]string{"tenant": "acme", "env": "prod"})
+	st.Expect(t, a.History()[0].Labels, map[string]string{"tenant": "acme", "env": "prod"})
+
+	var acme []string
+	registry.RangeLabelled(map[string]string{"tenant": "acme", "env": "prod"}, func(key string, m fsm.Machine) bool {
+		acme = append(acme, key)
+		return true
+	})
+	st.Expect(t, len(acme), 2)
+
+	stats := registry.Stats()
+	st.Expect(t, stats.Labels[fsm.Label{Name: "tenant", Value: "acme"}].Transitions, uint64(1))
+	st.Expect(t, stats.Labels[fsm.Label{Name: "tenant", Value: "globex"}].Rejections["invalid"], uint64(1))
+	st.Expect(t, stats.Labels[fsm.Label{Name: "env", Value: "prod"}].Transitions, uint64(2))
+}
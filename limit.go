@@ -0,0 +1,280 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limit is intended to be passed to New. It caps the number of times the
+// machine may take t, e.g. "at most 3 retries". The machine keeps the count
+// itself; ResetLimit starts it over. A limit on a transition out of a state
+// counts those out of the states nested within it too.
+func Limit(t Transition, n int) func(*Machine) {
+	return func(m *Machine) {
+		if m.limits == nil {
+			m.limits = &limits{taken: map[T]int{}}
+
+			WithHooks(func(tc TransitionContext) {
+				for _, s := range m.hierarchy.lineage(tc.Origin) {
+					m.limits.add(T{s, tc.Goal})
+				}
+			})(m)
+		}
+
+		for _, t := range expand(t) {
+			key := T{t.Origin(), t.Exit()}
+
+			WithChecks(key, func(tc TransitionContext) error {
+				if taken := m.limits.count(key); taken >= n {
+					return fmt.Errorf("%w: %w: %s -> %s taken %d of %d times",
+						ErrInvalidTransition, ErrLimitExceeded, key.O, key.E, taken, n)
+				}
+				return nil
+			})(m)
+		}
+	}
+}
+
+type limits struct {
+	mu    sync.Mutex
+	taken map[T]int
+}
+
+func (l *limits) count(t T) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.taken[t]
+}
+
+func (l *limits) add(t T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.taken[t]++
+}
+
+// ResetLimit starts the count of t, as capped by Limit, over.
+func (m Machine) ResetLimit(t Transition) {
+	if m.limits == nil {
+		return
+	}
+
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	for _, t := range expand(t) {
+		delete(m.limits.taken, T{t.Origin(), t.Exit()})
+	}
+}
+
+// Counter stores named counts. Implementations backed by a shared store,
+// such as Redis, let a Quota span several processes.
+type Counter interface {
+	Get(ctx context.Context, key string) (int64, error)
+	Add(ctx context.Context, key string, delta int64) (int64, error)
+}
+
+// Reserver is implemented by Counters that can hold a place under a key
+// before counting it, so that a Quota spanning several processes isn't
+// overshot by machines entering its state at the same time.
+type Reserver interface {
+	// Reserve holds a place under key, unless its count and the places
+	// already held there have reached max, and reports whether it did. A
+	// place that isn't committed is given up after a while, so that one
+	// held for a transition that failed isn't held for good.
+	Reserve(ctx context.Context, key string, max int64) (bool, error)
+
+	// Commit adds one to the count of key, taking up a place held there
+	// if there is one.
+	Commit(ctx context.Context, key string) error
+}
+
+// MemoryCounter is a Counter and Reserver for a single process.
+type MemoryCounter struct {
+	Clock Clock         // defaults to SystemClock
+	Hold  time.Duration // how long a reserved place is held; 30s when zero
+
+	mu     sync.Mutex
+	counts map[string]int64
+	held   map[string][]time.Time // when each place expires, soonest first
+}
+
+func (c *MemoryCounter) Get(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts[key], nil
+}
+
+func (c *MemoryCounter) Add(ctx context.Context, key string, delta int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = map[string]int64{}
+	}
+	c.counts[key] += delta
+	return c.counts[key], nil
+}
+
+func (c *MemoryCounter) Reserve(ctx context.Context, key string, max int64) (bool, error) {
+	now := c.now()
+	hold := c.Hold
+	if hold <= 0 {
+		hold = 30 * time.Second
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	held := c.expire(key, now)
+	if c.counts[key]+int64(len(held)) >= max {
+		return false, nil
+	}
+	if c.held == nil {
+		c.held = map[string][]time.Time{}
+	}
+	c.held[key] = append(held, now.Add(hold))
+	return true, nil
+}
+
+func (c *MemoryCounter) Commit(ctx context.Context, key string) error {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held := c.expire(key, now); len(held) > 0 {
+		c.held[key] = held[1:]
+	}
+	if c.counts == nil {
+		c.counts = map[string]int64{}
+	}
+	c.counts[key]++
+	return nil
+}
+
+// expire gives up the places under key that expired by now, and returns
+// those still held.
+func (c *MemoryCounter) expire(key string, now time.Time) []time.Time {
+	held := c.held[key]
+	for len(held) > 0 && !now.Before(held[0]) {
+		held = held[1:]
+	}
+	if len(held) == 0 {
+		delete(c.held, key)
+		return nil
+	}
+	c.held[key] = held
+	return held
+}
+
+func (c *MemoryCounter) now() time.Time {
+	if c.Clock == nil {
+		return SystemClock{}.Now()
+	}
+	return c.Clock.Now()
+}
+
+// Quota is intended to be passed to New. It caps how many machines sharing
+// the counter c may be in state at once, e.g. "no more than 100 items in
+// processing per tenant". Machines entering and leaving the state are
+// counted under the key returned by key, and entering is refused once the
+// count has reached max.
+//
+// The count is checked along with the machine's checks, so Available and
+// the like see a full quota. When c is a Reserver, a place is also
+// reserved once the transition has been permitted, just before it is
+// recorded, and committed once it has been made, so machines entering
+// concurrently can't overshoot max. With any other Counter they may,
+// briefly, since the count is only updated after the transition.
+//
+// The count is updated as the transition is settled, along with its
+// snapshot; the transition stands if that fails, and the Counter's error
+// is returned.
+func Quota(c Counter, state State, max int64, key func(tc TransitionContext) string) func(*Machine) {
+	return func(m *Machine) {
+		q := quota{c, state, max, key}
+
+		WithPolicies(func(tc TransitionContext) error {
+			if !q.entered(tc) {
+				return nil
+			}
+
+			n, err := c.Get(tc.Context(), key(tc))
+			if err != nil {
+				return err
+			}
+			if n >= max {
+				return q.exceeded(fmt.Sprintf("%d of %d", n, max))
+			}
+			return nil
+		})(m)
+
+		m.quotas = append(m.quotas, q)
+	}
+}
+
+type quota struct {
+	counter Counter
+	state   State
+	max     int64
+	key     func(tc TransitionContext) string
+}
+
+func (q quota) entered(tc TransitionContext) bool {
+	return tc.Goal == q.state && tc.Origin != q.state
+}
+
+func (q quota) left(tc TransitionContext) bool {
+	return tc.Origin == q.state && tc.Goal != q.state
+}
+
+func (q quota) exceeded(taken string) error {
+	return fmt.Errorf("%w: %w: %s in %s", ErrInvalidTransition, ErrLimitExceeded, taken, q.state)
+}
+
+// reserve holds a place for tc in every quota it enters whose counter is a
+// Reserver.
+func (m Machine) reserve(tc TransitionContext) error {
+	for _, q := range m.quotas {
+		r, ok := q.counter.(Reserver)
+		if !ok || !q.entered(tc) {
+			continue
+		}
+		held, err := r.Reserve(tc.Context(), q.key(tc), q.max)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return q.exceeded(fmt.Sprintf("all %d places taken or held", q.max))
+		}
+	}
+	return nil
+}
+
+// count updates the counters of the quotas tc enters or leaves.
+func (m Machine) count(tc TransitionContext) error {
+	var errs []error
+	for _, q := range m.quotas {
+		switch {
+		case q.entered(tc):
+			if r, ok := q.counter.(Reserver); ok {
+				errs = append(errs, r.Commit(tc.Context(), q.key(tc)))
+			} else {
+				_, err := q.counter.Add(tc.Context(), q.key(tc), 1)
+				errs = append(errs, err)
+			}
+		case q.left(tc):
+			_, err := q.counter.Add(tc.Context(), q.key(tc), -1)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestLimit(t *testing.T) {
+	some_thing := Thing{State: "failed"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"failed", "retrying"},
+			fsm.T{"retrying", "failed"},
+		)),
+		fsm.WithSubject(&some_thing),
+		fsm.Limit(fsm.T{"failed", "retrying"}, 3),
+	)
+
+	for i := 0; i < 3; i++ {
+		st.Expect(t, the_machine.Transition("retrying"), nil, i)
+		st.Expect(t, the_machine.Transition("failed"), nil, i)
+	}
+
+	err := the_machine.Transition("retrying")
+	st.Expect(t, errors.Is(err, fsm.ErrLimitExceeded), true)
+	st.Expect(t, the_machine.Stats().Rejections["limit"], uint64(1))
+
+	the_machine.ResetLimit(fsm.T{"failed", "retrying"})
+	st.Expect(t, the_machine.Transition("retrying"), nil)
+}
+
+func TestQuota(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"queued", "processing"},
+		fsm.T{"processing", "done"},
+	)
+	counter := &fsm.MemoryCounter{}
+	tenant := func(fsm.TransitionContext) string { return "acme" }
+
+	machines := make([]fsm.Machine, 3)
+	for i := range machines {
+		machines[i] = fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "queued"}),
+			fsm.Quota(counter, "processing", 2, tenant),
+		)
+	}
+
+	st.Expect(t, machines[0].Transition("processing"), nil)
+	st.Expect(t, machines[1].Transition("processing"), nil)
+	st.Expect(t, errors.Is(machines[2].Transition("processing"), fsm.ErrLimitExceeded), true)
+
+	st.Expect(t, machines[0].Transition("done"), nil)
+	st.Expect(t, machines[2].Transition("processing"), nil)
+
+	n, _ := counter.Get(context.Background(), "acme")
+	st.Expect(t, n, int64(2))
+}
+
+func TestQuotaReserves(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	counter := &fsm.MemoryCounter{Clock: fsm.ClockFunc(func() time.Time { return now }), Hold: time.Minute}
+	tenant := func(fsm.TransitionContext) string { return "acme" }
+	rules := fsm.CreateRuleset(fsm.T{O: "queued", E: "processing"})
+
+	second := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "queued"}),
+		fsm.Quota(counter, "processing", 1, tenant),
+	)
+
+	// the second machine tries to enter while the first is entering, when
+	// its place is held but not yet counted
+	var overshot error
+	first := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "queued"}),
+		fsm.Quota(counter, "processing", 1, tenant),
+		fsm.WithActions(fsm.T{O: "queued", E: "processing"}, func(tc fsm.TransitionContext) error {
+			overshot = second.Transition("processing")
+			return nil
+		}),
+	)
+	st.Expect(t, first.Transition("processing"), nil)
+	st.Expect(t, errors.Is(overshot, fsm.ErrLimitExceeded), true)
+	n, _ := counter.Get(context.Background(), "acme")
+	st.Expect(t, n, int64(1))
+
+	// a place held for a transition that failed is given up in time
+	third := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "queued"}),
+		fsm.WithEventStore(&brokenStore{broken: true}, 0),
+		fsm.Quota(counter, "processing", 2, tenant),
+	)
+	st.Expect(t, third.Transition("processing"), errStoreDown)
+	held, _ := counter.Reserve(context.Background(), "acme", 2)
+	st.Expect(t, held, false)
+	now = now.Add(time.Minute)
+	held, _ = counter.Reserve(context.Background(), "acme", 2)
+	st.Expect(t, held, true)
+}
+
+// brokenCounter can't be updated.
+type brokenCounter struct{ fsm.MemoryCounter }
+
+var errCounterDown = errors.New("counter down")
+
+func (c *brokenCounter) Add(ctx context.Context, key string, delta int64) (int64, error) {
+	return 0, errCounterDown
+}
+
+func TestQuotaCounterFails(t *testing.T) {
+	some_thing := Thing{State: "processing"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "processing", E: "done"})),
+		fsm.WithSubject(&some_thing),
+		fsm.Quota(&brokenCounter{}, "processing", 2, func(fsm.TransitionContext) string { return "acme" }),
+	)
+
+	// the transition stands, but the count is off and the caller is told
+	st.Expect(t, errors.Is(the_machine.Transition("done"), errCounterDown), true)
+	st.Expect(t, some_thing.State, fsm.State("done"))
+}
@@ -0,0 +1,186 @@
+package fsm
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"errors"
+	"iter"
+	"slices"
+	"sort"
+)
+
+var ErrCursor = errors.New("bad registry cursor")
+
+// Page is one page of a Registry listing.
+type Page struct {
+	Keys []string
+
+	// Next is the cursor of the following page, empty on the last one.
+	Next string
+}
+
+// List returns the keys of up to limit machines selected by f, in key
+// order, starting after cursor: empty for the first page, and the Next of
+// the previous page after that. Only the page is held in memory, so a
+// registry of any size can be walked page by page; machines added or
+// removed in between may or may not be listed. Each shard keeps its keys
+// sorted, so a page costs about as much as the keys it goes through,
+// however large the registry; keys put or deleted since the last listing
+// are sorted in when the next one begins, which keeps Put and Delete
+// constant time.
+func (r *Registry) List(f Filter, cursor string, limit int) (Page, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Page{}, ErrCursor
+	}
+	if limit < 1 {
+		limit = 1
+	}
+
+	clock := f.clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+	match := func(key string, m Machine) bool { return f.match(key, m, now) }
+
+	// the next key of every shard, merged in key order
+	next := make(shardKeys, 0, len(r.shards))
+	for i := range r.shards {
+		if key, ok := r.shards[i].next(string(after), cursor == ""); ok {
+			next = append(next, shardKey{key, i})
+		}
+	}
+	heap.Init(&next)
+
+	// limit+1 keys, to know if there's more
+	var keys []string
+	for len(next) > 0 && len(keys) <= limit {
+		key, shard := next[0].key, next[0].shard
+		if e, ok := r.entry(key); ok && e.visit(key, match) {
+			keys = append(keys, key)
+		}
+		if key, ok := r.shards[shard].next(key, false); ok {
+			next[0].key = key
+			heap.Fix(&next, 0)
+		} else {
+			heap.Pop(&next)
+		}
+	}
+
+	page := Page{Keys: keys}
+	if len(keys) > limit {
+		page.Keys = keys[:limit]
+		page.Next = base64.RawURLEncoding.EncodeToString([]byte(keys[limit-1]))
+	}
+	return page, nil
+}
+
+// All yields every machine in the registry, in no particular order. Only
+// the keys of one shard are held in memory at a time; machines added or
+// removed while iterating may or may not be yielded.
+func (r *Registry) All() iter.Seq2[string, Machine] {
+	return func(yield func(string, Machine) bool) {
+		r.Range(yield)
+	}
+}
+
+func (s *registryShard) keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// next returns the first key after key, or from key on when inclusive.
+func (s *registryShard) next(key string, inclusive bool) (string, bool) {
+	s.mu.RLock()
+	if s.stale {
+		s.mu.RUnlock()
+		s.mu.Lock()
+		s.sort()
+		s.mu.Unlock()
+		s.mu.RLock()
+	}
+	defer s.mu.RUnlock()
+
+	i := sort.SearchStrings(s.sorted, key)
+	if i < len(s.sorted) && s.sorted[i] == key && !inclusive {
+		i++
+	}
+	if i == len(s.sorted) {
+		return "", false
+	}
+	return s.sorted[i], true
+}
+
+// put stores e under key. The shard must be locked.
+func (s *registryShard) put(key string, e *registryEntry) {
+	if _, ok := s.entries[key]; !ok {
+		s.added = append(s.added, key)
+		s.stale = true
+	}
+	s.entries[key] = e
+}
+
+// remove deletes the entry under key. The shard must be locked.
+func (s *registryShard) remove(key string) {
+	if _, ok := s.entries[key]; !ok {
+		return
+	}
+	delete(s.entries, key)
+	s.stale = true
+}
+
+// sort merges the keys added since it last ran into sorted, dropping those
+// removed meanwhile. The shard must be locked.
+func (s *registryShard) sort() {
+	if !s.stale {
+		return
+	}
+	slices.Sort(s.added)
+
+	merged := make([]string, 0, len(s.entries))
+	keep := func(key string) {
+		_, ok := s.entries[key]
+		if ok && (len(merged) == 0 || merged[len(merged)-1] != key) {
+			merged = append(merged, key)
+		}
+	}
+	i, j := 0, 0
+	for i < len(s.sorted) || j < len(s.added) {
+		if j == len(s.added) || i < len(s.sorted) && s.sorted[i] <= s.added[j] {
+			keep(s.sorted[i])
+			i++
+		} else {
+			keep(s.added[j])
+			j++
+		}
+	}
+
+	s.sorted, s.added, s.stale = merged, nil, false
+}
+
+// shardKey is the next key of a shard to be merged by List.
+type shardKey struct {
+	key   string
+	shard int
+}
+
+// shardKeys is a heap of shardKeys, smallest key first.
+type shardKeys []shardKey
+
+func (h shardKeys) Len() int           { return len(h) }
+func (h shardKeys) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h shardKeys) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *shardKeys) Push(x any)        { *h = append(*h, x.(shardKey)) }
+func (h *shardKeys) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
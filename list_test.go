@@ -0,0 +1,86 @@
+package fsm_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistryList(t *testing.T) {
+	registry := fsm.NewRegistry(fsm.WithShards(4))
+	for i := range 25 {
+		state := fsm.State("pending")
+		if i%5 == 0 {
+			state = "started"
+		}
+		registry.Put(fmt.Sprintf("job-%02d", i), fsm.New(fsm.WithSubject(&Thing{State: state})))
+	}
+
+	var keys []string
+	pages := 0
+	for cursor := ""; pages == 0 || cursor != ""; pages++ {
+		page, err := registry.List(fsm.InState("pending"), cursor, 7)
+		st.Expect(t, err, nil)
+		keys = append(keys, page.Keys...)
+		cursor = page.Next
+	}
+	st.Expect(t, pages, 3)
+	st.Expect(t, len(keys), 20)
+	st.Expect(t, keys[0], "job-01")
+	st.Expect(t, keys[19], "job-24")
+	for i := 1; i < len(keys); i++ {
+		st.Expect(t, keys[i-1] < keys[i], true, i)
+	}
+
+	page, err := registry.List(fsm.Filter{}, "", 25)
+	st.Expect(t, err, nil)
+	st.Expect(t, len(page.Keys), 25)
+	st.Expect(t, page.Next, "")
+
+	// machines removed and replaced are kept out of and in the listing
+	registry.Delete("job-01")
+	registry.Put("job-02", fsm.New(fsm.WithSubject(&Thing{State: "pending"})))
+	page, err = registry.List(fsm.InState("pending"), "", 2)
+	st.Expect(t, err, nil)
+	st.Expect(t, page.Keys, []string{"job-02", "job-03"})
+	page, err = registry.List(fsm.InState("pending"), page.Next, 2)
+	st.Expect(t, err, nil)
+	st.Expect(t, page.Keys, []string{"job-04", "job-06"})
+
+	_, err = registry.List(fsm.Filter{}, "not a cursor!", 10)
+	st.Expect(t, errors.Is(err, fsm.ErrCursor), true)
+
+	n := 0
+	for _, m := range registry.All() {
+		st.Expect(t, m.Subject != nil, true)
+		if n++; n == 10 {
+			break
+		}
+	}
+	st.Expect(t, n, 10)
+}
+
+func TestRegistryListAfterChanges(t *testing.T) {
+	registry := fsm.NewRegistry(fsm.WithShards(1))
+	listed := func() []string {
+		page, err := registry.List(fsm.Filter{}, "", 100)
+		st.Expect(t, err, nil)
+		return page.Keys
+	}
+	for _, key := range []string{"d", "b", "a"} {
+		registry.Put(key, fsm.New(fsm.WithSubject(&Thing{State: "pending"})))
+	}
+	st.Expect(t, listed(), []string{"a", "b", "d"})
+
+	// keys put, deleted and put back between listings are listed once
+	registry.Put("c", fsm.New(fsm.WithSubject(&Thing{State: "pending"})))
+	registry.Delete("b")
+	registry.Delete("d")
+	registry.Put("d", fsm.New(fsm.WithSubject(&Thing{State: "pending"})))
+	registry.Put("e", fsm.New(fsm.WithSubject(&Thing{State: "pending"})))
+	registry.Delete("e")
+	st.Expect(t, listed(), []string{"a", "c", "d"})
+}
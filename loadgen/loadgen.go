@@ -0,0 +1,154 @@
+// Package loadgen drives the machines of a Registry with random, weighted
+// transitions, for soak-testing services built on fsm.
+package loadgen
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// ErrStuck is counted when a machine is in a state none of the weighted
+// transitions leave.
+var ErrStuck = errors.New("no weighted transition out of the current state")
+
+// Generator makes random transitions of the machines in a Registry. Each
+// attempt picks a machine at random, then one of the transitions out of
+// its current state, in proportion to their Weights.
+type Generator struct {
+	Registry *fsm.Registry
+	Weights  map[fsm.T]float64
+
+	Rate    float64 // attempts per second; 0 is as fast as possible
+	Workers int     // attempting concurrently; at least 1
+	Seed    uint64  // makes the sequence of choices repeatable
+
+	// Restart, if set, is called under the machine's lock when it is stuck,
+	// to put it back somewhere the load can continue from. Workers may call
+	// it concurrently for different machines.
+	Restart func(key string, m fsm.Machine)
+}
+
+// Report counts what a run did.
+type Report struct {
+	Attempts    uint64
+	Transitions uint64
+	Errors      map[string]uint64 // by message
+	Elapsed     time.Duration
+}
+
+// Run makes n attempts, or keeps going until ctx is done if n isn't
+// positive. It returns ctx's error only if the run was cut short.
+func (g *Generator) Run(ctx context.Context, n int) (Report, error) {
+	var keys []string
+	g.Registry.Range(func(key string, m fsm.Machine) bool {
+		keys = append(keys, key)
+		return true
+	})
+	slices.Sort(keys)
+	if len(keys) == 0 {
+		return Report{Errors: map[string]uint64{}}, nil
+	}
+
+	moves := map[fsm.State][]fsm.T{}
+	for t := range g.Weights {
+		moves[t.O] = append(moves[t.O], t)
+	}
+	for _, ts := range moves {
+		slices.SortFunc(ts, func(a, b fsm.T) int { return cmp.Compare(a.E, b.E) })
+	}
+
+	var (
+		attempts = make(chan *rand.Rand)
+		mu       sync.Mutex
+		report   = Report{Errors: map[string]uint64{}}
+		wg       sync.WaitGroup
+		start    = time.Now()
+	)
+
+	for range max(g.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rng := range attempts {
+				err := g.attempt(keys[rng.IntN(len(keys))], moves, rng)
+
+				mu.Lock()
+				report.Attempts++
+				if err == nil {
+					report.Transitions++
+				} else {
+					report.Errors[err.Error()]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var tick <-chan time.Time
+	if g.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / g.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var err error
+	seed := rand.New(rand.NewPCG(g.Seed, g.Seed))
+feed:
+	for i := 0; n <= 0 || i < n; i++ {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				err = ctx.Err()
+				break feed
+			}
+		}
+
+		// each attempt gets its own generator, so workers don't share one
+		rng := rand.New(rand.NewPCG(seed.Uint64(), seed.Uint64()))
+		select {
+		case attempts <- rng:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(attempts)
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	return report, err
+}
+
+func (g *Generator) attempt(key string, moves map[fsm.State][]fsm.T, rng *rand.Rand) error {
+	return g.Registry.Do(key, func(m fsm.Machine) error {
+		options := moves[m.Subject.CurrentState()]
+		total := 0.0
+		for _, t := range options {
+			total += g.Weights[t]
+		}
+		if total <= 0 {
+			if g.Restart != nil {
+				g.Restart(key, m)
+			}
+			return ErrStuck
+		}
+
+		x := rng.Float64() * total
+		goal := options[len(options)-1].E
+		for _, t := range options {
+			if x -= g.Weights[t]; x < 0 {
+				goal = t.E
+				break
+			}
+		}
+		return m.Transition(goal)
+	})
+}
@@ -0,0 +1,80 @@
+package loadgen_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/loadgen"
+)
+
+type Job struct {
+	State fsm.State
+}
+
+func (j *Job) CurrentState() fsm.State  { return j.State }
+func (j *Job) SetState(state fsm.State) { j.State = state }
+
+func TestGenerator(t *testing.T) {
+	weights := map[fsm.T]float64{
+		{O: "queued", E: "running"}:  1,
+		{O: "running", E: "done"}:    3,
+		{O: "running", E: "failed"}:  1,
+		{O: "failed", E: "queued"}:   1,
+		{O: "running", E: "unknown"}: 0,
+	}
+	rules := fsm.CreateRuleset(
+		fsm.T{O: "queued", E: "running"},
+		fsm.T{O: "running", E: "done"},
+		fsm.T{O: "running", E: "failed"},
+		fsm.T{O: "failed", E: "queued"},
+	)
+
+	registry := fsm.NewRegistry()
+	for i := range 50 {
+		registry.Put(fmt.Sprint("job-", i), fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Job{State: "queued"})))
+	}
+
+	var restarted atomic.Uint64
+	g := &loadgen.Generator{
+		Registry: registry,
+		Weights:  weights,
+		Workers:  4,
+		Seed:     7,
+		Restart: func(key string, m fsm.Machine) {
+			restarted.Add(1)
+			m.Subject.SetState("queued")
+		},
+	}
+
+	report, err := g.Run(context.Background(), 2000)
+	st.Expect(t, err, nil)
+	st.Expect(t, report.Attempts, uint64(2000))
+	st.Expect(t, report.Transitions+report.Errors[loadgen.ErrStuck.Error()], uint64(2000))
+	st.Expect(t, restarted.Load(), report.Errors[loadgen.ErrStuck.Error()])
+	st.Expect(t, report.Transitions > 1000, true)
+}
+
+func TestGeneratorRate(t *testing.T) {
+	registry := fsm.NewRegistry()
+	registry.Put("job", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "queued", E: "queued"})),
+		fsm.WithSubject(&Job{State: "queued"}),
+	))
+
+	g := &loadgen.Generator{
+		Registry: registry,
+		Weights:  map[fsm.T]float64{{O: "queued", E: "queued"}: 1},
+		Rate:     200,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report, err := g.Run(ctx, 0)
+	st.Expect(t, err, context.DeadlineExceeded)
+	st.Expect(t, report.Attempts > 5 && report.Attempts <= 21, true)
+}
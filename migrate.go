@@ -0,0 +1,120 @@
+package fsm
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Migration moves many machines of a Registry from one state to another at
+// once, as when remedying an incident. Every machine goes through a normal
+// transition, guards and all.
+type Migration struct {
+	From, To State
+	Actor    string                           // who the migration is made by
+	Filter   func(key string, m Machine) bool // further narrows the machines; nil matches all
+
+	DryRun bool    // only check which machines would be let through
+	Rate   float64 // transitions per second; 0 is as fast as possible
+
+	// Progress, if set, is called after each machine with a copy of the
+	// report so far.
+	Progress func(MigrationReport)
+}
+
+// MigrationReport says what a Migration did, or would do in a dry run.
+type MigrationReport struct {
+	Matched  int              // machines in From that passed the Filter
+	Migrated []string         // keys of the machines moved (or movable) to To
+	Failed   map[string]error // keys of the machines that couldn't be, and why
+}
+
+// Migrate runs mg over the registry. Machines are visited in key order; the
+// report so far is returned along with ctx's error if ctx ends first. A dry
+// run checks each machine as fully as CanTransition would, and the
+// approvals and Authorizer too, without changing anything. The Rate is kept
+// by waiting between machines, never while one of them is held.
+func (r *Registry) Migrate(ctx context.Context, mg Migration) (MigrationReport, error) {
+	auth := AuthRequest{Operation: OpMigrate, Actor: mg.Actor, Origin: mg.From, Goal: mg.To}
+	if err := r.authorizer.authorize(auth); err != nil {
+		return MigrationReport{}, err
+	}
+
+	var keys []string
+	r.Range(func(key string, m Machine) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+
+	var tick <-chan time.Time
+	if mg.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / mg.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	report := MigrationReport{Failed: map[string]error{}}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		matches := func(m Machine) bool {
+			return m.Subject.CurrentState() == mg.From && (mg.Filter == nil || mg.Filter(key, m))
+		}
+		matched := false
+		if err := r.Do(key, func(m Machine) error {
+			matched = matches(m)
+			return nil
+		}); err == ErrUnknownMachine || !matched {
+			continue // deleted since the keys were listed, or not to be migrated
+		}
+
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return report, ctx.Err()
+			}
+		}
+
+		err := r.Do(key, func(m Machine) error {
+			if matched = matches(m); !matched {
+				return nil // moved on while waiting its turn
+			}
+			if mg.DryRun {
+				tc, _ := m.peek(mg.To)
+				tc.Actor = mg.Actor
+				return m.permit(tc)
+			}
+			return m.TransitionAs(mg.Actor, mg.To, nil)
+		})
+		if err == ErrUnknownMachine || !matched {
+			continue
+		}
+
+		report.Matched++
+		if err == nil {
+			report.Migrated = append(report.Migrated, key)
+		} else {
+			report.Failed[key] = err
+		}
+		if mg.Progress != nil {
+			mg.Progress(report.clone())
+		}
+	}
+
+	return report, nil
+}
+
+// clone returns a copy of r that shares nothing with it.
+func (r MigrationReport) clone() MigrationReport {
+	return MigrationReport{
+		Matched:  r.Matched,
+		Migrated: slices.Clone(r.Migrated),
+		Failed:   maps.Clone(r.Failed),
+	}
+}
@@ -0,0 +1,87 @@
+package fsm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistryMigrate(t *testing.T) {
+	registry := fsm.NewRegistry()
+	subjects := map[string]*Thing{}
+	for i := range 6 {
+		key := fmt.Sprint("job-", i)
+		state := fsm.State("stuck")
+		if i == 5 {
+			state = "done"
+		}
+		subjects[key] = &Thing{State: state}
+
+		rules := fsm.CreateRuleset(fsm.T{"stuck", "retrying"})
+		if i == 3 {
+			rules.AddRule(fsm.T{"stuck", "retrying"}, func(fsm.Stater, fsm.State) bool { return false })
+		}
+		registry.Put(key, fsm.New(fsm.WithRules(rules), fsm.WithSubject(subjects[key])))
+	}
+
+	migration := fsm.Migration{
+		From:   "stuck",
+		To:     "retrying",
+		Filter: func(key string, m fsm.Machine) bool { return !strings.HasSuffix(key, "4") },
+		DryRun: true,
+	}
+
+	report, err := registry.Migrate(context.Background(), migration)
+	st.Expect(t, err, nil)
+	st.Expect(t, report.Matched, 4)
+	st.Expect(t, report.Migrated, []string{"job-0", "job-1", "job-2"})
+	st.Expect(t, report.Failed["job-3"], fsm.ErrInvalidTransition)
+	st.Expect(t, subjects["job-0"].State, fsm.State("stuck"))
+
+	var progress []int
+	migration.DryRun = false
+	migration.Rate = 1000
+	migration.Progress = func(r fsm.MigrationReport) { progress = append(progress, r.Matched) }
+
+	report, err = registry.Migrate(context.Background(), migration)
+	st.Expect(t, err, nil)
+	st.Expect(t, report.Migrated, []string{"job-0", "job-1", "job-2"})
+	st.Expect(t, progress, []int{1, 2, 3, 4})
+	st.Expect(t, subjects["job-0"].State, fsm.State("retrying"))
+	st.Expect(t, subjects["job-3"].State, fsm.State("stuck"))
+	st.Expect(t, subjects["job-4"].State, fsm.State("stuck"))
+}
+
+func TestRegistryMigrateDryRunChecks(t *testing.T) {
+	errFrozen := fmt.Errorf("frozen")
+	registry := fsm.NewRegistry()
+	registry.Put("job-0", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"stuck", "retrying"})),
+		fsm.WithSubject(&Thing{State: "stuck"}),
+		fsm.WithChecks(fsm.T{"stuck", "retrying"}, func(fsm.TransitionContext) error { return errFrozen }),
+	))
+	registry.Put("job-1", fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"stuck", "retrying"})),
+		fsm.WithSubject(&Thing{State: "stuck"}),
+	))
+
+	var reports []fsm.MigrationReport
+	report, err := registry.Migrate(context.Background(), fsm.Migration{
+		From:   "stuck",
+		To:     "retrying",
+		DryRun: true,
+		Progress: func(r fsm.MigrationReport) {
+			r.Failed["tampered"] = nil // a copy, so the migration doesn't see it
+			reports = append(reports, r)
+		},
+	})
+	st.Expect(t, err, nil)
+	st.Expect(t, report.Migrated, []string{"job-1"})
+	st.Expect(t, report.Failed, map[string]error{"job-0": errFrozen})
+	st.Expect(t, len(reports), 2)
+	st.Expect(t, len(reports[0].Migrated), 0)
+}
@@ -0,0 +1,104 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OPAGuard asks an Open Policy Agent server whether a transition is allowed,
+// through its data API. The input document is
+//
+//	{"subject": {...}, "origin": "pending", "goal": "approved", "event": "", "actor": "alice", "principal": {...}, "payload": {...}}
+//
+// where actor is the Actor of the transition, as given to TransitionAs,
+// and principal is the payload when it is a Principal. The decision at Path,
+// e.g. "workflows/allow", must either be a boolean or an object such as
+// {"allow": false, "reason": "..."}; an undefined decision denies. Use its
+// Check with WithChecks or WithPolicies.
+type OPAGuard struct {
+	URL    string // of the OPA server, e.g. http://localhost:8181
+	Path   string // of the decision below /v1/data
+	Client *http.Client
+
+	// Timeout bounds each query; zero means no timeout.
+	Timeout time.Duration
+}
+
+type opaInput struct {
+	Subject   interface{} `json:"subject"`
+	Origin    State       `json:"origin"`
+	Goal      State       `json:"goal"`
+	Event     string      `json:"event"`
+	Actor     string      `json:"actor"`
+	Principal interface{} `json:"principal"`
+	Payload   interface{} `json:"payload"`
+}
+
+// Check queries OPA about tc. A denial is reported as an error wrapping
+// ErrDenied and ErrInvalidTransition.
+func (g *OPAGuard) Check(tc TransitionContext) error {
+	input := opaInput{Subject: tc.Subject, Origin: tc.Origin, Goal: tc.Goal, Event: tc.Event, Actor: tc.Actor, Payload: tc.Payload}
+	if p, ok := tc.Payload.(Principal); ok {
+		input.Principal = p
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return err
+	}
+
+	ctx := tc.Context()
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+
+	url := strings.TrimSuffix(g.URL, "/") + "/v1/data/" + strings.TrimPrefix(g.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fsm: opa %s answered %s", url, resp.Status)
+	}
+
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return err
+	}
+
+	var answer httpAnswer
+	if len(decision.Result) == 0 {
+		answer.Reason = "undefined decision"
+	} else if err := json.Unmarshal(decision.Result, &answer.Allow); err != nil {
+		if err := json.Unmarshal(decision.Result, &answer); err != nil {
+			return fmt.Errorf("fsm: opa decision %s: %w", g.Path, err)
+		}
+	}
+
+	if !answer.Allow {
+		return fmt.Errorf("%w: %w: %s -> %s: %s", ErrInvalidTransition, ErrDenied, tc.Origin, tc.Goal, answer.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestOPAGuard(t *testing.T) {
+	// stands in for OPA evaluating a policy that lets admins and the
+	// operator do anything and leaves the decision undefined otherwise
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.Expect(t, r.URL.Path, "/v1/data/workflows/allow")
+
+		var query struct {
+			Input struct {
+				Origin, Goal string
+				Actor        string
+				Principal    *struct{ Group []string }
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+
+		admin := query.Input.Principal != nil && fmt.Sprint(query.Input.Principal.Group) == "[admin]"
+		if admin || query.Input.Actor == "operator" {
+			fmt.Fprint(w, `{"result": true}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	guard := &fsm.OPAGuard{URL: server.URL, Path: "workflows/allow"}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "approved"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithPolicies(guard.Check),
+	)
+
+	err := the_machine.TransitionWith("approved", User{"bob", []string{"staff"}})
+	st.Expect(t, errors.Is(err, fsm.ErrDenied), true)
+
+	st.Expect(t, the_machine.TransitionWith("approved", User{"alice", []string{"admin"}}), nil)
+
+	some_thing.State = "pending"
+	st.Expect(t, errors.Is(the_machine.TransitionAs("bob", "approved", nil), fsm.ErrDenied), true)
+	st.Expect(t, the_machine.TransitionAs("operator", "approved", nil), nil)
+}
@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxMessage is an event waiting in an Outbox to be published.
+type OutboxMessage struct {
+	ID    uint64
+	Event TransitionEvent
+}
+
+// Outbox is implemented by EventStores that, when appending an event, also
+// queue it for publication in the same transaction. An event is thereby
+// published if and only if it was stored, without the two having to agree
+// across a distributed transaction; a Relay does the publishing.
+type Outbox interface {
+	// Pending returns up to limit unpublished messages, oldest first.
+	Pending(limit int) ([]OutboxMessage, error)
+
+	// MarkPublished removes messages from the outbox.
+	MarkPublished(ids ...uint64) error
+}
+
+// OutboxCounter is implemented by Outboxes that can say how many messages
+// wait to be published, as reported by Stats.
+type OutboxCounter interface {
+	Depth() (int, error)
+}
+
+// Relay publishes the messages of an Outbox. A message is only marked once
+// Publish has succeeded, so it may be published more than once if the
+// relay stops in between; consumers should expect duplicates.
+type Relay struct {
+	Outbox  Outbox
+	Publish func(ctx context.Context, e TransitionEvent) error
+	Batch   int // messages fetched at a time; defaults to 100
+}
+
+// Flush publishes pending messages until the outbox is empty or Publish
+// fails, and returns how many were published.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	batch := r.Batch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	published := 0
+	for {
+		messages, err := r.Outbox.Pending(batch)
+		if err != nil || len(messages) == 0 {
+			return published, err
+		}
+
+		for _, msg := range messages {
+			if err := ctx.Err(); err != nil {
+				return published, err
+			}
+			if err := r.Publish(ctx, msg.Event); err != nil {
+				return published, err
+			}
+			if err := r.Outbox.MarkPublished(msg.ID); err != nil {
+				return published, err
+			}
+			published++
+		}
+	}
+}
+
+// Run flushes the outbox every interval until ctx is done, and returns
+// ctx's error. Failures to publish are left to the next flush to retry;
+// onError, if not nil, is told about them.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// MemoryOutboxStore is a MemoryEventStore that is also an Outbox: every
+// event appended to it is queued for publication under the same lock.
+type MemoryOutboxStore struct {
+	MemoryEventStore
+
+	outbox []OutboxMessage
+	nextID uint64
+}
+
+func (s *MemoryOutboxStore) Append(e TransitionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+	s.nextID++
+	s.outbox = append(s.outbox, OutboxMessage{ID: s.nextID, Event: e})
+	return nil
+}
+
+func (s *MemoryOutboxStore) Pending(limit int) ([]OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := min(limit, len(s.outbox))
+	return append([]OutboxMessage(nil), s.outbox[:n]...), nil
+}
+
+func (s *MemoryOutboxStore) Depth() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.outbox), nil
+}
+
+func (s *MemoryOutboxStore) MarkPublished(ids ...uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	published := map[uint64]bool{}
+	for _, id := range ids {
+		published[id] = true
+	}
+
+	kept := s.outbox[:0]
+	for _, msg := range s.outbox {
+		if !published[msg.ID] {
+			kept = append(kept, msg)
+		}
+	}
+	s.outbox = kept
+	return nil
+}
@@ -0,0 +1,57 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRelay(t *testing.T) {
+	store := &fsm.MemoryOutboxStore{}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"started", "finished"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 0),
+	)
+
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("finished"), nil)
+	st.Expect(t, the_machine.Stats().Outbox, 2)
+
+	var published []fsm.State
+	broker := errors.New("broker unavailable")
+	relay := &fsm.Relay{
+		Outbox: store,
+		Batch:  1,
+		Publish: func(ctx context.Context, e fsm.TransitionEvent) error {
+			if e.Goal == "finished" && broker != nil {
+				return broker
+			}
+			published = append(published, e.Goal)
+			return nil
+		},
+	}
+
+	n, err := relay.Flush(context.Background())
+	st.Expect(t, n, 1)
+	st.Expect(t, err, broker)
+
+	broker = nil
+	n, err = relay.Flush(context.Background())
+	st.Expect(t, n, 1)
+	st.Expect(t, err, nil)
+	st.Expect(t, published, []fsm.State{"started", "finished"})
+	st.Expect(t, the_machine.Stats().Outbox, 0)
+
+	pending, _ := store.Pending(10)
+	st.Expect(t, len(pending), 0)
+	events := 0
+	for range store.Events(0) {
+		events++
+	}
+	st.Expect(t, events, 2)
+}
@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// PermittedParallel is like Permitted but evaluates the guards concurrently
+// on at most workers goroutines. Once a guard rejects the transition, or ctx
+// is done, no further guards are started. Guards that have already started
+// are allowed to finish, and PermittedParallel does not return until they
+// have, so no goroutine outlives the call; a plain Guard can't be told to
+// stop, so use GuardsParallel for guards that might take a long time.
+//
+// It returns nil when the transition is permitted and ErrInvalidTransition
+// when it isn't. If ctx ends before every guard has passed and none has
+// rejected, ctx.Err() is returned; a rejection always takes precedence.
+func (r Ruleset) PermittedParallel(ctx context.Context, subject Stater, goal State, workers int) error {
+	guards, ok := r[T{subject.CurrentState(), goal}]
+	if !ok {
+		return ErrInvalidTransition // No rule found for the transition
+	}
+
+	adapted := make([]GuardCtx, len(guards))
+	for i, g := range guards {
+		adapted[i] = GuardWithCtx(g)
+	}
+	return GuardsParallel(ctx, subject, goal, workers, adapted...)
+}
+
+// GuardsParallel evaluates guards concurrently on at most workers
+// goroutines, like PermittedParallel. The guards are handed a context that
+// is cancelled as soon as the outcome is known, so that those still running
+// can give up, and GuardsParallel waits for them to do so before returning.
+//
+// It returns nil when every guard passed, and otherwise a *GuardError, or
+// ctx.Err() as PermittedParallel does. When several guards fail, the one
+// earliest in guards is reported, whichever finished first; a guard that
+// merely gave up because it was cancelled doesn't count as failing.
+func GuardsParallel(ctx context.Context, subject Stater, goal State, workers int, guards ...GuardCtx) error {
+	if len(guards) == 0 {
+		return nil
+	}
+	workers = min(max(workers, 1), len(guards))
+	origin := subject.CurrentState()
+
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	next.Store(-1)
+	// Each guard has a slot of its own, so the workers never contend.
+	errs := make([]error, len(guards))
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for stop.Err() == nil {
+				i := int(next.Add(1))
+				if i >= len(guards) {
+					return
+				}
+
+				if errs[i] = guards[i](stop, subject, goal); errs[i] != nil {
+					cancel() // tells the guards still running that they lost
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	first, stopped := -1, stop.Err()
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, stopped) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// only guards that returned the cancellation error of their own accord
+		for i, err := range errs {
+			if err != nil {
+				first = i
+				break
+			}
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	return rejection(ctx, &GuardError{From: origin, To: goal, Index: first, Err: errs[first]})
+}
+
+// rejection explains the failure of a guard run with ctx. Plain guards
+// adapted by PermittedParallel are reported as ErrInvalidTransition, as
+// Permitted would.
+func rejection(ctx context.Context, failure *GuardError) error {
+	switch {
+	case failure.Err == ErrInvalidTransition:
+		return ErrInvalidTransition
+	case ctx.Err() != nil && failure.Err == ctx.Err():
+		return failure.Err
+	default:
+		return failure
+	}
+}
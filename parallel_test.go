@@ -0,0 +1,111 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestPermittedParallel(t *testing.T) {
+	var running, peak, calls int64
+
+	guard := func(ok bool) fsm.Guard {
+		return func(subject fsm.Stater, goal fsm.State) bool {
+			atomic.AddInt64(&calls, 1)
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return ok
+		}
+	}
+
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	for i := 0; i < 10; i++ {
+		rules.AddRule(fsm.T{"pending", "started"}, guard(true))
+	}
+
+	ctx := context.Background()
+	some_thing := &Thing{State: "pending"}
+
+	st.Expect(t, rules.PermittedParallel(ctx, some_thing, "finished", 4), fsm.ErrInvalidTransition)
+	st.Expect(t, rules.PermittedParallel(ctx, some_thing, "started", 4), nil)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(10))
+	st.Expect(t, atomic.LoadInt64(&peak) <= 4, true)
+	st.Expect(t, atomic.LoadInt64(&running), int64(0))
+
+	// a rejection stops the remaining guards from being started
+	rules = fsm.CreateRuleset(fsm.T{"pending", "started"})
+	rules.AddRule(fsm.T{"pending", "started"}, guard(false))
+	for i := 0; i < 10; i++ {
+		rules.AddRule(fsm.T{"pending", "started"}, guard(true))
+	}
+
+	atomic.StoreInt64(&calls, 0)
+	st.Expect(t, rules.PermittedParallel(ctx, some_thing, "started", 1), fsm.ErrInvalidTransition)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(1))
+
+	// a context that is already done doesn't start anything
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	atomic.StoreInt64(&calls, 0)
+	st.Expect(t, rules.PermittedParallel(cancelled, some_thing, "started", 4), context.Canceled)
+	st.Expect(t, atomic.LoadInt64(&calls), int64(0))
+}
+
+func TestGuardsParallelCancelsLosers(t *testing.T) {
+	var cancelled atomic.Int64
+	slow := func(ctx context.Context, subject fsm.Stater, goal fsm.State) error {
+		<-ctx.Done()
+		cancelled.Add(1)
+		return ctx.Err()
+	}
+	rejecting := func(ctx context.Context, subject fsm.Stater, goal fsm.State) error {
+		time.Sleep(time.Millisecond)
+		return errOutOfStock
+	}
+
+	some_thing := &Thing{State: "pending"}
+	err := fsm.GuardsParallel(context.Background(), some_thing, "started", 3, slow, slow, rejecting)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, errors.Is(err, errOutOfStock), true)
+
+	// the losing guards were told to stop, and had by the time it returned
+	st.Expect(t, cancelled.Load(), int64(2))
+
+	// the earliest failing guard is reported, not the first to finish
+	errLate := errors.New("late")
+	late := func(ctx context.Context, subject fsm.Stater, goal fsm.State) error {
+		time.Sleep(5 * time.Millisecond)
+		return errLate
+	}
+	var guardErr *fsm.GuardError
+	err = fsm.GuardsParallel(context.Background(), some_thing, "started", 2, late, rejecting)
+	st.Expect(t, errors.As(err, &guardErr), true)
+	st.Expect(t, guardErr.Index, 0)
+	st.Expect(t, errors.Is(err, errLate), true)
+
+	// plain guards can't be cancelled, so they are waited for
+	var running atomic.Int64
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	rules.AddRule(fsm.T{"pending", "started"}, func(fsm.Stater, fsm.State) bool {
+		running.Add(1)
+		defer running.Add(-1)
+		time.Sleep(5 * time.Millisecond)
+		return true
+	})
+	rules.AddRule(fsm.T{"pending", "started"}, func(fsm.Stater, fsm.State) bool { return false })
+	st.Expect(t, rules.PermittedParallel(context.Background(), some_thing, "started", 2), fsm.ErrInvalidTransition)
+	st.Expect(t, running.Load(), int64(0))
+}
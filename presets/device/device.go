@@ -0,0 +1,80 @@
+// Package device provides the lifecycle of a connected device as a ready
+// made fsm.Ruleset.
+//
+//	provisioning -> online <-> degraded
+//	                  |           |
+//	                  +-> offline <+
+//
+// Any device can be decommissioned, which is final. Devices go offline
+// when they miss their heartbeat: the machine's clock, advanced with Tick,
+// moves an online or degraded device to offline once HeartbeatTimeout
+// passes without a call to Heartbeat.
+package device
+
+import (
+	"time"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+const (
+	Provisioning   fsm.State = "provisioning"
+	Online         fsm.State = "online"
+	Degraded       fsm.State = "degraded"
+	Offline        fsm.State = "offline"
+	Decommissioned fsm.State = "decommissioned"
+)
+
+// Guards are the points where an application decides whether a device may
+// move on. Nil guards always pass.
+type Guards struct {
+	Activate     fsm.Guard // provisioning -> online
+	Degrade      fsm.Guard // online -> degraded
+	Recover      fsm.Guard // degraded -> online
+	Decommission fsm.Guard // any -> decommissioned
+}
+
+// Rules returns the device lifecycle protected by g.
+func Rules(g Guards) (fsm.Ruleset, error) {
+	b := fsm.NewBuilder()
+
+	step := func(t fsm.Transition, name string, guard fsm.Guard) {
+		if guard == nil {
+			b.Transition(t)
+			return
+		}
+		b.Guard(name, guard).Transition(t, name)
+	}
+
+	step(fsm.T{O: Provisioning, E: Online}, "activate", g.Activate)
+	step(fsm.T{O: Online, E: Degraded}, "degrade", g.Degrade)
+	step(fsm.T{O: Degraded, E: Online}, "recover", g.Recover)
+	step(fsm.FromAnyOf(Provisioning, Online, Degraded, Offline).To(Decommissioned), "decommission", g.Decommission)
+
+	// heartbeats
+	b.Transition(fsm.T{O: Online, E: Online})
+	b.Transition(fsm.T{O: Degraded, E: Degraded})
+	b.Transition(fsm.T{O: Offline, E: Online})
+
+	// missed heartbeats
+	b.Transition(fsm.FromAnyOf(Online, Degraded).To(Offline))
+
+	return b.Final(Decommissioned).Build()
+}
+
+// Liveness is intended to be passed to fsm.New alongside Rules. It arms the
+// missed-heartbeat timer.
+func Liveness(heartbeatTimeout time.Duration) func(*fsm.Machine) {
+	return fsm.After(heartbeatTimeout, fsm.FromAnyOf(Online, Degraded).To(Offline))
+}
+
+// Heartbeat records that the device was heard from, which restarts its
+// missed-heartbeat timer and brings an offline device back online.
+func Heartbeat(m fsm.Machine) error {
+	switch current := m.Subject.CurrentState(); current {
+	case Offline:
+		return m.Transition(Online)
+	default:
+		return m.Transition(current)
+	}
+}
@@ -0,0 +1,56 @@
+package device_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/presets/device"
+)
+
+type Device struct {
+	State fsm.State
+}
+
+func (d *Device) CurrentState() fsm.State { return d.State }
+func (d *Device) SetState(s fsm.State)    { d.State = s }
+
+func TestDeviceFleetLiveness(t *testing.T) {
+	rules, err := device.Rules(device.Guards{})
+	st.Expect(t, err, nil)
+
+	fleet := fsm.NewRegistry()
+	devices := make([]*Device, 1000)
+	for i := range devices {
+		devices[i] = &Device{State: device.Provisioning}
+		fleet.Put(fmt.Sprint(i), fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(devices[i]),
+			device.Liveness(30*time.Second),
+		))
+		st.Expect(t, fleet.Transition(fmt.Sprint(i), device.Online), nil)
+	}
+
+	st.Expect(t, fleet.Tick(20*time.Second), nil)
+
+	// only the even devices check in
+	for i := 0; i < len(devices); i += 2 {
+		m, _ := fleet.Get(fmt.Sprint(i))
+		st.Expect(t, device.Heartbeat(m), nil)
+	}
+
+	st.Expect(t, fleet.Tick(20*time.Second), nil)
+
+	stats := fleet.Stats()
+	st.Expect(t, stats.States[device.Online], 500)
+	st.Expect(t, stats.States[device.Offline], 500)
+
+	m, _ := fleet.Get("1")
+	st.Expect(t, device.Heartbeat(m), nil)
+	st.Expect(t, devices[1].State, device.Online)
+
+	st.Expect(t, fleet.Transition("1", device.Decommissioned), nil)
+	st.Expect(t, device.Heartbeat(m), fsm.ErrInvalidTransition)
+}
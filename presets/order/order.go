@@ -0,0 +1,54 @@
+// Package order provides the lifecycle of an e-commerce order as a ready
+// made fsm.Ruleset. It can be used as is, or as a starting point for a
+// workflow of your own.
+//
+//	cart -> pending -> paid -> fulfilled -> delivered
+//
+// An order can be cancelled until it has been fulfilled, and refunded once
+// it has been paid for. Cancelled and refunded are final.
+package order
+
+import "github.com/ryanfaerman/fsm/v3"
+
+const (
+	Cart      fsm.State = "cart"
+	Pending   fsm.State = "pending"
+	Paid      fsm.State = "paid"
+	Fulfilled fsm.State = "fulfilled"
+	Delivered fsm.State = "delivered"
+	Cancelled fsm.State = "cancelled"
+	Refunded  fsm.State = "refunded"
+)
+
+// Guards are the points where an application decides whether an order may
+// move on, e.g. whether the payment went through. Nil guards always pass.
+type Guards struct {
+	Checkout fsm.Guard // cart -> pending
+	Pay      fsm.Guard // pending -> paid
+	Fulfill  fsm.Guard // paid -> fulfilled
+	Deliver  fsm.Guard // fulfilled -> delivered
+	Cancel   fsm.Guard // cart, pending, paid -> cancelled
+	Refund   fsm.Guard // paid, fulfilled, delivered -> refunded
+}
+
+// Rules returns the order lifecycle protected by g.
+func Rules(g Guards) (fsm.Ruleset, error) {
+	b := fsm.NewBuilder()
+
+	step := func(t fsm.Transition, name string, guard fsm.Guard) {
+		if guard == nil {
+			b.Transition(t)
+			return
+		}
+		b.Guard(name, guard).Transition(t, name)
+	}
+
+	step(fsm.T{O: Cart, E: Pending}, "checkout", g.Checkout)
+	step(fsm.T{O: Pending, E: Paid}, "pay", g.Pay)
+	step(fsm.T{O: Paid, E: Fulfilled}, "fulfill", g.Fulfill)
+	step(fsm.T{O: Fulfilled, E: Delivered}, "deliver", g.Deliver)
+	step(fsm.FromAnyOf(Cart, Pending, Paid).To(Cancelled), "cancel", g.Cancel)
+	step(fsm.FromAnyOf(Paid, Fulfilled, Delivered).To(Refunded), "refund", g.Refund)
+
+	return b.Final(Cancelled, Refunded).Build()
+}
@@ -0,0 +1,64 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/presets/order"
+)
+
+type Order struct {
+	State fsm.State
+	Paid  bool
+}
+
+func (o *Order) CurrentState() fsm.State { return o.State }
+func (o *Order) SetState(s fsm.State)    { o.State = s }
+
+func TestOrderLifecycle(t *testing.T) {
+	rules, err := order.Rules(order.Guards{
+		Pay: func(subject fsm.Stater, goal fsm.State) bool {
+			return subject.(*Order).Paid
+		},
+	})
+	st.Expect(t, err, nil)
+
+	some_order := Order{State: order.Cart}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_order))
+
+	st.Expect(t, the_machine.Transition(order.Pending), nil)
+	st.Expect(t, the_machine.Transition(order.Paid), fsm.ErrInvalidTransition)
+
+	some_order.Paid = true
+	for _, goal := range []fsm.State{order.Paid, order.Fulfilled, order.Delivered, order.Refunded} {
+		st.Expect(t, the_machine.Transition(goal), nil)
+		st.Expect(t, some_order.State, goal)
+	}
+
+	// refunded is final
+	for _, goal := range []fsm.State{order.Cart, order.Pending, order.Paid, order.Cancelled} {
+		st.Expect(t, the_machine.Transition(goal), fsm.ErrInvalidTransition)
+	}
+}
+
+func TestOrderCancellation(t *testing.T) {
+	rules, err := order.Rules(order.Guards{})
+	st.Expect(t, err, nil)
+
+	examples := []struct {
+		state   fsm.State
+		outcome bool
+	}{
+		{order.Cart, true},
+		{order.Pending, true},
+		{order.Paid, true},
+		{order.Fulfilled, false},
+		{order.Delivered, false},
+		{order.Refunded, false},
+	}
+
+	for i, ex := range examples {
+		st.Expect(t, rules.Permitted(&Order{State: ex.state}, order.Cancelled), ex.outcome, i)
+	}
+}
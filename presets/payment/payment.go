@@ -0,0 +1,105 @@
+// Package payment provides the lifecycle of a card payment as a ready made
+// fsm.Ruleset.
+//
+//	authorized -> captured -> settled
+//
+// An authorization can be voided, and expires if it isn't captured in time.
+// A settled payment can be refunded or disputed; a dispute is either won,
+// returning the payment to settled, or lost to a chargeback. Voided,
+// expired, refunded and charged back are final.
+package payment
+
+import (
+	"time"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+const (
+	Authorized  fsm.State = "authorized"
+	Captured    fsm.State = "captured"
+	Settled     fsm.State = "settled"
+	Voided      fsm.State = "voided"
+	Expired     fsm.State = "expired"
+	Refunded    fsm.State = "refunded"
+	Disputed    fsm.State = "disputed"
+	ChargedBack fsm.State = "charged_back"
+)
+
+// DefaultAuthorizationTTL is how long an authorization can be captured for
+// unless Config says otherwise.
+const DefaultAuthorizationTTL = 7 * 24 * time.Hour
+
+// Authorization is implemented by subjects that know when they were
+// authorized. Subjects that don't implement it never expire.
+type Authorization interface {
+	AuthorizedAt() time.Time
+}
+
+// Guards are the points where an application decides whether a payment may
+// move on. Nil guards always pass.
+type Guards struct {
+	Capture     fsm.Guard // authorized -> captured
+	Void        fsm.Guard // authorized -> voided
+	Settle      fsm.Guard // captured -> settled
+	Refund      fsm.Guard // settled -> refunded
+	Dispute     fsm.Guard // settled -> disputed
+	WinDispute  fsm.Guard // disputed -> settled
+	LoseDispute fsm.Guard // disputed -> charged_back
+}
+
+// Config customizes the payment lifecycle.
+type Config struct {
+	Guards
+
+	// AuthorizationTTL is how long after AuthorizedAt a payment may still be
+	// captured. Once it has passed, capturing is refused and the payment may
+	// move to expired instead.
+	AuthorizationTTL time.Duration
+
+	// Now tells the time; it defaults to time.Now.
+	Now func() time.Time
+}
+
+// Rules returns the payment lifecycle configured by c. Nothing moves a
+// payment to expired by itself; the application does so, typically from a
+// periodic sweep, once the transition is permitted.
+func Rules(c Config) (fsm.Ruleset, error) {
+	if c.AuthorizationTTL == 0 {
+		c.AuthorizationTTL = DefaultAuthorizationTTL
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	expired := func(subject fsm.Stater, goal fsm.State) bool {
+		a, ok := subject.(Authorization)
+		return ok && c.Now().Sub(a.AuthorizedAt()) >= c.AuthorizationTTL
+	}
+	unexpired := func(subject fsm.Stater, goal fsm.State) bool {
+		return !expired(subject, goal)
+	}
+
+	b := fsm.NewBuilder().
+		Guard("expired", expired).
+		Guard("unexpired", unexpired)
+
+	step := func(t fsm.Transition, name string, guard fsm.Guard, names ...string) {
+		if guard != nil {
+			b.Guard(name, guard)
+			names = append(names, name)
+		}
+		b.Transition(t, names...)
+	}
+
+	step(fsm.T{O: Authorized, E: Captured}, "capture", c.Capture, "unexpired")
+	step(fsm.T{O: Authorized, E: Voided}, "void", c.Void)
+	b.Transition(fsm.T{O: Authorized, E: Expired}, "expired")
+	step(fsm.T{O: Captured, E: Settled}, "settle", c.Settle)
+	step(fsm.T{O: Settled, E: Refunded}, "refund", c.Refund)
+	step(fsm.T{O: Settled, E: Disputed}, "dispute", c.Dispute)
+	step(fsm.T{O: Disputed, E: Settled}, "win-dispute", c.WinDispute)
+	step(fsm.T{O: Disputed, E: ChargedBack}, "lose-dispute", c.LoseDispute)
+
+	return b.Final(Voided, Expired, Refunded, ChargedBack).Build()
+}
@@ -0,0 +1,58 @@
+package payment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+	"github.com/ryanfaerman/fsm/v3/presets/payment"
+)
+
+type Payment struct {
+	State      fsm.State
+	Authorized time.Time
+}
+
+func (p *Payment) CurrentState() fsm.State { return p.State }
+func (p *Payment) SetState(s fsm.State)    { p.State = s }
+func (p *Payment) AuthorizedAt() time.Time { return p.Authorized }
+
+func TestPaymentLifecycle(t *testing.T) {
+	rules, err := payment.Rules(payment.Config{})
+	st.Expect(t, err, nil)
+
+	some_payment := Payment{State: payment.Authorized, Authorized: time.Now()}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_payment))
+
+	steps := []fsm.State{
+		payment.Captured,
+		payment.Settled,
+		payment.Disputed,
+		payment.Settled,
+		payment.Disputed,
+		payment.ChargedBack,
+	}
+	for i, goal := range steps {
+		st.Expect(t, the_machine.Transition(goal), nil, i)
+	}
+
+	st.Expect(t, the_machine.Transition(payment.Settled), fsm.ErrInvalidTransition)
+}
+
+func TestPaymentAuthorizationExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rules, err := payment.Rules(payment.Config{
+		AuthorizationTTL: time.Hour,
+		Now:              func() time.Time { return now },
+	})
+	st.Expect(t, err, nil)
+
+	fresh := &Payment{State: payment.Authorized, Authorized: now.Add(-time.Minute)}
+	stale := &Payment{State: payment.Authorized, Authorized: now.Add(-2 * time.Hour)}
+
+	st.Expect(t, rules.Permitted(fresh, payment.Captured), true)
+	st.Expect(t, rules.Permitted(fresh, payment.Expired), false)
+	st.Expect(t, rules.Permitted(stale, payment.Captured), false)
+	st.Expect(t, rules.Permitted(stale, payment.Expired), true)
+}
@@ -0,0 +1,71 @@
+package fsm
+
+import "strings"
+
+// PairSeparator joins the component states of a Product state.
+const PairSeparator = "|"
+
+// Pair names the Product state made of a and b, which must not contain
+// PairSeparator themselves.
+func Pair(a, b State) State {
+	return a + PairSeparator + b
+}
+
+// Split returns the component states of a Product state.
+func Split(s State) (a, b State) {
+	x, y, _ := strings.Cut(string(s), PairSeparator)
+	return State(x), State(y)
+}
+
+// Product returns the synchronous product of a and b: its states are the
+// Pairs of their states, and it moves from Pair(a1, b1) to Pair(a2, b2)
+// when a can move from a1 to a2 and b from b1 to b2 at the same time. A
+// component that may also keep still needs a self-transition for it.
+//
+// The guards of each component see a subject whose CurrentState is its
+// own half of the pair, and which ignores SetState.
+func Product(a, b Ruleset) Ruleset {
+	p := NewRuleset(len(a) * len(b))
+	for ta, ga := range a {
+		for tb, gb := range b {
+			t := T{Pair(ta.Origin(), tb.Origin()), Pair(ta.Exit(), tb.Exit())}
+			p.AddTransition(t)
+			if len(ga) > 0 || len(gb) > 0 {
+				p.AddRule(t, productGuard(ga, gb, ta.Exit(), tb.Exit()))
+			}
+		}
+	}
+	return p
+}
+
+func productGuard(ga, gb []Guard, goalA, goalB State) Guard {
+	return func(subject Stater, goal State) bool {
+		for _, g := range ga {
+			if !g(component{subject, 0}, goalA) {
+				return false
+			}
+		}
+		for _, g := range gb {
+			if !g(component{subject, 1}, goalB) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// component is one half of the state of a subject of a Product.
+type component struct {
+	Stater
+	half int
+}
+
+func (c component) CurrentState() State {
+	a, b := Split(c.Stater.CurrentState())
+	if c.half == 0 {
+		return a
+	}
+	return b
+}
+
+func (c component) SetState(State) {}
@@ -0,0 +1,46 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestProduct(t *testing.T) {
+	notShipped := func(subject fsm.Stater, goal fsm.State) bool {
+		return subject.CurrentState() != "shipped"
+	}
+
+	payment := fsm.CreateRuleset(
+		fsm.T{"unpaid", "paid"},
+		fsm.T{"paid", "refunded"},
+		fsm.T{"unpaid", "unpaid"},
+		fsm.T{"paid", "paid"},
+	)
+	shipping := fsm.Ruleset{}
+	shipping.AddTransitions(
+		fsm.T{"packing", "shipped"},
+		fsm.T{"packing", "packing"},
+		fsm.T{"shipped", "shipped"},
+	)
+	shipping.AddRule(fsm.T{"packing", "packing"}, notShipped)
+
+	product := fsm.Product(payment, shipping)
+	st.Expect(t, len(product), len(payment)*len(shipping))
+	st.Expect(t, product.ExitsFrom(fsm.Pair("paid", "packing")), []fsm.State{
+		fsm.Pair("paid", "packing"),
+		fsm.Pair("paid", "shipped"),
+		fsm.Pair("refunded", "packing"),
+		fsm.Pair("refunded", "shipped"),
+	})
+
+	some_thing := Thing{State: fsm.Pair("unpaid", "packing")}
+	the_machine := fsm.New(fsm.WithRules(product), fsm.WithSubject(&some_thing))
+	st.Expect(t, the_machine.Transition(fsm.Pair("paid", "packing")), nil)
+	st.Expect(t, the_machine.Transition(fsm.Pair("refunded", "shipped")), nil)
+
+	a, b := fsm.Split(some_thing.State)
+	st.Expect(t, a, fsm.State("refunded"))
+	st.Expect(t, b, fsm.State("shipped"))
+}
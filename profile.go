@@ -0,0 +1,42 @@
+package fsm
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// WithProfilerLabels is intended to be passed to New to tag guard, action
+// and hook execution with pprof labels, so CPU profiles attribute time to
+// individual transitions. The labels are fsm_transition ("origin->goal"),
+// fsm_phase ("guards", "actions" or "hooks") and fsm_ruleset, which is set
+// to version, plus fsm_label_<name> for each of the machine's labels. They
+// add to any labels of the transition's context, as set by the caller of
+// TransitionCtx with pprof.WithLabels.
+func WithProfilerLabels(version string) func(*Machine) {
+	return func(m *Machine) {
+		m.profile = &profileLabels{version: version}
+	}
+}
+
+type profileLabels struct {
+	version string
+}
+
+// do runs fn, labelled for tc and phase when profiling labels are enabled.
+func (p *profileLabels) do(tc TransitionContext, phase string, fn func()) {
+	if p == nil {
+		fn()
+		return
+	}
+
+	pairs := []string{
+		"fsm_transition", string(tc.Origin) + "->" + string(tc.Goal),
+		"fsm_phase", phase,
+		"fsm_ruleset", p.version,
+	}
+	for name, value := range tc.Labels {
+		pairs = append(pairs, "fsm_label_"+name, value)
+	}
+	labels := pprof.Labels(pairs...)
+	pprof.Do(tc.Context(), labels, func(context.Context) { fn() })
+}
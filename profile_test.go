@@ -0,0 +1,30 @@
+package fsm_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestMachineWithProfilerLabels(t *testing.T) {
+	var fired, acted bool
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithHooks(func(fsm.TransitionContext) { fired = true }),
+		fsm.WithActions(fsm.T{"pending", "started"}, func(fsm.TransitionContext) error { acted = true; return nil }),
+		fsm.WithProfilerLabels("v1"),
+	)
+
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "42"))
+	st.Expect(t, the_machine.TransitionCtx(ctx, "started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+	st.Expect(t, fired, true)
+	st.Expect(t, acted, true)
+}
@@ -0,0 +1,218 @@
+package fsm
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Verdict is the outcome of checking a Property.
+type Verdict int
+
+const (
+	Pending  Verdict = iota // not yet decided
+	Holds                   // satisfied, or never violated in a finished trace
+	Violated                // broken
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Holds:
+		return "holds"
+	case Violated:
+		return "violated"
+	default:
+		return "pending"
+	}
+}
+
+// Property is a temporal assertion about the sequence of transitions a
+// machine makes, such as "eventually delivered within 30 days". Properties
+// are checked over recorded traces with Verify, or as machines run with
+// WithProperties.
+type Property struct {
+	Name string
+	new  func() monitor
+}
+
+func (p Property) String() string { return p.Name }
+
+// monitor follows one trace for a Property.
+type monitor interface {
+	// observe returns the verdict after e; once decided it doesn't change.
+	observe(e TransitionEvent) Verdict
+	// at returns the verdict at now, or at the end of a finished trace.
+	at(now time.Time) Verdict
+}
+
+// Result is the Verdict on a Property. Event is the event that decided it,
+// if one did.
+type Result struct {
+	Property string
+	Verdict  Verdict
+	Event    *TransitionEvent
+}
+
+// Eventually holds once the trace enters state. With a positive within it
+// must do so no later than within after the first event.
+func Eventually(state State, within time.Duration) Property {
+	name := fmt.Sprintf("eventually(%s)", state)
+	if within > 0 {
+		name += " within " + within.String()
+	}
+	return Property{name, func() monitor { return &eventually{state: state, within: within} }}
+}
+
+type eventually struct {
+	state  State
+	within time.Duration
+	start  time.Time
+}
+
+func (p *eventually) observe(e TransitionEvent) Verdict {
+	if p.start.IsZero() {
+		p.start = e.Time
+	}
+	if p.within > 0 && e.Time.Sub(p.start) > p.within {
+		return Violated
+	}
+	if e.Goal == p.state {
+		return Holds
+	}
+	return Pending
+}
+
+func (p *eventually) at(now time.Time) Verdict {
+	if p.within > 0 && !p.start.IsZero() && now.Sub(p.start) > p.within {
+		return Violated
+	}
+	return Pending
+}
+
+// Never is violated if the trace ever enters state.
+func Never(state State) Property {
+	name := fmt.Sprintf("never(%s)", state)
+	return Property{name, func() monitor { return &neverBefore{state: state} }}
+}
+
+// NeverBefore is violated if the trace enters state before it has entered
+// before, e.g. NeverBefore("refunded", "paid").
+func NeverBefore(state, before State) Property {
+	name := fmt.Sprintf("never(%s before %s)", state, before)
+	return Property{name, func() monitor { return &neverBefore{state: state, before: before} }}
+}
+
+type neverBefore struct {
+	state, before State
+}
+
+func (p *neverBefore) observe(e TransitionEvent) Verdict {
+	switch {
+	case p.before != "" && e.Goal == p.before:
+		return Holds
+	case e.Goal == p.state:
+		return Violated
+	default:
+		return Pending
+	}
+}
+
+func (p *neverBefore) at(now time.Time) Verdict {
+	return Holds
+}
+
+// Verify checks props over a finished trace, as of now, which for a trace
+// of a machine that is still running is usually the current time.
+func Verify(events iter.Seq[TransitionEvent], now time.Time, props ...Property) []Result {
+	s := newPropertySet(props)
+	for e := range events {
+		s.observe(e)
+	}
+	return s.results(now)
+}
+
+type propertySet struct {
+	monitors []monitor
+	verdicts []Result
+}
+
+func newPropertySet(props []Property) *propertySet {
+	s := &propertySet{}
+	for _, p := range props {
+		s.monitors = append(s.monitors, p.new())
+		s.verdicts = append(s.verdicts, Result{Property: p.Name})
+	}
+	return s
+}
+
+// observe feeds e to every undecided monitor, returning the results it
+// decided.
+func (s *propertySet) observe(e TransitionEvent) []Result {
+	var decided []Result
+	for i, m := range s.monitors {
+		if s.verdicts[i].Verdict != Pending {
+			continue
+		}
+		if v := m.observe(e); v != Pending {
+			e := e
+			s.verdicts[i].Verdict, s.verdicts[i].Event = v, &e
+			decided = append(decided, s.verdicts[i])
+		}
+	}
+	return decided
+}
+
+func (s *propertySet) results(now time.Time) []Result {
+	results := make([]Result, len(s.verdicts))
+	for i, r := range s.verdicts {
+		if r.Verdict == Pending {
+			r.Verdict = s.monitors[i].at(now)
+		}
+		results[i] = r
+	}
+	return results
+}
+
+type machineProperties struct {
+	mu     sync.Mutex
+	set    *propertySet
+	report func(Result)
+}
+
+// WithProperties is intended to be passed to New to check props as the
+// machine runs. report, which may be nil, is called as soon as a
+// transition decides one of them; Machine.Properties gives the verdicts at
+// any time.
+func WithProperties(report func(Result), props ...Property) func(*Machine) {
+	return func(m *Machine) {
+		m.properties = &machineProperties{set: newPropertySet(props), report: report}
+	}
+}
+
+func (p *machineProperties) observe(e TransitionEvent) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	decided := p.set.observe(e)
+	p.mu.Unlock()
+
+	if p.report != nil {
+		for _, r := range decided {
+			p.report(r)
+		}
+	}
+}
+
+// Properties returns the verdict on each Property given to WithProperties,
+// as of now.
+func (m Machine) Properties(now time.Time) []Result {
+	if m.properties == nil {
+		return nil
+	}
+	m.properties.mu.Lock()
+	defer m.properties.mu.Unlock()
+
+	return m.properties.set.results(now)
+}
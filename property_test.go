@@ -0,0 +1,64 @@
+package fsm_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestVerify(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(days int) time.Time { return start.Add(time.Duration(days) * day) }
+
+	trace := []fsm.TransitionEvent{
+		{Seq: 1, Origin: "cart", Goal: "placed", Time: at(0)},
+		{Seq: 2, Origin: "placed", Goal: "refunded", Time: at(1)},
+		{Seq: 3, Origin: "refunded", Goal: "paid", Time: at(2)},
+	}
+
+	results := fsm.Verify(slices.Values(trace), at(40),
+		fsm.Eventually("paid", 30*day),
+		fsm.Eventually("delivered", 30*day),
+		fsm.Eventually("delivered", 0),
+		fsm.NeverBefore("refunded", "paid"),
+		fsm.Never("lost"),
+	)
+
+	verdicts := make([]fsm.Verdict, len(results))
+	for i, r := range results {
+		verdicts[i] = r.Verdict
+	}
+	st.Expect(t, verdicts, []fsm.Verdict{fsm.Holds, fsm.Violated, fsm.Pending, fsm.Violated, fsm.Holds})
+	st.Expect(t, results[0].Event.Seq, uint64(3))
+	st.Expect(t, results[3].Property, "never(refunded before paid)")
+	st.Expect(t, results[3].Event.Seq, uint64(2))
+}
+
+func TestWithProperties(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	var reported []fsm.Result
+
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"placed", "refunded"},
+			fsm.T{"placed", "paid"},
+		)),
+		fsm.WithSubject(&Thing{State: "placed"}),
+		fsm.WithClock(fsm.ClockFunc(func() time.Time { return now })),
+		fsm.WithProperties(func(r fsm.Result) { reported = append(reported, r) },
+			fsm.NeverBefore("refunded", "paid"),
+			fsm.Eventually("paid", time.Hour),
+		),
+	)
+
+	st.Expect(t, the_machine.Transition("refunded"), nil)
+	st.Expect(t, len(reported), 1)
+	st.Expect(t, reported[0].Verdict, fsm.Violated)
+
+	st.Expect(t, the_machine.Properties(now)[1].Verdict, fsm.Pending)
+	st.Expect(t, the_machine.Properties(now.Add(2 * time.Hour))[1].Verdict, fsm.Violated)
+}
@@ -0,0 +1,133 @@
+package fsm
+
+import (
+	"strings"
+	"sync"
+)
+
+// History returns the shallow history pseudo-state of the composite state
+// parent, for use as a goal. A machine created with a NestedRuleset as its
+// Rules resumes, when moved there, the child of parent it was last in, or
+// parent itself if it has never been within it:
+//
+//	rules.AddTransition(fsm.T{"paused", fsm.History("processing")})
+//	the_machine.Transition(fsm.History("processing"))
+//
+// The Rules are asked about the pseudo-state itself, so transitions into
+// it need a rule of their own; everything after that, from events to
+// hooks, sees the state resumed.
+//
+// Pseudo-states begin with a NUL byte, which no state name may, so they
+// can't be mistaken for a state that happens to be called "H(processing)".
+func History(parent State) State {
+	return historyPrefix + parent + ")"
+}
+
+// DeepHistory is like History but resumes the innermost state the machine
+// was last in within parent, however deeply it is nested.
+func DeepHistory(parent State) State {
+	return deepHistoryPrefix + parent + ")"
+}
+
+const (
+	historyPrefix     = "\x00H("
+	deepHistoryPrefix = "\x00H*("
+)
+
+// reserved reports whether s is a name kept for pseudo-states that isn't
+// one of them.
+func reserved(s State) bool {
+	_, _, ok := pseudo(s)
+	return strings.HasPrefix(string(s), "\x00") && !ok
+}
+
+// pseudo parses a pseudo-state made by History or DeepHistory.
+func pseudo(s State) (parent State, deep, ok bool) {
+	if !strings.HasSuffix(string(s), ")") {
+		return "", false, false
+	}
+	switch {
+	case strings.HasPrefix(string(s), deepHistoryPrefix):
+		return s[len(deepHistoryPrefix) : len(s)-1], true, true
+	case strings.HasPrefix(string(s), historyPrefix):
+		return s[len(historyPrefix) : len(s)-1], false, true
+	}
+	return "", false, false
+}
+
+// recall remembers where a machine last was within each composite state
+// of a Hierarchy. A nil *recall remembers nothing, so history pseudo-states
+// resolve to their composite state.
+type recall struct {
+	hierarchy Hierarchy
+
+	mu      sync.Mutex
+	shallow map[State]State // parent -> child last active
+	deep    map[State]State // parent -> innermost state last active
+}
+
+func newRecall(h Hierarchy) *recall {
+	return &recall{hierarchy: h, shallow: map[State]State{}, deep: map[State]State{}}
+}
+
+// observe notes that the subject entered s.
+func (r *recall) observe(s State) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	child := s
+	for _, parent := range r.hierarchy.Ancestors(s) {
+		r.shallow[parent] = child
+		r.deep[parent] = s
+		child = parent
+	}
+}
+
+// resolve returns the state the goal stands for: goal itself, or the one a
+// history pseudo-state resumes.
+func (r *recall) resolve(goal State) State {
+	parent, deep, ok := pseudo(goal)
+	if !ok {
+		return goal
+	}
+	if r == nil {
+		return parent
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := r.shallow
+	if deep {
+		last = r.deep
+	}
+	if s, ok := last[parent]; ok {
+		return s
+	}
+	return parent
+}
+
+// resolveAfter is like resolve, as if the subject had since entered each of
+// visited in turn.
+func (r *recall) resolveAfter(goal State, visited []State) State {
+	parent, deep, ok := pseudo(goal)
+	if !ok || r == nil {
+		return r.resolve(goal)
+	}
+	for i := len(visited) - 1; i >= 0; i-- {
+		s := visited[i]
+		if s == parent || !r.hierarchy.In(s, parent) {
+			continue
+		}
+		if deep {
+			return s
+		}
+		for r.hierarchy[s] != parent {
+			s = r.hierarchy[s]
+		}
+		return s
+	}
+	return r.resolve(goal)
+}
@@ -0,0 +1,86 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestHistoryPseudoStates(t *testing.T) {
+	h := fsm.Hierarchy{}
+	h.Nest("processing", "picking", "packing")
+	h.Nest("packing", "boxing", "labelling")
+
+	rules := fsm.CreateRuleset(
+		fsm.T{"picking", "boxing"},
+		fsm.T{"boxing", "labelling"},
+		fsm.T{"processing", "paused"},
+		fsm.T{"paused", fsm.History("processing")},
+		fsm.T{"paused", fsm.DeepHistory("processing")},
+	)
+
+	for i, ex := range []struct {
+		goal fsm.State
+		want fsm.State
+	}{
+		{fsm.History("processing"), "packing"},
+		{fsm.DeepHistory("processing"), "labelling"},
+	} {
+		some_thing := Thing{State: "picking"}
+		the_machine := fsm.New(fsm.WithPermitter(h.Rules(rules)), fsm.WithSubject(&some_thing), fsm.WithHistory())
+
+		st.Expect(t, the_machine.Transition("boxing"), nil, i)
+		st.Expect(t, the_machine.Transition("labelling"), nil, i)
+		st.Expect(t, the_machine.Transition("paused"), nil, i)
+		st.Expect(t, the_machine.Transition(ex.goal), nil, i)
+		st.Expect(t, some_thing.State, ex.want, i)
+
+		history := the_machine.History()
+		st.Expect(t, history[len(history)-1].Goal, ex.want, i)
+	}
+
+	// Without having been within the composite state, it is entered itself.
+	some_thing := Thing{State: "paused"}
+	the_machine := fsm.New(fsm.WithPermitter(h.Rules(rules)), fsm.WithSubject(&some_thing))
+	st.Expect(t, the_machine.Transition(fsm.DeepHistory("processing")), nil)
+	st.Expect(t, some_thing.State, fsm.State("processing"))
+
+	// The pseudo-state needs a rule of its own.
+	some_thing.State = "picking"
+	st.Expect(t, the_machine.Transition(fsm.History("packing")), fsm.ErrInvalidTransition)
+}
+
+func TestHistoryOnEveryPath(t *testing.T) {
+	h := fsm.Hierarchy{}
+	h.Nest("processing", "picking", "packing")
+
+	rules := fsm.CreateRuleset(
+		fsm.T{"picking", "packing"},
+		fsm.T{"processing", "paused"},
+		fsm.T{"paused", fsm.History("processing")},
+	)
+
+	some_thing := Thing{State: "picking"}
+	the_machine := fsm.New(fsm.WithPermitter(h.Rules(rules)), fsm.WithSubject(&some_thing), fsm.WithHistory())
+
+	st.Expect(t, the_machine.TransitionThrough("packing", "paused", fsm.History("processing")), nil)
+	st.Expect(t, some_thing.State, fsm.State("packing"))
+
+	st.Expect(t, the_machine.ForceTransition("paused", "maintenance", "ops"), nil)
+	st.Expect(t, the_machine.ForceTransition(fsm.History("processing"), "maintenance", "ops"), nil)
+	st.Expect(t, some_thing.State, fsm.State("packing"))
+	for _, e := range the_machine.History() {
+		st.Expect(t, e.Goal != fsm.History("processing"), true)
+	}
+
+	// A state that merely looks like a pseudo-state is a state like any other.
+	some_thing.State = "paused"
+	plain := fsm.New(fsm.WithPermitter(h.Rules(fsm.CreateRuleset(fsm.T{"paused", "H(processing)"}))), fsm.WithSubject(&some_thing))
+	st.Expect(t, plain.Transition("H(processing)"), nil)
+	st.Expect(t, some_thing.State, fsm.State("H(processing)"))
+
+	_, err := fsm.CreateRulesetE(fsm.T{"paused", "\x00processing"})
+	st.Expect(t, errors.Is(err, fsm.ErrReservedState), true)
+}
@@ -0,0 +1,86 @@
+package fsm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redacted replaces values removed by RedactFields.
+const Redacted = "[REDACTED]"
+
+// Redactor returns a copy of payload with anything sensitive removed. It
+// must not modify payload itself, which hooks still receive intact.
+type Redactor func(payload interface{}) interface{}
+
+// WithRedaction is intended to be passed to New to apply redactors, in
+// order, to payloads before they are recorded in the machine's EventStore
+// or History.
+func WithRedaction(redactors ...Redactor) func(*Machine) {
+	return func(m *Machine) {
+		m.redactors = append(m.redactors, redactors...)
+	}
+}
+
+// Redact applies the machine's redactors to payload. Hooks that publish
+// transitions elsewhere, such as to a message bus, can use it to redact
+// what they send the same way.
+func (m Machine) Redact(payload interface{}) interface{} {
+	if payload == nil {
+		return nil
+	}
+	for _, r := range m.redactors {
+		payload = r(payload)
+	}
+	return payload
+}
+
+// RedactFields creates a Redactor that replaces the values at the given
+// paths with Redacted. Paths name JSON fields separated by dots, such as
+// "card.number", and "*" matches every field of an object or element of an
+// array, as in "items.*.serial". Payloads are converted to their JSON form
+// first, so the result is made of maps, slices, and plain values. A payload
+// that can't be encoded is replaced by Redacted as a whole.
+func RedactFields(paths ...string) Redactor {
+	split := make([][]string, len(paths))
+	for i, p := range paths {
+		split[i] = strings.Split(p, ".")
+	}
+
+	return func(payload interface{}) interface{} {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return Redacted
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return Redacted
+		}
+
+		for _, path := range split {
+			v = redactPath(v, path)
+		}
+		return v
+	}
+}
+
+func redactPath(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return Redacted
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if path[0] == "*" || path[0] == k {
+				v[k] = redactPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range v {
+				v[i] = redactPath(child, path[1:])
+			}
+		}
+	}
+	return v
+}
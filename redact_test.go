@@ -0,0 +1,55 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type checkout struct {
+	Customer string `json:"customer"`
+	Card     struct {
+		Number string `json:"number"`
+		Brand  string `json:"brand"`
+	} `json:"card"`
+	Items []map[string]string `json:"items"`
+}
+
+func TestRedaction(t *testing.T) {
+	payload := checkout{Customer: "c-42"}
+	payload.Card.Number = "4242424242424242"
+	payload.Card.Brand = "visa"
+	payload.Items = []map[string]string{{"sku": "a", "serial": "s1"}, {"sku": "b", "serial": "s2"}}
+
+	var hooked interface{}
+	store := &fsm.MemoryEventStore{}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"cart", "paid"})),
+		fsm.WithSubject(&Thing{State: "cart"}),
+		fsm.WithEventStore(store, 0),
+		fsm.WithHistory(),
+		fsm.WithRedaction(fsm.RedactFields("card.number", "items.*.serial", "missing.field")),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { hooked = tc.Payload }),
+	)
+	st.Expect(t, the_machine.TransitionWith("paid", payload), nil)
+
+	want := map[string]interface{}{
+		"customer": "c-42",
+		"card":     map[string]interface{}{"number": fsm.Redacted, "brand": "visa"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "serial": fsm.Redacted},
+			map[string]interface{}{"sku": "b", "serial": fsm.Redacted},
+		},
+	}
+	for e := range store.Events(0) {
+		st.Expect(t, e.Payload, want)
+	}
+	st.Expect(t, the_machine.History()[0].Payload, want)
+	st.Expect(t, hooked, payload)
+}
+
+func TestRedactUnencodable(t *testing.T) {
+	redact := fsm.RedactFields("card.number")
+	st.Expect(t, redact(map[string]interface{}{"callback": func() {}}), interface{}(fsm.Redacted))
+}
@@ -0,0 +1,191 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrUnknownMachine = errors.New("unknown machine")
+
+// Registry keeps Machines by key. The keys are spread across shards, each
+// with its own lock, so calls for unrelated machines rarely contend.
+// Transitions of a single machine are serialized.
+type Registry struct {
+	shards     []registryShard
+	authorizer Authorizer
+}
+
+type registryShard struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	sorted  []string // the keys of entries, in order, as of the last List
+	added   []string // keys put since sorted was last brought up to date
+	stale   bool     // whether sorted is out of date
+}
+
+type registryEntry struct {
+	mu      sync.Mutex
+	machine Machine
+}
+
+// DefaultShards is the number of shards a Registry uses unless WithShards
+// says otherwise.
+const DefaultShards = 64
+
+// NewRegistry initializes a registry
+func NewRegistry(opts ...func(*Registry)) *Registry {
+	r := &Registry{}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if len(r.shards) == 0 {
+		WithShards(DefaultShards)(r)
+	}
+
+	return r
+}
+
+// WithShards is intended to be passed to NewRegistry to set the number of
+// shards.
+func WithShards(n int) func(*Registry) {
+	return func(r *Registry) {
+		if n < 1 {
+			n = 1
+		}
+		r.shards = make([]registryShard, n)
+		for i := range r.shards {
+			r.shards[i].entries = map[string]*registryEntry{}
+		}
+	}
+}
+
+func (r *Registry) shard(key string) *registryShard {
+	// FNV-1a, inlined so hashing the key doesn't allocate
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &r.shards[h%uint32(len(r.shards))]
+}
+
+func (r *Registry) entry(key string) (*registryEntry, bool) {
+	s := r.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[key]
+	return e, ok
+}
+
+// Put stores m under key, replacing any machine already there.
+func (r *Registry) Put(key string, m Machine) {
+	s := r.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(key, &registryEntry{machine: m})
+}
+
+// Get returns the machine stored under key. The machine isn't locked once
+// Get returns: use Do or Range to read it while transitions may be under
+// way.
+func (r *Registry) Get(key string) (Machine, bool) {
+	e, ok := r.entry(key)
+	if !ok {
+		return Machine{}, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.machine, true
+}
+
+// Delete removes the machine stored under key.
+func (r *Registry) Delete(key string) {
+	s := r.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+}
+
+// Transition attempts to move the machine stored under key to the goal
+// state.
+func (r *Registry) Transition(key string, goal State) error {
+	e, ok := r.entry(key)
+	if !ok {
+		return ErrUnknownMachine
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.machine.Transition(goal)
+}
+
+// TransitionCtx is like Transition but hands ctx to the machine's
+// TransitionCtx.
+func (r *Registry) TransitionCtx(ctx context.Context, key string, goal State) error {
+	e, ok := r.entry(key)
+	if !ok {
+		return ErrUnknownMachine
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.machine.TransitionCtx(ctx, goal)
+}
+
+// Do calls fn with the machine stored under key, holding off any other
+// transition of it until fn returns, and returns what fn does.
+func (r *Registry) Do(key string, fn func(m Machine) error) error {
+	e, ok := r.entry(key)
+	if !ok {
+		return ErrUnknownMachine
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return fn(e.machine)
+}
+
+// Len returns the number of machines in the registry.
+func (r *Registry) Len() int {
+	n := 0
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		n += len(s.entries)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for every machine in the registry until fn returns false.
+// Each machine is locked while fn runs, as in Do, so fn sees a settled
+// state but must not call back into the registry for the same key.
+// Machines added or removed during Range may or may not be visited.
+func (r *Registry) Range(fn func(key string, m Machine) bool) {
+	for i := range r.shards {
+		for _, key := range r.shards[i].keys() {
+			e, ok := r.entry(key)
+			if ok && !e.visit(key, fn) {
+				return
+			}
+		}
+	}
+}
+
+func (e *registryEntry) visit(key string, fn func(key string, m Machine) bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return fn(key, e.machine)
+}
@@ -0,0 +1,116 @@
+package fsm_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRegistry(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	registry := fsm.NewRegistry()
+
+	some_thing := Thing{State: "pending"}
+	registry.Put("a", fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing)))
+
+	st.Expect(t, registry.Len(), 1)
+	st.Expect(t, registry.Transition("b", "started"), fsm.ErrUnknownMachine)
+	st.Expect(t, registry.Transition("a", "finished"), fsm.ErrInvalidTransition)
+	st.Expect(t, registry.Transition("a", "started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	m, ok := registry.Get("a")
+	st.Expect(t, ok, true)
+	st.Expect(t, m.Subject, fsm.Stater(&some_thing))
+
+	registry.Delete("a")
+	_, ok = registry.Get("a")
+	st.Expect(t, ok, false)
+	st.Expect(t, registry.Len(), 0)
+}
+
+func TestRegistryRangeDuringTransition(t *testing.T) {
+	// run with -race: Range, and what's built on it, must not read a
+	// machine's state while a transition is writing it
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+	registry := fsm.NewRegistry(fsm.WithShards(2))
+	for i := range 8 {
+		registry.Put(fmt.Sprintf("machine-%d", i), fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"})))
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			for j := range 100 {
+				goal := fsm.State("started")
+				if j%2 == 1 {
+					goal = "pending"
+				}
+				st.Expect(t, registry.Transition(key, goal), nil)
+			}
+		}(fmt.Sprintf("machine-%d", i))
+	}
+
+	for range 50 {
+		n := 0
+		registry.Range(func(key string, m fsm.Machine) bool {
+			if m.Subject.CurrentState() != "" {
+				n++
+			}
+			return true
+		})
+		st.Expect(t, n, 8)
+		st.Expect(t, len(registry.Find(fsm.Filter{})), 8)
+		st.Expect(t, registry.Stats().Machines, 8)
+	}
+	wg.Wait()
+}
+
+func BenchmarkRegistryParallelTransition(b *testing.B) {
+	// Every goroutine toggles its own machines back and forth. With a single
+	// shard they all fight over one lock; with the default shard count they
+	// should scale with the number of cores (compare with -cpu 1,2,4,8).
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "pending"},
+	)
+
+	for _, shards := range []int{1, fsm.DefaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			registry := fsm.NewRegistry(fsm.WithShards(shards))
+
+			keys := make([]string, 10000)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("machine-%d", i)
+				registry.Put(keys[i], fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"})))
+			}
+
+			var next int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				offset := int(atomic.AddInt64(&next, 1)) * 1000
+				for i := 0; pb.Next(); i++ {
+					key := keys[(offset+i%1000)%len(keys)]
+					m, _ := registry.Get(key)
+					goal := fsm.State("started")
+					if m.Subject.CurrentState() == goal {
+						goal = "pending"
+					}
+					registry.Transition(key, goal)
+				}
+			})
+		})
+	}
+}
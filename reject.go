@@ -0,0 +1,155 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// NoRuleError reports a transition that the rules don't have at all.
+type NoRuleError struct {
+	From, To State
+}
+
+func (e *NoRuleError) Error() string {
+	return fmt.Sprintf("%v: no rule for %s -> %s", ErrInvalidTransition, e.From, e.To)
+}
+
+// Unwrap returns ErrInvalidTransition.
+func (e *NoRuleError) Unwrap() error { return ErrInvalidTransition }
+
+// GuardError reports a transition refused by one of its guards: the one
+// at Index among the guards of the rule. Err is what the guard returned,
+// for guards that explain themselves, and nil for a plain Guard.
+type GuardError struct {
+	From, To State
+	Index    int
+	Err      error
+}
+
+func (e *GuardError) Error() string {
+	msg := fmt.Sprintf("%v: guard %d of %s -> %s refused", ErrInvalidTransition, e.Index+1, e.From, e.To)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns ErrInvalidTransition and Err, so that either can be
+// matched with errors.Is.
+func (e *GuardError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrInvalidTransition}
+	}
+	return []error{ErrInvalidTransition, e.Err}
+}
+
+// PermittedE is like Permitted but says why a transition isn't allowed,
+// with a *NoRuleError or a *GuardError.
+func (r Ruleset) PermittedE(subject Stater, goal State) error {
+	origin := subject.CurrentState()
+
+	guards, ok := r[T{origin, goal}]
+	if !ok {
+		return &NoRuleError{From: origin, To: goal}
+	}
+	for i, guard := range guards {
+		if !guard(subject, goal) {
+			return &GuardError{From: origin, To: goal, Index: i}
+		}
+	}
+	return nil
+}
+
+// PermittedE is like Permitted but says why a transition isn't allowed,
+// with a *NoRuleError or a *GuardError.
+func (c *CompiledRuleset) PermittedE(subject Stater, goal State) error {
+	origin := subject.CurrentState()
+
+	e := c.find(origin, goal)
+	if e == nil {
+		return &NoRuleError{From: origin, To: goal}
+	}
+	for i, guard := range e.guards {
+		if !guard(subject, goal) {
+			return &GuardError{From: origin, To: goal, Index: i}
+		}
+	}
+	return nil
+}
+
+// WithRejectionErrors is intended to be passed to New to have the machine
+// say why its Rules refuse a transition, with a *NoRuleError or a
+// *GuardError, when they are a Ruleset or CompiledRuleset. Both wrap
+// ErrInvalidTransition. Without it, the machine returns
+// ErrInvalidTransition itself, which costs no allocation.
+func WithRejectionErrors() func(*Machine) {
+	return func(m *Machine) {
+		m.rejectionErrors = true
+	}
+}
+
+// consult asks the machine's Rules whether tc is permitted, and if not, why.
+func (m Machine) consult(tc TransitionContext) error {
+	if m.tracer != nil {
+		if r, ok := m.rules().(guarded); ok {
+			return m.consultTraced(tc, r)
+		}
+	}
+	if m.rejectionErrors {
+		if r, ok := m.rules().(interface {
+			PermittedE(Stater, State) error
+		}); ok {
+			return r.PermittedE(tc.Subject, tc.Goal)
+		}
+	}
+	if !m.rules().Permitted(tc.Subject, tc.Goal) {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// guarded is implemented by Rules whose guards can be run one by one.
+type guarded interface {
+	guardsOf(origin, goal State) ([]Guard, bool)
+}
+
+func (r Ruleset) guardsOf(origin, goal State) ([]Guard, bool) {
+	guards, ok := r[T{origin, goal}]
+	return guards, ok
+}
+
+func (c *CompiledRuleset) guardsOf(origin, goal State) ([]Guard, bool) {
+	e := c.find(origin, goal)
+	if e == nil {
+		return nil, false
+	}
+	return e.guards, true
+}
+
+// consultTraced is consult for a traced machine, recording each guard.
+func (m Machine) consultTraced(tc TransitionContext, r guarded) error {
+	origin := tc.Subject.CurrentState()
+	guards, ok := r.guardsOf(origin, tc.Goal)
+	if !ok {
+		if m.rejectionErrors {
+			return &NoRuleError{From: origin, To: tc.Goal}
+		}
+		return ErrInvalidTransition
+	}
+
+	for i, guard := range guards {
+		start := time.Now()
+		if guard(tc.Subject, tc.Goal) {
+			m.tracer.guard(tc, i, start, nil)
+			continue
+		}
+
+		var err error = ErrInvalidTransition
+		if m.rejectionErrors {
+			err = &GuardError{From: origin, To: tc.Goal, Index: i}
+		}
+		m.tracer.guard(tc, i, start, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestPermittedE(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	rules.AddRule(fsm.T{"started", "finished"},
+		func(fsm.Stater, fsm.State) bool { return true },
+		func(fsm.Stater, fsm.State) bool { return false },
+	)
+	started := &Thing{State: "started"}
+
+	for i, p := range []interface {
+		PermittedE(fsm.Stater, fsm.State) error
+	}{rules, rules.Compile()} {
+		st.Expect(t, p.PermittedE(&Thing{State: "pending"}, "started"), nil, i)
+		st.Expect(t, p.PermittedE(started, "pending"), error(&fsm.NoRuleError{From: "started", To: "pending"}), i)
+		st.Expect(t, p.PermittedE(started, "finished"), error(&fsm.GuardError{From: "started", To: "finished", Index: 1}), i)
+	}
+}
+
+func TestWithRejectionErrors(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithRejectionErrors())
+
+	err := the_machine.Transition("finished")
+	var noRule *fsm.NoRuleError
+	st.Expect(t, errors.As(err, &noRule), true)
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, err.Error(), "invalid transition: no rule for pending -> finished")
+	st.Expect(t, the_machine.Stats().Rejections["invalid"], uint64(1))
+
+	// Without the option, the sentinel itself is returned.
+	the_machine = fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing))
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+}
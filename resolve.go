@@ -0,0 +1,76 @@
+package fsm
+
+// Resolver chooses which of several permitted goals a machine moves to.
+// The candidates are in the order they were offered, and are never empty.
+// Returning false declines all of them.
+type Resolver func(subject Stater, candidates []State) (State, bool)
+
+// FirstDeclared resolves to the first candidate. It is the default.
+func FirstDeclared(subject Stater, candidates []State) (State, bool) {
+	return candidates[0], true
+}
+
+// Priority resolves to the candidate that comes first in order, falling
+// back to the first candidate for goals that aren't listed.
+func Priority(order ...State) Resolver {
+	rank := make(map[State]int, len(order))
+	for i, s := range order {
+		rank[s] = i + 1
+	}
+
+	return func(subject Stater, candidates []State) (State, bool) {
+		best := candidates[0]
+		for _, c := range candidates[1:] {
+			if r := rank[c]; r > 0 && (rank[best] == 0 || r < rank[best]) {
+				best = c
+			}
+		}
+		return best, true
+	}
+}
+
+// WithResolver is intended to be passed to New to set how
+// Machine.TransitionAmong and Machine.Fire pick between permitted goals.
+func WithResolver(r Resolver) func(*Machine) {
+	return func(m *Machine) {
+		m.resolver = r
+	}
+}
+
+// TransitionAmong moves the Subject to one of goals, for triggers that can
+// lead to several states. The goals the Rules permit are handed to the
+// machine's Resolver, and the one it picks is returned once the transition
+// has been made. The guards of the chosen goal run again as part of it.
+func (m Machine) TransitionAmong(goals ...State) (State, error) {
+	goal, ok := m.choose(m.permitted(goals))
+	if !ok {
+		m.stats.rejected(ErrInvalidTransition)
+		return "", ErrInvalidTransition
+	}
+
+	return goal, m.Transition(goal)
+}
+
+// permitted returns those of goals the Rules permit, in order.
+func (m Machine) permitted(goals []State) []State {
+	var permitted []State
+	for _, g := range goals {
+		if m.rules().Permitted(m.Subject, g) {
+			permitted = append(permitted, g)
+		}
+	}
+	return permitted
+}
+
+// choose hands candidates to the machine's Resolver. It is false when
+// there are none or the Resolver declines them all.
+func (m Machine) choose(candidates []State) (State, bool) {
+	if len(candidates) == 0 {
+		return "", false
+	}
+	resolve := m.resolver
+	if resolve == nil {
+		resolve = FirstDeclared
+	}
+	return resolve(m.Subject, candidates)
+}
@@ -0,0 +1,53 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestTransitionAmong(t *testing.T) {
+	deny := func(subject fsm.Stater, goal fsm.State) bool { return false }
+	rules := fsm.CreateRuleset(
+		fsm.T{"triage", "tier1"},
+		fsm.T{"triage", "tier2"},
+		fsm.T{"triage", "specialist"},
+	)
+	rules.AddRule(fsm.T{"triage", "specialist"}, deny)
+
+	leastBusy := func(subject fsm.Stater, candidates []fsm.State) (fsm.State, bool) {
+		return candidates[len(candidates)-1], true
+	}
+	never := func(subject fsm.Stater, candidates []fsm.State) (fsm.State, bool) {
+		return "", false
+	}
+
+	examples := []struct {
+		resolver fsm.Resolver
+		goal     fsm.State
+		err      error
+	}{
+		{nil, "tier1", nil},
+		{fsm.FirstDeclared, "tier1", nil},
+		{fsm.Priority("specialist", "tier2"), "tier2", nil},
+		{leastBusy, "tier2", nil},
+		{never, "", fsm.ErrInvalidTransition},
+	}
+
+	for i, ex := range examples {
+		some_thing := Thing{State: "triage"}
+		the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&some_thing), fsm.WithResolver(ex.resolver))
+
+		goal, err := the_machine.TransitionAmong("tier1", "specialist", "tier2")
+		st.Expect(t, err, ex.err, i)
+		st.Expect(t, goal, ex.goal, i)
+		if err == nil {
+			st.Expect(t, some_thing.State, ex.goal, i)
+		}
+	}
+
+	the_machine := fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "triage"}))
+	_, err := the_machine.TransitionAmong("specialist", "closed")
+	st.Expect(t, err, fsm.ErrInvalidTransition)
+}
@@ -0,0 +1,125 @@
+package fsm
+
+import "time"
+
+// Retention limits how much of a machine's past is kept. Zero fields don't
+// limit anything.
+type Retention struct {
+	MaxEntries int           // most recent events to keep
+	MaxAge     time.Duration // oldest event to keep, by its Time
+
+	// KeepSummary tallies the events dropped from History, so that
+	// Machine.HistorySummary still counts them. Events compacted out of an
+	// EventStore are always tallied in its snapshots.
+	KeepSummary bool
+}
+
+// WithRetention is intended to be passed to New to enforce r after every
+// transition, on the machine's History and on its EventStore. The store is
+// only trimmed if it is a Compacter; see Compact.
+func WithRetention(r Retention) func(*Machine) {
+	return func(m *Machine) {
+		m.retention = &r
+	}
+}
+
+func (m Machine) retain(now time.Time) error {
+	if m.retention == nil {
+		return nil
+	}
+	m.history.retain(*m.retention, now)
+	if m.log != nil {
+		return m.log.retain(*m.retention, now)
+	}
+	return nil
+}
+
+func (h *history) retain(r Retention, now time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	drop := 0
+	if r.MaxEntries > 0 && len(h.entries) > r.MaxEntries {
+		drop = len(h.entries) - r.MaxEntries
+	}
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for drop < len(h.entries) && h.entries[drop].Time.Before(cutoff) {
+			drop++
+		}
+	}
+	if drop == 0 {
+		return
+	}
+
+	if r.KeepSummary {
+		if h.summary == nil {
+			h.summary = tally{}
+		}
+		for _, e := range h.entries[:drop] {
+			h.summary.add(e)
+		}
+	}
+	h.entries = append(h.entries[:0:0], h.entries[drop:]...)
+}
+
+func (l *eventLog) retain(r Retention, now time.Time) error {
+	c, ok := l.store.(Compacter)
+	if !ok || !compactable(c) {
+		return nil
+	}
+
+	l.mu.Lock()
+	seq, through := l.seq, l.compacted
+	l.mu.Unlock()
+
+	if r.MaxEntries > 0 && seq > uint64(r.MaxEntries) {
+		through = max(through, seq-uint64(r.MaxEntries))
+	}
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for e, err := range l.store.Events(through) {
+			if err != nil {
+				return err
+			}
+			if !e.Time.Before(cutoff) {
+				break
+			}
+			through = e.Seq
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if through <= l.compacted {
+		return nil
+	}
+	if err := Compact(c, through); err != nil {
+		return err
+	}
+	l.compacted = through
+
+	return nil
+}
+
+// HistorySummary tallies every transition in the machine's History,
+// including those dropped by a Retention with KeepSummary.
+func (m Machine) HistorySummary() []TransitionCount {
+	if m.history == nil {
+		return nil
+	}
+	m.history.mu.Lock()
+	defer m.history.mu.Unlock()
+
+	t := tally{}
+	for k, c := range m.history.summary {
+		t[k] = c
+	}
+	for _, e := range m.history.entries {
+		t.add(e)
+	}
+	return t.summary()
+}
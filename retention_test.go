@@ -0,0 +1,76 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestRetention(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := &fsm.MemoryEventStore{}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"pending", "started"},
+			fsm.T{"started", "pending"},
+		)),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithClock(fsm.ClockFunc(func() time.Time { return now })),
+		fsm.WithEventStore(store, 0),
+		fsm.WithHistory(),
+		fsm.WithRetention(fsm.Retention{MaxEntries: 3, MaxAge: time.Hour, KeepSummary: true}),
+	)
+
+	goals := []fsm.State{"started", "pending", "started", "pending", "started"}
+	for i, goal := range goals {
+		st.Expect(t, the_machine.Transition(goal), nil, i)
+		now = now.Add(time.Minute)
+	}
+
+	st.Expect(t, len(the_machine.History()), 3)
+	st.Expect(t, the_machine.History()[0].Seq, uint64(3))
+	st.Expect(t, the_machine.HistorySummary()[0].Count, uint64(3)) // pending -> started
+
+	var kept []uint64
+	for e := range store.Events(0) {
+		kept = append(kept, e.Seq)
+	}
+	st.Expect(t, kept, []uint64{3, 4, 5})
+
+	// an hour later only the newest event is young enough to keep
+	now = now.Add(time.Hour)
+	st.Expect(t, the_machine.Transition("pending"), nil)
+	st.Expect(t, len(the_machine.History()), 1)
+	st.Expect(t, the_machine.HistorySummary()[1].Count, uint64(3)) // started -> pending
+
+	snap, ok, err := store.LatestSnapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	st.Expect(t, snap.Seq, uint64(5))
+}
+
+func TestRetentionKeepsNumbering(t *testing.T) {
+	// Without an event store the history numbers the events itself, and
+	// must carry on from the last one however many were dropped.
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"pending", "started"},
+			fsm.T{"started", "pending"},
+		)),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithHistory(),
+		fsm.WithRetention(fsm.Retention{MaxEntries: 2}),
+	)
+
+	goals := []fsm.State{"started", "pending", "started", "pending", "started"}
+	for i, goal := range goals {
+		st.Expect(t, the_machine.Transition(goal), nil, i)
+	}
+
+	history := the_machine.History()
+	st.Expect(t, len(history), 2)
+	st.Expect(t, history[0].Seq, uint64(4))
+	st.Expect(t, history[1].Seq, uint64(5))
+}
@@ -0,0 +1,106 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Principal is the actor behind a transition. RequireRole looks for one in
+// the transition's payload, or asks a Directory for that of its Actor.
+type Principal interface {
+	Roles() []string
+}
+
+// Directory finds the Principal of an actor, as named by TransitionAs. It
+// returns a nil Principal for actors it doesn't know.
+type Directory func(ctx context.Context, actor string) (Principal, error)
+
+// AuthorizationError reports a transition refused for lack of a role.
+type AuthorizationError struct {
+	Origin, Goal State
+	Required     []string
+	Actor        string    // of the transition, if it had one
+	Principal    Principal // nil when none was found
+}
+
+func (e *AuthorizationError) Error() string {
+	who := "no principal"
+	if e.Principal != nil {
+		who = fmt.Sprintf("principal with roles [%s]", strings.Join(e.Principal.Roles(), ", "))
+	}
+	if e.Actor != "" {
+		who = fmt.Sprintf("%s for actor %q", who, e.Actor)
+	}
+	return fmt.Sprintf("unauthorized: %s -> %s requires one of [%s], have %s",
+		e.Origin, e.Goal, strings.Join(e.Required, ", "), who)
+}
+
+func (e *AuthorizationError) Unwrap() error { return ErrUnauthorized }
+
+// RequireRole creates a Check that permits the transition only when its
+// payload is a Principal holding at least one of roles. Use a Directory's
+// RequireRole to look up the roles of the transition's Actor instead.
+func RequireRole(roles ...string) Check {
+	return Directory(nil).RequireRole(roles...)
+}
+
+// RequireRole creates a Check that permits the transition only when its
+// principal holds at least one of roles. The principal of a transition
+// made with an Actor is the one d finds for it, whatever the payload
+// holds; that of one made without is its payload, if it is a Principal. A
+// nil Directory always takes the payload's.
+func (d Directory) RequireRole(roles ...string) Check {
+	return func(tc TransitionContext) error {
+		p, _ := tc.Payload.(Principal)
+		if d != nil && tc.Actor != "" {
+			var err error
+			if p, err = d(tc.Context(), tc.Actor); err != nil {
+				return fmt.Errorf("%w: actor %q: %w", ErrUnauthorized, tc.Actor, err)
+			}
+		}
+		if holds(p, roles) {
+			return nil
+		}
+
+		return &AuthorizationError{Origin: tc.Origin, Goal: tc.Goal, Required: roles, Actor: tc.Actor, Principal: p}
+	}
+}
+
+// RoleGuard creates a Guard that permits its transition only when the
+// principal of the subject holds at least one of roles. Guards don't see
+// the transition's actor or payload, so the principal comes from the
+// subject, such as whoever it is assigned to; principal returns nil when
+// it has none. Use RequireRole to check the actor making the transition.
+func RoleGuard(principal func(subject Stater) Principal, roles ...string) Guard {
+	return func(subject Stater, goal State) bool {
+		return holds(principal(subject), roles)
+	}
+}
+
+// Roles lets the transitions of the builder, and of the definitions it
+// loads, be protected by RoleGuards: the guard "role:manager" permits a
+// transition only when the principal of the subject is a manager, and
+// "role:manager,admin" when it is either.
+func (b *Builder) Roles(principal func(subject Stater) Principal) *Builder {
+	return b.GuardFactory("role", func(roles string) (Guard, error) {
+		return RoleGuard(principal, strings.Split(roles, ",")...), nil
+	})
+}
+
+// holds reports whether p holds any of roles.
+func holds(p Principal, roles []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, have := range p.Roles() {
+		if slices.Contains(roles, have) {
+			return true
+		}
+	}
+	return false
+}
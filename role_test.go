@@ -0,0 +1,90 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+type User struct {
+	Name  string
+	Group []string
+}
+
+func (u User) Roles() []string { return u.Group }
+
+func TestRequireRole(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "approved"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithChecks(fsm.T{"pending", "approved"}, fsm.RequireRole("manager", "admin")),
+	)
+
+	err := the_machine.Transition("approved")
+	st.Expect(t, errors.Is(err, fsm.ErrUnauthorized), true)
+
+	err = the_machine.TransitionWith("approved", User{"bob", []string{"staff"}})
+	var authErr *fsm.AuthorizationError
+	st.Expect(t, errors.As(err, &authErr), true)
+	st.Expect(t, authErr.Required, []string{"manager", "admin"})
+	st.Expect(t, authErr.Principal, fsm.Principal(User{"bob", []string{"staff"}}))
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+	st.Expect(t, the_machine.Stats().Rejections["unauthorized"], uint64(2))
+
+	st.Expect(t, the_machine.TransitionWith("approved", User{"alice", []string{"staff", "admin"}}), nil)
+	st.Expect(t, some_thing.State, fsm.State("approved"))
+}
+
+func TestDirectoryRequireRole(t *testing.T) {
+	staff := fsm.Directory(func(ctx context.Context, actor string) (fsm.Principal, error) {
+		switch actor {
+		case "alice":
+			return User{"alice", []string{"admin"}}, nil
+		case "bob":
+			return User{"bob", []string{"staff"}}, nil
+		}
+		return nil, nil
+	})
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "pending", E: "approved"})),
+		fsm.WithSubject(&some_thing),
+		fsm.WithChecks(fsm.T{O: "pending", E: "approved"}, staff.RequireRole("admin")),
+	)
+
+	// the actor's roles count, not those the payload claims
+	err := the_machine.TransitionAs("bob", "approved", User{"bob", []string{"admin"}})
+	var authErr *fsm.AuthorizationError
+	st.Expect(t, errors.As(err, &authErr), true)
+	st.Expect(t, authErr.Actor, "bob")
+	st.Expect(t, authErr.Principal, fsm.Principal(User{"bob", []string{"staff"}}))
+
+	st.Expect(t, errors.Is(the_machine.TransitionAs("mallory", "approved", nil), fsm.ErrUnauthorized), true)
+	st.Expect(t, the_machine.TransitionAs("alice", "approved", nil), nil)
+}
+
+// Ticket is assigned to someone, who needs a role to close it.
+type Ticket struct {
+	State    fsm.State
+	Assignee User
+}
+
+func (t *Ticket) CurrentState() fsm.State  { return t.State }
+func (t *Ticket) SetState(state fsm.State) { t.State = state }
+
+func TestRoleGuard(t *testing.T) {
+	assignee := func(subject fsm.Stater) fsm.Principal { return subject.(*Ticket).Assignee }
+	rules, err := fsm.NewBuilder().
+		Roles(assignee).
+		Transition(fsm.T{O: "open", E: "closed"}, "role:manager,admin").
+		Build()
+	st.Expect(t, err, nil)
+
+	st.Expect(t, rules.Permitted(&Ticket{State: "open", Assignee: User{"bob", []string{"staff"}}}, "closed"), false)
+	st.Expect(t, rules.Permitted(&Ticket{State: "open", Assignee: User{"alice", []string{"admin"}}}, "closed"), true)
+}
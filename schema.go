@@ -0,0 +1,154 @@
+package fsm
+
+import "sort"
+
+// StateChange is a change to the states of a ruleset, made by a Revision.
+type StateChange struct {
+	old, new State
+	pick     func(e TransitionEvent) State
+}
+
+// RenameState declares that old is now called new.
+func RenameState(old, new State) StateChange {
+	return StateChange{old: old, new: new}
+}
+
+// SplitState declares that old has become several states. pick says which
+// of them each event that entered old entered instead; a persisted state
+// is passed as an event with only its Seq, Goal and Time.
+func SplitState(old State, pick func(e TransitionEvent) State) StateChange {
+	return StateChange{old: old, pick: pick}
+}
+
+// Revision is a version of a ruleset's states and the changes that led to
+// it from the version before.
+type Revision struct {
+	Version int
+	Changes []StateChange
+}
+
+// Schema is the history of a ruleset's states, oldest Revision first. A
+// machine created WithSchema stamps its events and snapshots with the
+// Version of the schema, and migrates those of older versions to the
+// current states as it replays or restores them, so that states can be
+// renamed without rewriting what has been persisted.
+type Schema []Revision
+
+// Version returns the version of the latest Revision, or 0 for none.
+func (s Schema) Version() int {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].Version
+}
+
+// WithSchema is intended to be passed to New to migrate persisted events
+// and snapshots according to s.
+func WithSchema(s Schema) func(*Machine) {
+	return func(m *Machine) {
+		m.schema = s
+	}
+}
+
+// state migrates state from version to the current one. Splits are
+// decided by pick with e, unless e is nil, in which case ok is false.
+func (s Schema) state(version int, state State, e *TransitionEvent) (_ State, ok bool) {
+	for _, mg := range s {
+		if mg.Version <= version {
+			continue
+		}
+		for _, c := range mg.Changes {
+			if c.old != state {
+				continue
+			}
+			if c.pick == nil {
+				state = c.new
+			} else if e == nil {
+				return "", false
+			} else {
+				state = c.pick(*e)
+			}
+			break
+		}
+	}
+	return state, true
+}
+
+// MigrateEvent rewrites e, recorded under an older version of the schema,
+// in terms of the current states. current is the state the event is
+// replayed from, which decides its Origin when that state has been split.
+func (s Schema) MigrateEvent(e TransitionEvent, current State) TransitionEvent {
+	if e.Schema >= s.Version() {
+		return e
+	}
+
+	origin, ok := s.state(e.Schema, e.Origin, nil)
+	if !ok {
+		origin = current
+	}
+	e.Goal, _ = s.state(e.Schema, e.Goal, &e)
+	e.Origin, e.Schema = origin, s.Version()
+	return e
+}
+
+// migrateAll rewrites events, a machine's history oldest first, in terms
+// of the current states.
+func (s Schema) migrateAll(events []TransitionEvent) []TransitionEvent {
+	if len(events) == 0 {
+		return events
+	}
+
+	first := events[0]
+	current, _ := s.state(first.Schema, first.Origin, &TransitionEvent{Goal: first.Origin, Time: first.Time})
+	migrated := make([]TransitionEvent, len(events))
+	for i, e := range events {
+		migrated[i] = s.MigrateEvent(e, current)
+		current = migrated[i].Goal
+	}
+	return migrated
+}
+
+// MigrateSnapshot rewrites snap, saved under an older version of the
+// schema, in terms of the current states. Transitions in its Summary that
+// end up the same are counted together.
+func (s Schema) MigrateSnapshot(snap Snapshot) Snapshot {
+	if snap.Schema >= s.Version() {
+		return snap
+	}
+
+	snap.State, _ = s.state(snap.Schema, snap.State, &TransitionEvent{Seq: snap.Seq, Goal: snap.State, Time: snap.Time})
+
+	merged := map[T]TransitionCount{}
+	for _, c := range snap.Summary {
+		e := TransitionEvent{Origin: c.Origin, Goal: c.Goal, Time: c.Last}
+		c.Origin, _ = s.state(snap.Schema, c.Origin, &TransitionEvent{Goal: c.Origin, Time: c.First})
+		c.Goal, _ = s.state(snap.Schema, c.Goal, &e)
+
+		key := T{c.Origin, c.Goal}
+		if m, ok := merged[key]; ok {
+			c.Count += m.Count
+			if m.First.Before(c.First) {
+				c.First = m.First
+			}
+			if m.Last.After(c.Last) {
+				c.Last = m.Last
+			}
+		}
+		merged[key] = c
+	}
+
+	snap.Summary = nil
+	for _, c := range merged {
+		snap.Summary = append(snap.Summary, c)
+	}
+	sort.Slice(snap.Summary, func(i, j int) bool {
+		a, b := snap.Summary[i], snap.Summary[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		return a.Goal < b.Goal
+	})
+
+	snap.Schema = s.Version()
+	return snap
+}
@@ -0,0 +1,87 @@
+package fsm_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestSchemaMigratesReplayedEvents(t *testing.T) {
+	schema := fsm.Schema{
+		{Version: 1, Changes: []fsm.StateChange{fsm.RenameState("started", "running")}},
+		{Version: 2, Changes: []fsm.StateChange{
+			fsm.SplitState("finished", func(e fsm.TransitionEvent) fsm.State {
+				if e.Actor == "admin" {
+					return "cancelled"
+				}
+				return "succeeded"
+			}),
+		}},
+	}
+	st.Expect(t, schema.Version(), 2)
+
+	events := []fsm.TransitionEvent{
+		{Seq: 1, Origin: "pending", Goal: "started"},
+		{Seq: 2, Origin: "started", Goal: "finished", Actor: "admin"},
+	}
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(fsm.WithRules(fsm.Ruleset{}), fsm.WithSubject(&some_thing), fsm.WithSchema(schema))
+
+	st.Expect(t, the_machine.Replay(slices.Values(events)), nil)
+	st.Expect(t, some_thing.State, fsm.State("cancelled"))
+
+	// Events from the current version are left alone.
+	e := fsm.TransitionEvent{Origin: "pending", Goal: "started", Schema: 2}
+	st.Expect(t, schema.MigrateEvent(e, "pending"), e)
+
+	// An origin that has been split is whatever the subject was in.
+	e = schema.MigrateEvent(fsm.TransitionEvent{Origin: "finished", Goal: "started", Schema: 1}, "succeeded")
+	st.Expect(t, e.Origin, fsm.State("succeeded"))
+	st.Expect(t, e.Goal, fsm.State("started"))
+	st.Expect(t, e.Schema, 2)
+}
+
+func TestSchemaMigratesRestoredSnapshots(t *testing.T) {
+	store := &fsm.MemoryEventStore{}
+	first, last := time.Unix(100, 0), time.Unix(200, 0)
+	store.SaveSnapshot(fsm.Snapshot{Seq: 3, State: "started", Time: last, Summary: []fsm.TransitionCount{
+		{Origin: "pending", Goal: "running", Count: 1, First: last, Last: last},
+		{Origin: "pending", Goal: "started", Count: 2, First: first, Last: first},
+	}})
+	store.Append(fsm.TransitionEvent{Seq: 4, Origin: "started", Goal: "pending", Time: last})
+
+	schema := fsm.Schema{{Version: 1, Changes: []fsm.StateChange{fsm.RenameState("started", "running")}}}
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "running"},
+		fsm.T{"running", "pending"},
+	)
+
+	some_thing := Thing{}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 5),
+		fsm.WithSchema(schema),
+	)
+
+	st.Expect(t, the_machine.Restore(), nil)
+	st.Expect(t, some_thing.State, fsm.State("pending"))
+
+	// The next snapshot is saved under the current version, with the
+	// renamed transitions counted together.
+	st.Expect(t, the_machine.Transition("running"), nil)
+	snap, _, err := store.LatestSnapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, snap.Seq, uint64(5))
+	st.Expect(t, snap.Schema, 1)
+	st.Expect(t, snap.Summary[0].Origin, fsm.State("pending"))
+	st.Expect(t, snap.Summary[0].Goal, fsm.State("running"))
+	st.Expect(t, snap.Summary[0].Count, uint64(4))
+	st.Expect(t, snap.Summary[0].First, first)
+	st.Expect(t, snap.Summary[1].Origin, fsm.State("running"))
+	st.Expect(t, snap.Summary[1].Count, uint64(1))
+}
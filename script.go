@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrScriptTimeout = errors.New("script timed out")
+	ErrScriptLimit   = errors.New("script exceeded its limits")
+)
+
+// ScriptEngine compiles guard and action scripts written in an embedded
+// language. The only engine this package ships is ExprEngine, so that it
+// doesn't depend on an interpreter. Other languages, such as Starlark or
+// Lua, are plugged in by implementing this interface around their
+// interpreter; such an adapter is responsible for sandboxing the scripts
+// it runs, limiting their resources and giving up once the context of Run
+// is done. For Starlark, Compile would parse src with the starlark package
+// and Run would call the program's result with a starlark.Thread whose
+// Cancel is called when ctx is done and whose SetMaxExecutionSteps bounds
+// the work.
+type ScriptEngine interface {
+	Compile(name, src string) (Script, error)
+}
+
+// Script is a compiled script. Run must give up once ctx is done.
+type Script interface {
+	Run(ctx context.Context, tc TransitionContext) (allow bool, err error)
+}
+
+// ScriptCheck compiles src with engine into a Check. Each run of the script
+// is limited to timeout, when it is positive.
+func ScriptCheck(engine ScriptEngine, name, src string, timeout time.Duration) (Check, error) {
+	script, err := engine.Compile(name, src)
+	if err != nil {
+		return nil, fmt.Errorf("fsm: script %s: %w", name, err)
+	}
+
+	return func(tc TransitionContext) error {
+		allow, err := runScript(script, tc, timeout)
+		if err != nil {
+			return fmt.Errorf("fsm: script %s: %w", name, err)
+		}
+		if !allow {
+			return fmt.Errorf("%w: %w: script %s", ErrInvalidTransition, ErrDenied, name)
+		}
+		return nil
+	}, nil
+}
+
+// ScriptHook compiles src with engine into a Hook, for scripted actions.
+// Errors, including timeouts, are passed to onError, which may be nil.
+func ScriptHook(engine ScriptEngine, name, src string, timeout time.Duration, onError func(error)) (Hook, error) {
+	script, err := engine.Compile(name, src)
+	if err != nil {
+		return nil, fmt.Errorf("fsm: script %s: %w", name, err)
+	}
+
+	return func(tc TransitionContext) {
+		if _, err := runScript(script, tc, timeout); err != nil && onError != nil {
+			onError(fmt.Errorf("fsm: script %s: %w", name, err))
+		}
+	}, nil
+}
+
+// ScriptGuard compiles src with engine into a Guard, for rules that are
+// built apart from any machine, such as those of a Definition. The script
+// is handed a TransitionContext of just the subject, its state and the
+// goal, and refuses the transition if it fails.
+func ScriptGuard(engine ScriptEngine, name, src string, timeout time.Duration) (Guard, error) {
+	script, err := engine.Compile(name, src)
+	if err != nil {
+		return nil, fmt.Errorf("fsm: script %s: %w", name, err)
+	}
+
+	return func(subject Stater, goal State) bool {
+		tc := TransitionContext{Subject: subject, Origin: subject.CurrentState(), Goal: goal}
+		allow, err := runScript(script, tc, timeout)
+		return err == nil && allow
+	}, nil
+}
+
+// runScript runs script with tc, for at most timeout if it is positive. It
+// is ErrScriptTimeout only when that timeout ran out; when the transition's
+// own context is done first, its error is returned as it is.
+func runScript(script Script, tc TransitionContext, timeout time.Duration) (bool, error) {
+	ctx := tc.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	allow, err := script.Run(ctx, tc)
+	if err != nil && ctx.Err() == context.DeadlineExceeded && tc.Context().Err() == nil {
+		return false, ErrScriptTimeout
+	}
+	return allow, err
+}
+
+// Scripts lets the transitions of the builder, and of the definitions it
+// loads, be protected by scripts compiled with engine: the guard
+// "script:name" runs the script registered under name with Script, or
+// defined under "scripts" in a Definition.
+func (b *Builder) Scripts(engine ScriptEngine) *Builder {
+	return b.GuardFactory("script", func(name string) (Guard, error) {
+		s, ok := b.scripts[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: script %q", ErrUnknownGuard, name)
+		}
+		return ScriptGuard(engine, name, s.Source, s.timeout)
+	})
+}
+
+// Script registers the source of a script under name, for Scripts. Each
+// run of it is limited to timeout, when it is positive.
+func (b *Builder) Script(name, src string, timeout time.Duration) *Builder {
+	if _, ok := b.scripts[name]; ok {
+		b.errs = append(b.errs, fmt.Errorf("%w: script %q", ErrDuplicateGuard, name))
+		return b
+	}
+	if b.scripts == nil {
+		b.scripts = map[string]DefinedScript{}
+	}
+	b.scripts[name] = DefinedScript{Source: src, timeout: timeout}
+	return b
+}
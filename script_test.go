@@ -0,0 +1,104 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// exprEngine understands just two scripts: "goal == <state>" and "loop".
+type exprEngine struct{}
+
+type exprScript string
+
+func (exprEngine) Compile(name, src string) (fsm.Script, error) {
+	if src != "loop" && !strings.HasPrefix(src, "goal == ") {
+		return nil, errors.New("syntax error")
+	}
+	return exprScript(src), nil
+}
+
+func (s exprScript) Run(ctx context.Context, tc fsm.TransitionContext) (bool, error) {
+	if s == "loop" {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	return string(tc.Goal) == strings.TrimPrefix(string(s), "goal == "), nil
+}
+
+func TestScriptCheck(t *testing.T) {
+	_, err := fsm.ScriptCheck(exprEngine{}, "broken", "goal ~ x", time.Second)
+	st.Expect(t, err != nil, true)
+
+	onlyStarted, err := fsm.ScriptCheck(exprEngine{}, "only-started", "goal == started", time.Second)
+	st.Expect(t, err, nil)
+	forever, err := fsm.ScriptCheck(exprEngine{}, "forever", "loop", time.Millisecond)
+	st.Expect(t, err, nil)
+
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(
+			fsm.T{"pending", "started"},
+			fsm.T{"pending", "cancelled"},
+			fsm.T{"started", "finished"},
+		)),
+		fsm.WithSubject(&some_thing),
+		fsm.WithChecks(fsm.FromAnyOf("pending").To("started"), onlyStarted),
+		fsm.WithChecks(fsm.T{"pending", "cancelled"}, onlyStarted),
+		fsm.WithChecks(fsm.T{"started", "finished"}, forever),
+	)
+
+	st.Expect(t, errors.Is(the_machine.Transition("cancelled"), fsm.ErrDenied), true)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, errors.Is(the_machine.Transition("finished"), fsm.ErrScriptTimeout), true)
+}
+
+func TestScriptTimeoutIsTheScripts(t *testing.T) {
+	forever, err := fsm.ScriptCheck(exprEngine{}, "forever", "loop", time.Hour)
+	st.Expect(t, err, nil)
+
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{"pending", "started"})),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithChecks(fsm.T{"pending", "started"}, forever),
+	)
+
+	// the caller's deadline runs out long before the script's
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = the_machine.TransitionCtx(ctx, "started")
+	st.Expect(t, errors.Is(err, fsm.ErrScriptTimeout), false)
+	st.Expect(t, errors.Is(err, context.DeadlineExceeded), true)
+}
+
+func TestScriptsInDefinition(t *testing.T) {
+	rules, err := fsm.NewBuilder().
+		Scripts(fsm.ExprEngine{}).
+		LoadJSONWithParams(strings.NewReader(`{
+		  "params": {"limit": {"default": "100"}},
+		  "transitions": [
+		    {"from": "pending", "to": "approved", "guards": ["script:small"]}
+		  ],
+		  "scripts": {"small": {"source": "subject.Amount < ${limit}", "timeout": "50ms"}}
+		}`), map[string]string{"limit": "500"}).
+		Build()
+	st.Expect(t, err, nil)
+
+	st.Expect(t, rules.Permitted(&order{State: "pending", Amount: 250}, "approved"), true)
+	st.Expect(t, rules.Permitted(&order{State: "pending", Amount: 750}, "approved"), false)
+
+	_, err = fsm.NewBuilder().
+		Scripts(fsm.ExprEngine{}).
+		LoadJSON(strings.NewReader(`{
+		  "transitions": [{"from": "pending", "to": "approved", "guards": ["script:missing", "script:broken"]}],
+		  "scripts": {"broken": {"source": "subject.Amount <"}}
+		}`)).
+		Build()
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownGuard), true)
+	st.Expect(t, strings.Contains(err.Error(), "script broken"), true)
+}
@@ -0,0 +1,84 @@
+package fsm
+
+import "sync"
+
+// Divergence is a transition that the shadow ruleset decided differently
+// from the machine's own Rules.
+type Divergence struct {
+	Origin    State
+	Goal      State
+	Event     string
+	Permitted bool // the machine's decision, which is the one applied
+	Shadow    bool // the shadow ruleset's decision
+}
+
+type shadow struct {
+	rules  Permitter
+	report func(TransitionContext, Divergence)
+}
+
+// WithShadow is intended to be passed to New to evaluate every transition
+// against rules as well, without applying its decisions. Whenever the two
+// disagree, report is called with the transition and the Divergence.
+// Guards of the shadow ruleset run on every transition, so they should be
+// free of side effects.
+func WithShadow(rules Permitter, report func(TransitionContext, Divergence)) func(*Machine) {
+	return func(m *Machine) {
+		m.shadow = &shadow{rules, report}
+	}
+}
+
+func (s *shadow) compare(tc TransitionContext, permitted bool) {
+	if s == nil {
+		return
+	}
+	if shadowed := s.rules.Permitted(tc.Subject, tc.Goal); shadowed != permitted {
+		s.report(tc, Divergence{
+			Origin:    tc.Origin,
+			Goal:      tc.Goal,
+			Event:     tc.Event,
+			Permitted: permitted,
+			Shadow:    shadowed,
+		})
+	}
+}
+
+// DivergenceLog collects Divergences; its Record method can be passed to
+// WithShadow and shared by many machines.
+type DivergenceLog struct {
+	mu      sync.Mutex
+	entries []Divergence
+	counts  map[T]int
+}
+
+// Record adds d to the log.
+func (l *DivergenceLog) Record(tc TransitionContext, d Divergence) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.counts == nil {
+		l.counts = map[T]int{}
+	}
+	l.entries = append(l.entries, d)
+	l.counts[T{d.Origin, d.Goal}]++
+}
+
+// Divergences returns every recorded Divergence, oldest first.
+func (l *DivergenceLog) Divergences() []Divergence {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Divergence(nil), l.entries...)
+}
+
+// Counts returns the number of divergences of each transition.
+func (l *DivergenceLog) Counts() map[T]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := make(map[T]int, len(l.counts))
+	for t, n := range l.counts {
+		c[t] = n
+	}
+	return c
+}
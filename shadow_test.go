@@ -0,0 +1,41 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestShadow(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "finished"},
+	)
+	candidate := fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"pending", "finished"},
+	)
+
+	divergences := &fsm.DivergenceLog{}
+	some_thing := Thing{State: "pending"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithShadow(candidate, divergences.Record),
+	)
+
+	st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, the_machine.Transition("finished"), nil)
+	st.Expect(t, some_thing.State, fsm.State("finished"))
+
+	st.Expect(t, divergences.Divergences(), []fsm.Divergence{
+		{Origin: "pending", Goal: "finished", Permitted: false, Shadow: true},
+		{Origin: "started", Goal: "finished", Permitted: true, Shadow: false},
+	})
+	st.Expect(t, divergences.Counts(), map[fsm.T]int{
+		{"pending", "finished"}: 1,
+		{"started", "finished"}: 1,
+	})
+}
@@ -0,0 +1,51 @@
+package fsm
+
+// StateSet is a group of States that can share a single rule.
+type StateSet []State
+
+// FromAnyOf creates a StateSet from the given origin States, e.g.
+//
+//	rules.AddRule(fsm.FromAnyOf("a", "b", "c").To("d"), guard)
+func FromAnyOf(states ...State) StateSet {
+	return StateSet(states)
+}
+
+// Contains reports whether state is a member of the set.
+func (s StateSet) Contains(state State) bool {
+	for _, member := range s {
+		if member == state {
+			return true
+		}
+	}
+	return false
+}
+
+// To creates a Transition from every State in the set to goal. Ruleset
+// expands it into one T per origin, so it has no single Origin of its own.
+func (s StateSet) To(goal State) Transition {
+	return setTransition{s, goal}
+}
+
+type setTransition struct {
+	origins StateSet
+	exit    State
+}
+
+func (t setTransition) Origin() State { return "" }
+func (t setTransition) Exit() State   { return t.exit }
+
+func (t setTransition) expand() []Transition {
+	ts := make([]Transition, len(t.origins))
+	for i, o := range t.origins {
+		ts[i] = T{o, t.exit}
+	}
+	return ts
+}
+
+// expand returns the keys of the concrete transitions that t stands for.
+func expand(t Transition) []Transition {
+	if e, ok := t.(interface{ expand() []Transition }); ok {
+		return e.expand()
+	}
+	return []Transition{key(t)}
+}
@@ -0,0 +1,21 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestStateSetRule(t *testing.T) {
+	rules := fsm.Ruleset{}
+	rules.AddTransition(fsm.FromAnyOf("pending", "started").To("cancelled"))
+	rules.AddRule(fsm.FromAnyOf("pending", "started").To("cancelled"), func(subject fsm.Stater, goal fsm.State) bool {
+		return subject.(*Thing).State != "started"
+	})
+
+	st.Expect(t, len(rules), 2)
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "cancelled"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "cancelled"), false)
+	st.Expect(t, rules.Permitted(&Thing{State: "finished"}, "cancelled"), false)
+}
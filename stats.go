@@ -0,0 +1,286 @@
+package fsm
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Stats summarizes what a machine, or every machine in a Registry, has
+// done. It is a plain value so it can be exported however the application
+// likes.
+type Stats struct {
+	Transitions uint64
+	Rejections  map[string]uint64 // keyed by reason
+	Guards      Latency           // time spent deciding if a transition is permitted
+	Counts      map[T]uint64      // transitions by origin and goal
+	Durations   map[State]Latency // time spent in each state before leaving it
+
+	// Outbox is how many events wait in the Outbox of the machine's
+	// EventStore to be published, when it is an OutboxCounter. A
+	// RegistryStats counts each store once, however many machines share
+	// it, and leaves it out of its Variants and Labels.
+	Outbox int
+}
+
+// Latency aggregates a set of durations.
+type Latency struct {
+	Count uint64
+	Total time.Duration
+	Min   time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average duration, or zero when there are none.
+func (l Latency) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	return l.Total / time.Duration(l.Count)
+}
+
+func (l *Latency) observe(d time.Duration) {
+	if l.Count == 0 || d < l.Min {
+		l.Min = d
+	}
+	if d > l.Max {
+		l.Max = d
+	}
+	l.Count++
+	l.Total += d
+}
+
+func (l *Latency) merge(o Latency) {
+	if o.Count == 0 {
+		return
+	}
+	if l.Count == 0 || o.Min < l.Min {
+		l.Min = o.Min
+	}
+	if o.Max > l.Max {
+		l.Max = o.Max
+	}
+	l.Count += o.Count
+	l.Total += o.Total
+}
+
+func (s *Stats) merge(o Stats) {
+	s.Transitions += o.Transitions
+	s.Guards.merge(o.Guards)
+	for reason, n := range o.Rejections {
+		if s.Rejections == nil {
+			s.Rejections = map[string]uint64{}
+		}
+		s.Rejections[reason] += n
+	}
+	for t, n := range o.Counts {
+		if s.Counts == nil {
+			s.Counts = map[T]uint64{}
+		}
+		s.Counts[t] += n
+	}
+	for state, d := range o.Durations {
+		if s.Durations == nil {
+			s.Durations = map[State]Latency{}
+		}
+		l := s.Durations[state]
+		l.merge(d)
+		s.Durations[state] = l
+	}
+}
+
+// RegistryStats adds the population of a Registry to the combined Stats of
+// its machines.
+type RegistryStats struct {
+	Stats
+	Machines int
+	States   map[State]int    // number of machines in each state
+	Variants map[string]Stats // combined Stats of the machines in each Experiment variant
+	Labels   map[Label]Stats  // combined Stats of the machines carrying each label
+}
+
+// machineStats collects Stats for a Machine. A nil *machineStats, as found in
+// a Machine that wasn't made by New, ignores everything.
+type machineStats struct {
+	mu      sync.Mutex
+	stats   Stats
+	entered time.Time // when the subject entered its current state
+}
+
+func newMachineStats() *machineStats {
+	return &machineStats{stats: Stats{
+		Rejections: map[string]uint64{},
+		Counts:     map[T]uint64{},
+		Durations:  map[State]Latency{},
+	}}
+}
+
+func (s *machineStats) guarded(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stats.Guards.observe(d)
+	s.mu.Unlock()
+}
+
+func (s *machineStats) transitioned(e TransitionEvent) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stats.Transitions++
+	s.stats.Counts[T{e.Origin, e.Goal}]++
+	if !s.entered.IsZero() {
+		d := s.stats.Durations[e.Origin]
+		d.observe(e.Time.Sub(s.entered))
+		s.stats.Durations[e.Origin] = d
+	}
+	s.entered = e.Time
+	s.mu.Unlock()
+}
+
+// enter notes that the subject entered its state at t, without counting a
+// transition, as when the machine is created or its history replayed.
+func (s *machineStats) enter(t time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.entered = t
+	s.mu.Unlock()
+}
+
+func (s *machineStats) rejected(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stats.Rejections[rejectionReason(err)]++
+	s.mu.Unlock()
+}
+
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrDenied):
+		return "denied"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrLimitExceeded):
+		return "limit"
+	case errors.Is(err, ErrApprovalRequired):
+		return "approval"
+	case errors.Is(err, ErrInvalidTransition):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
+func (s *machineStats) snapshot() Stats {
+	if s == nil {
+		return Stats{Rejections: map[string]uint64{}}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.stats
+	c.Rejections = maps.Clone(s.stats.Rejections)
+	c.Counts = maps.Clone(s.stats.Counts)
+	c.Durations = maps.Clone(s.stats.Durations)
+	return c
+}
+
+// Stats returns a copy of the machine's statistics. Machines that weren't
+// created by New don't collect any.
+func (m Machine) Stats() Stats {
+	s := m.stats.snapshot()
+	s.Outbox, _ = m.outboxDepth()
+	return s
+}
+
+// outboxDepth returns the depth of the machine's Outbox, and the store it
+// is kept in, or nil if it has none it can count.
+func (m Machine) outboxDepth() (int, OutboxCounter) {
+	if m.log == nil {
+		return 0, nil
+	}
+	c, ok := m.log.store.(OutboxCounter)
+	if !ok {
+		return 0, nil
+	}
+	n, err := c.Depth()
+	if err != nil {
+		return 0, nil
+	}
+	return n, c
+}
+
+// Since returns when the subject entered its current state: the time of the
+// last transition, or of the machine's creation or replay. It is zero for
+// machines that weren't created by New.
+func (m Machine) Since() time.Time {
+	if m.stats == nil {
+		return time.Time{}
+	}
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+
+	return m.stats.entered
+}
+
+// Stats combines the statistics of every machine in the registry.
+func (r *Registry) Stats() RegistryStats {
+	rs := RegistryStats{
+		Stats: Stats{
+			Rejections: map[string]uint64{},
+			Counts:     map[T]uint64{},
+			Durations:  map[State]Latency{},
+		},
+		States:   map[State]int{},
+		Variants: map[string]Stats{},
+		Labels:   map[Label]Stats{},
+	}
+
+	outboxes := map[OutboxCounter]bool{}
+	r.Range(func(key string, m Machine) bool {
+		rs.Machines++
+		rs.States[m.Subject.CurrentState()]++
+		s := m.stats.snapshot()
+		rs.merge(s)
+		if n, c := m.outboxDepth(); c != nil && !counted(outboxes, c) {
+			rs.Outbox += n
+		}
+		if v := m.Variant(); v != "" {
+			vs := rs.Variants[v]
+			vs.merge(s)
+			rs.Variants[v] = vs
+		}
+		for name, value := range m.labels {
+			l := Label{name, value}
+			ls := rs.Labels[l]
+			ls.merge(s)
+			rs.Labels[l] = ls
+		}
+		return true
+	})
+
+	return rs
+}
+
+// counted reports whether the outbox c was already counted, marking it if
+// not. Outboxes that can't be compared are counted for every machine.
+func counted(seen map[OutboxCounter]bool, c OutboxCounter) bool {
+	if !reflect.ValueOf(c).Comparable() {
+		return false
+	}
+	if seen[c] {
+		return true
+	}
+	seen[c] = true
+	return false
+}
@@ -0,0 +1,59 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestStats(t *testing.T) {
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	registry := fsm.NewRegistry()
+
+	for _, key := range []string{"a", "b", "c"} {
+		registry.Put(key, fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"})))
+	}
+
+	st.Expect(t, registry.Transition("a", "started"), nil)
+	st.Expect(t, registry.Transition("a", "started"), fsm.ErrInvalidTransition)
+	st.Expect(t, registry.Transition("b", "finished"), fsm.ErrInvalidTransition)
+
+	a, _ := registry.Get("a")
+	stats := a.Stats()
+	st.Expect(t, stats.Transitions, uint64(1))
+	st.Expect(t, stats.Rejections["invalid"], uint64(1))
+	st.Expect(t, stats.Guards.Count, uint64(2))
+	st.Expect(t, stats.Guards.Min <= stats.Guards.Mean(), true)
+	st.Expect(t, stats.Guards.Mean() <= stats.Guards.Max, true)
+
+	rs := registry.Stats()
+	st.Expect(t, rs.Machines, 3)
+	st.Expect(t, rs.States, map[fsm.State]int{"pending": 2, "started": 1})
+	st.Expect(t, rs.Transitions, uint64(1))
+	st.Expect(t, rs.Rejections["invalid"], uint64(2))
+	st.Expect(t, rs.Guards.Count, uint64(3))
+}
+
+func TestStatsOutbox(t *testing.T) {
+	shared := &fsm.MemoryOutboxStore{}
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+	registry := fsm.NewRegistry()
+	for _, key := range []string{"a", "b"} {
+		registry.Put(key, fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"}), fsm.WithEventStore(shared, 0)))
+	}
+	registry.Put("c", fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"}), fsm.WithEventStore(&fsm.MemoryOutboxStore{}, 0)))
+	registry.Put("d", fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "pending"}), fsm.WithEventStore(&fsm.MemoryEventStore{}, 0)))
+
+	for i, key := range []string{"a", "b", "c", "d"} {
+		st.Expect(t, registry.Transition(key, "started"), nil, i)
+	}
+
+	a, _ := registry.Get("a")
+	st.Expect(t, a.Stats().Outbox, 2)
+	d, _ := registry.Get("d")
+	st.Expect(t, d.Stats().Outbox, 0)
+
+	// the shared store is counted once
+	st.Expect(t, registry.Stats().Outbox, 3)
+}
@@ -0,0 +1,92 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StuckDetector watches a Registry for machines that have stayed in a
+// state that isn't Final for longer than its threshold.
+type StuckDetector struct {
+	Registry   *Registry
+	Threshold  time.Duration           // for states not in Thresholds; 0 disables them
+	Thresholds map[State]time.Duration // per state
+	Final      StateSet                // states a machine may stay in forever
+	Clock      Clock                   // defaults to SystemClock
+
+	// Alert is called by Run for each machine found stuck, once until it
+	// moves on and gets stuck again.
+	Alert func(Breach)
+
+	mu      sync.Mutex
+	alerted map[string]time.Time // key -> Since of the stuck machine
+}
+
+// Scan returns the machines that are stuck now, longest stuck first.
+func (d *StuckDetector) Scan() []Breach {
+	clock := d.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+
+	var stuck []Breach
+	d.Registry.Range(func(key string, m Machine) bool {
+		state := m.Subject.CurrentState()
+		if d.Final.Contains(state) {
+			return true
+		}
+		limit, ok := d.Thresholds[state]
+		if !ok {
+			limit = d.Threshold
+		}
+		if limit > 0 && now.Sub(m.Since()) > limit {
+			stuck = append(stuck, Breach{Key: key, State: state, Since: m.Since(), SLA: limit})
+		}
+		return true
+	})
+
+	sortBreaches(stuck)
+	return stuck
+}
+
+// Run scans every interval until ctx is done, calling Alert for machines
+// that have newly got stuck, and returns ctx's error.
+func (d *StuckDetector) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.alert(d.Scan())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *StuckDetector) alert(stuck []Breach) {
+	d.mu.Lock()
+	if d.alerted == nil {
+		d.alerted = map[string]time.Time{}
+	}
+	var fresh []Breach
+	current := make(map[string]time.Time, len(stuck))
+	for _, b := range stuck {
+		current[b.Key] = b.Since
+		if since, ok := d.alerted[b.Key]; !ok || !since.Equal(b.Since) {
+			fresh = append(fresh, b)
+		}
+	}
+	d.alerted = current
+	d.mu.Unlock()
+
+	if d.Alert != nil {
+		for _, b := range fresh {
+			d.Alert(b)
+		}
+	}
+}
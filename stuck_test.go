@@ -0,0 +1,66 @@
+package fsm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestStuckDetector(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		now = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	)
+	clock := fsm.ClockFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	rules := fsm.CreateRuleset(
+		fsm.T{"queued", "processing"},
+		fsm.T{"processing", "done"},
+	)
+	registry := fsm.NewRegistry()
+	for _, key := range []string{"a", "b", "c"} {
+		registry.Put(key, fsm.New(fsm.WithRules(rules), fsm.WithSubject(&Thing{State: "queued"}), fsm.WithClock(clock)))
+	}
+	st.Expect(t, registry.Transition("b", "processing"), nil)
+	st.Expect(t, registry.Transition("c", "processing"), nil)
+	st.Expect(t, registry.Transition("c", "done"), nil)
+
+	var alerts []fsm.Breach
+	detector := &fsm.StuckDetector{
+		Registry:   registry,
+		Threshold:  24 * time.Hour,
+		Thresholds: map[fsm.State]time.Duration{"processing": 6 * time.Hour},
+		Final:      fsm.FromAnyOf("done"),
+		Clock:      clock,
+		Alert:      func(b fsm.Breach) { alerts = append(alerts, b) },
+	}
+
+	advance(7 * time.Hour)
+	stuck := detector.Scan()
+	st.Expect(t, len(stuck), 1)
+	st.Expect(t, stuck[0].Key, "b")
+
+	advance(24 * time.Hour)
+	st.Expect(t, len(detector.Scan()), 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	st.Expect(t, detector.Run(ctx, 5*time.Millisecond), context.DeadlineExceeded)
+
+	st.Expect(t, len(alerts), 2)
+	st.Expect(t, alerts[0].SLA, 24*time.Hour)
+	st.Expect(t, alerts[1].SLA, 6*time.Hour)
+}
@@ -0,0 +1,106 @@
+package fsm
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SyncRuleset wraps a Ruleset so it can be changed while in use. Any number
+// of Permitted calls may run at once; AddRule and AddTransition wait for
+// them to finish and are applied one at a time.
+//
+// Guards run while the read lock is held, so a guard must not modify the
+// SyncRuleset that is evaluating it.
+type SyncRuleset struct {
+	mu    sync.RWMutex
+	rules Ruleset
+}
+
+// NewSyncRuleset creates a SyncRuleset from a copy of r.
+func NewSyncRuleset(r Ruleset) *SyncRuleset {
+	return &SyncRuleset{rules: r.Clone()}
+}
+
+// AddRule adds Guards for the given Transition
+func (s *SyncRuleset) AddRule(t Transition, guards ...Guard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules.AddRule(t, guards...)
+}
+
+// AddTransition adds a transition with a default rule
+func (s *SyncRuleset) AddTransition(t Transition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules.AddTransition(t)
+}
+
+// Permitted determines if a transition is allowed.
+func (s *SyncRuleset) Permitted(subject Stater, goal State) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.rules.Permitted(subject, goal)
+}
+
+// AtomicRuleset holds a Ruleset snapshot that is replaced, never modified.
+// Changes are made to a copy which is then swapped in, so Permitted never
+// blocks and never sees a partially applied update.
+type AtomicRuleset struct {
+	mu       sync.Mutex // serializes writers
+	snapshot atomic.Pointer[Ruleset]
+}
+
+// NewAtomicRuleset creates an AtomicRuleset from a copy of r.
+func NewAtomicRuleset(r Ruleset) *AtomicRuleset {
+	a := &AtomicRuleset{}
+	a.Store(r)
+	return a
+}
+
+// Load returns the current snapshot. It is shared with concurrent readers
+// and must not be modified.
+func (a *AtomicRuleset) Load() Ruleset {
+	if r := a.snapshot.Load(); r != nil {
+		return *r
+	}
+	return nil
+}
+
+// Store replaces the snapshot with a copy of r.
+func (a *AtomicRuleset) Store(r Ruleset) {
+	c := r.Clone()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.snapshot.Store(&c)
+}
+
+// Update applies fn to a copy of the current snapshot and then swaps the
+// copy in. Readers see either none or all of fn's changes.
+func (a *AtomicRuleset) Update(fn func(Ruleset)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c := a.Load().Clone()
+	fn(c)
+	a.snapshot.Store(&c)
+}
+
+// AddRule adds Guards for the given Transition
+func (a *AtomicRuleset) AddRule(t Transition, guards ...Guard) {
+	a.Update(func(r Ruleset) { r.AddRule(t, guards...) })
+}
+
+// AddTransition adds a transition with a default rule
+func (a *AtomicRuleset) AddTransition(t Transition) {
+	a.Update(func(r Ruleset) { r.AddTransition(t) })
+}
+
+// Permitted determines if a transition is allowed.
+func (a *AtomicRuleset) Permitted(subject Stater, goal State) bool {
+	return a.Load().Permitted(subject, goal)
+}
@@ -0,0 +1,59 @@
+package fsm_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestSyncRulesetConcurrentUse(t *testing.T) {
+	rules := fsm.NewSyncRuleset(fsm.CreateRuleset(fsm.T{"pending", "started"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rules.AddTransition(fsm.T{"started", "finished"})
+		}()
+		go func() {
+			defer wg.Done()
+			rules.Permitted(&Thing{State: "started"}, "finished")
+		}()
+	}
+	wg.Wait()
+
+	st.Expect(t, rules.Permitted(&Thing{State: "pending"}, "started"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "finished"), true)
+}
+
+func TestAtomicRulesetUpdate(t *testing.T) {
+	rules := fsm.NewAtomicRuleset(fsm.CreateRuleset(fsm.T{"pending", "started"}))
+	before := rules.Load()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rules.Update(func(r fsm.Ruleset) {
+				r.AddTransition(fsm.T{"started", "finished"})
+				r.AddTransition(fsm.T{"finished", "archived"})
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			// both halves of an update are visible, or neither is
+			r := rules.Load()
+			st.Expect(t,
+				r.Permitted(&Thing{State: "started"}, "finished"),
+				r.Permitted(&Thing{State: "finished"}, "archived"))
+		}()
+	}
+	wg.Wait()
+
+	st.Expect(t, len(before), 1)
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "finished"), true)
+}
@@ -0,0 +1,152 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrUnknownTransition = errors.New("unknown transition")
+
+// Overlay describes how a tenant's ruleset differs from the shared base.
+type Overlay struct {
+	// Add holds transitions, and their guards, that only the tenant has.
+	Add Ruleset
+	// Restrict holds extra guards for transitions of the base or of Add.
+	Restrict Ruleset
+	// Remove lists base transitions the tenant doesn't allow.
+	Remove []Transition
+}
+
+// Tenants resolves per-tenant rulesets from a base Ruleset and the
+// Overlays registered for each tenant. Resolved rulesets are cached, so
+// every machine of a tenant shares one.
+type Tenants struct {
+	base Ruleset
+
+	mu          sync.RWMutex
+	overlays    map[string]Overlay
+	generations map[string]uint64 // of each tenant's overlay
+	resolved    map[string]Ruleset
+}
+
+// NewTenants creates Tenants sharing base, which must not be changed
+// afterwards.
+func NewTenants(base Ruleset) *Tenants {
+	return &Tenants{
+		base:        base,
+		overlays:    map[string]Overlay{},
+		generations: map[string]uint64{},
+		resolved:    map[string]Ruleset{},
+	}
+}
+
+// Overlay registers the overlay for tenant, replacing any earlier one. It
+// is rejected if it restricts or removes a transition that doesn't exist.
+func (t *Tenants) Overlay(tenant string, o Overlay) error {
+	if err := o.validate(t.base); err != nil {
+		return fmt.Errorf("tenant %s: %w", tenant, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.overlays[tenant] = o
+	t.generations[tenant]++
+	delete(t.resolved, tenant)
+
+	return nil
+}
+
+// validate checks that o only restricts or removes transitions that exist,
+// and doesn't restrict one it removes.
+func (o Overlay) validate(base Ruleset) error {
+	var errs []error
+	removed := map[Transition]bool{}
+	for _, tr := range o.Remove {
+		for _, tr := range expand(tr) {
+			if !base.has(tr) {
+				errs = append(errs, fmt.Errorf("%w: removes %s -> %s", ErrUnknownTransition, tr.Origin(), tr.Exit()))
+			}
+			removed[tr] = true
+		}
+	}
+	for tr := range o.Restrict {
+		for _, tr := range expand(tr) {
+			switch {
+			case o.Add.has(tr):
+			case !base.has(tr):
+				errs = append(errs, fmt.Errorf("%w: restricts %s -> %s", ErrUnknownTransition, tr.Origin(), tr.Exit()))
+			case removed[tr]:
+				errs = append(errs, fmt.Errorf("%w: restricts removed %s -> %s", ErrUnknownTransition, tr.Origin(), tr.Exit()))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply returns a copy of base with o applied.
+func (o Overlay) apply(base Ruleset) Ruleset {
+	r := base.Clone()
+	for _, tr := range o.Remove {
+		for _, tr := range expand(tr) {
+			delete(r, tr)
+		}
+	}
+	for tr, guards := range o.Add {
+		r.AddTransition(tr)
+		r.AddRule(tr, guards...)
+	}
+	for tr, guards := range o.Restrict {
+		// only those still there, so a Restrict can't bring back a Remove
+		for _, tr := range expand(tr) {
+			if r.has(tr) {
+				r.AddRule(tr, guards...)
+			}
+		}
+	}
+
+	return r
+}
+
+func (r Ruleset) has(t Transition) bool {
+	_, ok := r[t]
+	return ok
+}
+
+// Rules returns the ruleset of tenant: the base with the tenant's overlay
+// applied. Tenants without an overlay get the base itself.
+func (t *Tenants) Rules(tenant string) Ruleset {
+	t.mu.RLock()
+	r, ok := t.resolved[tenant]
+	o, hasOverlay := t.overlays[tenant]
+	generation := t.generations[tenant]
+	t.mu.RUnlock()
+
+	if ok {
+		return r
+	}
+	if !hasOverlay {
+		return t.base
+	}
+
+	r = o.apply(t.base)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if current, ok := t.resolved[tenant]; ok {
+		return current
+	}
+	// an overlay registered meanwhile must not be shadowed by this one
+	if t.generations[tenant] == generation {
+		t.resolved[tenant] = r
+	}
+
+	return r
+}
+
+// WithTenant is intended to be passed to New to set the Rules to those of
+// tenant.
+func WithTenant(t *Tenants, tenant string) func(*Machine) {
+	return WithRules(t.Rules(tenant))
+}
@@ -0,0 +1,102 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestTenants(t *testing.T) {
+	deny := func(subject fsm.Stater, goal fsm.State) bool { return false }
+
+	tenants := fsm.NewTenants(fsm.CreateRuleset(
+		fsm.T{"pending", "started"},
+		fsm.T{"started", "finished"},
+		fsm.T{"pending", "cancelled"},
+	))
+
+	err := tenants.Overlay("acme", fsm.Overlay{
+		Add:      fsm.CreateRuleset(fsm.T{"started", "paused"}),
+		Restrict: fsm.Ruleset{fsm.T{"started", "finished"}: {deny}},
+		Remove:   []fsm.Transition{fsm.T{"pending", "cancelled"}},
+	})
+	st.Expect(t, err, nil)
+
+	examples := []struct {
+		tenant string
+		origin fsm.State
+		goal   fsm.State
+		ok     bool
+	}{
+		{"acme", "pending", "started", true},
+		{"acme", "started", "paused", true},
+		{"acme", "started", "finished", false},
+		{"acme", "pending", "cancelled", false},
+		{"globex", "started", "paused", false},
+		{"globex", "started", "finished", true},
+		{"globex", "pending", "cancelled", true},
+	}
+
+	for i, ex := range examples {
+		some_thing := &Thing{State: ex.origin}
+		the_machine := fsm.New(fsm.WithTenant(tenants, ex.tenant), fsm.WithSubject(some_thing))
+		st.Expect(t, the_machine.Transition(ex.goal) == nil, ex.ok, i)
+	}
+}
+
+func TestTenantsRejectsUnknownTransitions(t *testing.T) {
+	tenants := fsm.NewTenants(fsm.CreateRuleset(fsm.T{"pending", "started"}))
+
+	err := tenants.Overlay("acme", fsm.Overlay{
+		Restrict: fsm.Ruleset{fsm.T{"started", "finished"}: nil},
+		Remove:   []fsm.Transition{fsm.T{"pending", "cancelled"}},
+	})
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownTransition), true)
+}
+
+func TestTenantsRejectsRestrictingRemoved(t *testing.T) {
+	tenants := fsm.NewTenants(fsm.CreateRuleset(fsm.T{"pending", "started"}, fsm.T{"pending", "cancelled"}))
+
+	err := tenants.Overlay("acme", fsm.Overlay{
+		Restrict: fsm.Ruleset{fsm.T{"pending", "cancelled"}: nil},
+		Remove:   []fsm.Transition{fsm.T{"pending", "cancelled"}},
+	})
+	st.Expect(t, errors.Is(err, fsm.ErrUnknownTransition), true)
+
+	// removed and added back, it may be restricted again
+	st.Expect(t, tenants.Overlay("acme", fsm.Overlay{
+		Add:      fsm.CreateRuleset(fsm.T{"pending", "cancelled"}),
+		Restrict: fsm.Ruleset{fsm.T{"pending", "cancelled"}: nil},
+		Remove:   []fsm.Transition{fsm.T{"pending", "cancelled"}},
+	}), nil)
+	st.Expect(t, tenants.Rules("acme").Permitted(&Thing{State: "pending"}, "cancelled"), true)
+}
+
+func TestTenantsOverlayWhileResolving(t *testing.T) {
+	tenants := fsm.NewTenants(fsm.CreateRuleset(fsm.T{"pending", "started"}))
+	paused := fsm.Overlay{Add: fsm.CreateRuleset(fsm.T{"started", "paused"})}
+	stopped := fsm.Overlay{Add: fsm.CreateRuleset(fsm.T{"started", "stopped"})}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			tenants.Rules("acme")
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		o := paused
+		if i%2 == 1 {
+			o = stopped
+		}
+		st.Expect(t, tenants.Overlay("acme", o), nil)
+	}
+	<-done
+
+	// whatever was resolved meanwhile, the last overlay is the one used
+	rules := tenants.Rules("acme")
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "stopped"), true)
+	st.Expect(t, rules.Permitted(&Thing{State: "started"}, "paused"), false)
+}
@@ -0,0 +1,175 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BatchAppender is implemented by EventStores that can append several
+// events at once, all or none of them. TransitionThrough needs one to be
+// atomic; with any other store a failure part way through leaves the
+// events appended before it behind.
+type BatchAppender interface {
+	AppendBatch(events []TransitionEvent) error
+}
+
+// TransitionThrough moves the subject through each of goals in turn as a
+// single operation, for actions the Rules model as several hops. Every hop
+// must be permitted from the state the previous one reached; if one isn't,
+// the subject is put back where it started and nothing is recorded. Once
+// every hop has been permitted their events are recorded together, and
+// their actions and hooks run in order.
+func (m Machine) TransitionThrough(goals ...State) error {
+	if len(goals) == 0 {
+		return nil
+	}
+	start := m.Subject.CurrentState()
+
+	hops := make([]TransitionContext, 0, len(goals))
+	visited := make([]State, 0, len(goals))
+	for _, goal := range goals {
+		tc := m.context(goal, nil)
+		if err := m.permit(tc); err != nil {
+			m.Subject.SetState(start)
+			m.stats.rejected(err)
+			return fmt.Errorf("%s -> %s: %w", tc.Origin, tc.Goal, err)
+		}
+		tc.Goal = m.recall.resolveAfter(tc.Goal, visited)
+		err := unheld(tc)
+		if err == nil {
+			err = m.reserve(tc)
+		}
+		if err != nil {
+			m.Subject.SetState(start)
+			m.stats.rejected(err)
+			return fmt.Errorf("%s -> %s: %w", tc.Origin, tc.Goal, err)
+		}
+		hops = append(hops, tc)
+		visited = append(visited, tc.Goal)
+		m.Subject.SetState(tc.Goal)
+	}
+	m.Subject.SetState(start)
+
+	now := m.now()
+	events := make([]TransitionEvent, len(hops))
+	for i, tc := range hops {
+		events[i] = m.newEvent(tc, now)
+	}
+	if m.log != nil {
+		var err error
+		if events, err = m.log.recordAll(events, m.chain); err != nil {
+			m.stats.rejected(err)
+			return m.fail(hops[0], start, err)
+		}
+	}
+
+	m.Subject.SetState(hops[len(hops)-1].Goal)
+	for _, event := range events {
+		m.recall.observe(event.Goal)
+		m.history.record(event, m.chain)
+		m.properties.observe(event)
+		m.stats.transitioned(event)
+	}
+	m.approvals.reset()
+	m.timers.reset()
+
+	failing := hops[len(hops)-1]
+	var failed, violated error
+	for _, tc := range hops {
+		if failed = m.act(tc); failed != nil {
+			failing = tc
+			break
+		}
+	}
+	if failed == nil {
+		failed, violated = m.verify(failing)
+	}
+
+	// every hop was made, whether or not its actions succeeded
+	for _, tc := range hops {
+		m.callHooks(tc)
+	}
+	var settled []error
+	for i, event := range events {
+		settled = append(settled, m.settle(hops[i], event))
+	}
+
+	return m.concluded(failing, failed, violated, errors.Join(settled...))
+}
+
+// permit runs the authorizer, rules, approvals and checks for tc.
+func (m Machine) permit(tc TransitionContext) error {
+	if err := stateless(tc); err != nil {
+		return err
+	}
+	auth := AuthRequest{Operation: OpTransition, Actor: tc.Actor, Origin: tc.Origin, Goal: tc.Goal}
+	if err := m.authorizer.authorize(auth); err != nil {
+		return err
+	}
+	if err := m.consult(tc); err != nil {
+		return err
+	}
+	if err := m.approvals.check(tc, m.hierarchy); err != nil {
+		return err
+	}
+	return m.check(tc)
+}
+
+// recordAll numbers and appends events as one batch when the store allows.
+func (l *eventLog) recordAll(events []TransitionEvent, chain *hashChain) ([]TransitionEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch, ok := l.store.(BatchAppender)
+	if !ok {
+		for i, e := range events {
+			e.Seq = l.seq + 1
+			chain.link(&e)
+			if err := l.store.Append(e); err != nil {
+				return events, err
+			}
+			l.seq = e.Seq
+			chain.advance(e)
+			l.tally.add(e)
+			events[i] = e
+		}
+		return events, nil
+	}
+
+	prev := chain.head()
+	for i := range events {
+		events[i].Seq = l.seq + uint64(i) + 1
+		chain.link(&events[i])
+		chain.advance(events[i])
+	}
+	if err := batch.AppendBatch(events); err != nil {
+		chain.restore(prev)
+		return events, err
+	}
+
+	l.seq += uint64(len(events))
+	for _, e := range events {
+		l.tally.add(e)
+	}
+	return events, nil
+}
+
+func (s *MemoryEventStore) AppendBatch(events []TransitionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *MemoryOutboxStore) AppendBatch(events []TransitionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		s.events = append(s.events, e)
+		s.nextID++
+		s.outbox = append(s.outbox, OutboxMessage{ID: s.nextID, Event: e})
+	}
+	return nil
+}
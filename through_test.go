@@ -0,0 +1,56 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestTransitionThrough(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"cart", "ordered"},
+		fsm.T{"ordered", "paid"},
+	)
+	rules.AddRule(fsm.T{"paid", "shipped"}, func(subject fsm.Stater, goal fsm.State) bool { return false })
+
+	var hooked []fsm.State
+	store := &fsm.MemoryEventStore{}
+	some_thing := Thing{State: "cart"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&some_thing),
+		fsm.WithEventStore(store, 0),
+		fsm.WithHashChain(),
+		fsm.WithHistory(),
+		fsm.WithHooks(func(tc fsm.TransitionContext) { hooked = append(hooked, tc.Goal) }),
+	)
+
+	err := the_machine.TransitionThrough("ordered", "paid", "shipped")
+	st.Expect(t, errors.Is(err, fsm.ErrInvalidTransition), true)
+	st.Expect(t, err.Error(), "paid -> shipped: invalid transition")
+	st.Expect(t, some_thing.State, fsm.State("cart"))
+	st.Expect(t, len(hooked), 0)
+	st.Expect(t, len(the_machine.History()), 0)
+
+	st.Expect(t, the_machine.TransitionThrough("ordered", "paid"), nil)
+	st.Expect(t, some_thing.State, fsm.State("paid"))
+	st.Expect(t, hooked, []fsm.State{"ordered", "paid"})
+
+	var events []fsm.TransitionEvent
+	for e := range store.Events(0) {
+		events = append(events, e)
+	}
+	st.Expect(t, len(events), 2)
+	st.Expect(t, events[1].Seq, uint64(2))
+	st.Expect(t, events[1].Prev, events[0].Hash)
+	st.Expect(t, fsm.VerifyChain(func(yield func(fsm.TransitionEvent) bool) {
+		for _, e := range events {
+			if !yield(e) {
+				return
+			}
+		}
+	}), nil)
+	st.Expect(t, the_machine.Stats().Transitions, uint64(2))
+}
@@ -0,0 +1,159 @@
+package fsm
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Ticker is implemented by anything that is advanced by a caller-controlled
+// loop, such as a game or simulation, rather than by the wall clock.
+type Ticker interface {
+	Tick(dt time.Duration) error
+}
+
+// After is intended to be passed to New. Once the machine has spent d in the
+// origin of t, as measured by Tick, it moves to the exit of t if permitted.
+func After(d time.Duration, t Transition) func(*Machine) {
+	return func(m *Machine) {
+		m.ticks().timed = append(m.ticks().timed, timedTransition{d, expand(t)})
+	}
+}
+
+// Always is intended to be passed to New. Whenever the machine is ticked in
+// the origin of t and t is permitted, it moves to the exit of t.
+func Always(t Transition) func(*Machine) {
+	return func(m *Machine) {
+		m.ticks().timed = append(m.ticks().timed, timedTransition{0, expand(t)})
+	}
+}
+
+type timedTransition struct {
+	after time.Duration
+	ts    []Transition
+}
+
+type machineClock struct {
+	timed []timedTransition
+
+	mu      sync.Mutex
+	elapsed time.Duration // time spent in the current state
+}
+
+func (m *Machine) ticks() *machineClock {
+	if m.timers == nil {
+		m.timers = &machineClock{}
+	}
+	return m.timers
+}
+
+// reset starts the clock over; the machine has entered a new state.
+func (c *machineClock) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.elapsed = 0
+	c.mu.Unlock()
+}
+
+// maxTickSteps bounds the transitions a single Tick may take, so Always
+// transitions that form a cycle can't spin forever.
+const maxTickSteps = 64
+
+// Tick advances the machine's clock by dt and takes every After or Always
+// transition that has become due. When an After transition fires, the time
+// left over in dt counts towards the next state, so a single large tick
+// behaves much like many small ones.
+func (m Machine) Tick(dt time.Duration) error {
+	if m.timers == nil {
+		return nil
+	}
+
+	m.timers.mu.Lock()
+	m.timers.elapsed += dt
+	m.timers.mu.Unlock()
+
+	for step := 0; step < maxTickSteps; step++ {
+		origin := m.Subject.CurrentState()
+
+		m.timers.mu.Lock()
+		elapsed := m.timers.elapsed
+		m.timers.mu.Unlock()
+
+		taken, err := m.tickOnce(origin, elapsed)
+		if err != nil || taken < 0 {
+			return err
+		}
+
+		m.timers.mu.Lock()
+		m.timers.elapsed = elapsed - taken
+		m.timers.mu.Unlock()
+	}
+
+	return nil
+}
+
+// tickOnce takes the earliest due transition out of origin. It returns how
+// long the machine had to wait for it, or -1 when nothing was taken.
+func (m Machine) tickOnce(origin State, elapsed time.Duration) (time.Duration, error) {
+	next := time.Duration(-1)
+	var goal State
+
+	for _, tt := range m.timers.timed {
+		if tt.after > elapsed || next >= 0 && tt.after >= next {
+			continue
+		}
+		for _, t := range tt.ts {
+			if t.Origin() == origin && m.rules().Permitted(m.Subject, t.Exit()) {
+				next, goal = tt.after, t.Exit()
+				break
+			}
+		}
+	}
+
+	if next < 0 {
+		return -1, nil
+	}
+
+	tc := m.context(goal, nil)
+	tc.Event = "tick"
+	if err := m.transition(tc); errors.Is(err, ErrInvalidTransition) {
+		return -1, nil
+	} else if err != nil {
+		return -1, err
+	}
+
+	if next == 0 {
+		// Always transitions can't tell when during dt they became possible,
+		// so the new state starts from the end of the tick.
+		return elapsed, nil
+	}
+	return next, nil
+}
+
+// TimeInState returns how long the machine has been in its current state,
+// as measured by Tick.
+func (m Machine) TimeInState() time.Duration {
+	if m.timers == nil {
+		return 0
+	}
+
+	m.timers.mu.Lock()
+	defer m.timers.mu.Unlock()
+
+	return m.timers.elapsed
+}
+
+// Tick advances every machine in the registry by dt, stopping at the first
+// error.
+func (r *Registry) Tick(dt time.Duration) error {
+	var err error
+	r.Range(func(key string, m Machine) bool {
+		err = m.Tick(dt)
+		return err == nil
+	})
+
+	return err
+}
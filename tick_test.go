@@ -0,0 +1,53 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestMachineTick(t *testing.T) {
+	rules := fsm.CreateRuleset(
+		fsm.T{"idle", "patrol"},
+		fsm.T{"patrol", "idle"},
+		fsm.T{"patrol", "chase"},
+		fsm.T{"chase", "patrol"},
+	)
+
+	var spotted bool
+	rules.AddRule(fsm.T{"patrol", "chase"}, func(subject fsm.Stater, goal fsm.State) bool {
+		return spotted
+	})
+
+	npc := Thing{State: "idle"}
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&npc),
+		fsm.After(2*time.Second, fsm.T{"idle", "patrol"}),
+		fsm.After(5*time.Second, fsm.T{"patrol", "idle"}),
+		fsm.After(3*time.Second, fsm.T{"chase", "patrol"}),
+		fsm.Always(fsm.T{"patrol", "chase"}),
+	)
+
+	st.Expect(t, the_machine.Tick(time.Second), nil)
+	st.Expect(t, npc.State, fsm.State("idle"))
+	st.Expect(t, the_machine.TimeInState(), time.Second)
+
+	// the second left over after becoming due counts towards patrolling
+	st.Expect(t, the_machine.Tick(2*time.Second), nil)
+	st.Expect(t, npc.State, fsm.State("patrol"))
+	st.Expect(t, the_machine.TimeInState(), time.Second)
+
+	spotted = true
+	st.Expect(t, the_machine.Tick(time.Second/2), nil)
+	st.Expect(t, npc.State, fsm.State("chase"))
+	st.Expect(t, the_machine.TimeInState(), time.Duration(0))
+
+	// one large tick walks through several timers
+	spotted = false
+	st.Expect(t, the_machine.Tick(9*time.Second), nil)
+	st.Expect(t, npc.State, fsm.State("idle"))
+	st.Expect(t, the_machine.TimeInState(), time.Second)
+}
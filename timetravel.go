@@ -0,0 +1,90 @@
+package fsm
+
+import (
+	"errors"
+	"iter"
+	"time"
+)
+
+var (
+	ErrNoHistory     = errors.New("machine keeps no history")
+	ErrBeforeHistory = errors.New("time precedes the retained history")
+)
+
+// PointInTime is what a machine looked like at some moment.
+type PointInTime struct {
+	State State
+
+	// Entered is the event that moved the subject into State, with its
+	// time, actor and payload. It is zero if the subject was already in
+	// State when its history begins, and only has Seq, Goal and Time when
+	// it has been compacted into a snapshot.
+	Entered TransitionEvent
+}
+
+// StateAt returns the machine's state as of at. It reads the machine's
+// EventStore if it has one, or else its History, and fails with
+// ErrBeforeHistory when at falls before what they still hold.
+func (m Machine) StateAt(at time.Time) (PointInTime, error) {
+	var (
+		events iter.Seq2[TransitionEvent, error]
+		snap   Snapshot
+		err    error
+	)
+	switch {
+	case m.log != nil:
+		if snap, _, err = m.log.store.LatestSnapshot(); err != nil {
+			return PointInTime{}, err
+		}
+		snap = m.schema.MigrateSnapshot(snap)
+		events = m.log.store.Events(0)
+	case m.history != nil:
+		history := m.History()
+		events = func(yield func(TransitionEvent, error) bool) {
+			for _, e := range history {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	default:
+		return PointInTime{}, ErrNoHistory
+	}
+
+	var (
+		p       PointInTime
+		first   *TransitionEvent
+		found   bool
+		current = snap.State // for migrating events from split states
+	)
+	for e, err := range events {
+		if err != nil {
+			return PointInTime{}, err
+		}
+		e = m.schema.MigrateEvent(e, current)
+		current = e.Goal
+		if first == nil {
+			first = &e
+		}
+		if e.Time.After(at) {
+			break
+		}
+		p, found = PointInTime{State: e.Goal, Entered: e}, true
+	}
+
+	switch {
+	case found:
+		return p, nil
+	case snap.Seq > 0 && !snap.Time.After(at):
+		return PointInTime{
+			State:   snap.State,
+			Entered: TransitionEvent{Seq: snap.Seq, Goal: snap.State, Time: snap.Time},
+		}, nil
+	case first != nil && first.Seq == 1:
+		return PointInTime{State: first.Origin}, nil
+	case first == nil && snap.Seq == 0:
+		return PointInTime{State: m.Subject.CurrentState()}, nil
+	default:
+		return PointInTime{}, ErrBeforeHistory
+	}
+}
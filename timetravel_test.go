@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestStateAt(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	rules := fsm.CreateRuleset(
+		fsm.T{"pending", "paid"},
+		fsm.T{"paid", "shipped"},
+		fsm.T{"shipped", "delivered"},
+	)
+
+	for _, keep := range []string{"history", "store"} {
+		now = start
+		opt := fsm.WithHistory()
+		store := &fsm.MemoryEventStore{}
+		if keep == "store" {
+			opt = fsm.WithEventStore(store, 0)
+		}
+		the_machine := fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithClock(fsm.ClockFunc(func() time.Time { return now })),
+			opt,
+		)
+
+		for _, goal := range []fsm.State{"paid", "shipped", "delivered"} {
+			now = now.Add(24 * time.Hour)
+			st.Expect(t, the_machine.TransitionAs("ops", goal, nil), nil)
+		}
+
+		examples := []struct {
+			at    time.Time
+			state fsm.State
+			since time.Time
+		}{
+			{start, "pending", time.Time{}},
+			{start.Add(24 * time.Hour), "paid", start.Add(24 * time.Hour)},
+			{start.Add(60 * time.Hour), "shipped", start.Add(48 * time.Hour)},
+			{start.Add(1000 * time.Hour), "delivered", start.Add(72 * time.Hour)},
+		}
+		for i, ex := range examples {
+			p, err := the_machine.StateAt(ex.at)
+			st.Expect(t, err, nil, i)
+			st.Expect(t, p.State, ex.state, i)
+			st.Expect(t, p.Entered.Time, ex.since, i)
+		}
+
+		if keep == "store" {
+			st.Expect(t, fsm.Compact(store, 2), nil)
+			p, err := the_machine.StateAt(start.Add(60 * time.Hour))
+			st.Expect(t, err, nil)
+			st.Expect(t, p.State, fsm.State("shipped"))
+
+			_, err = the_machine.StateAt(start)
+			st.Expect(t, errors.Is(err, fsm.ErrBeforeHistory), true)
+		}
+	}
+
+	_, err := fsm.New(fsm.WithSubject(&Thing{})).StateAt(start)
+	st.Expect(t, err, fsm.ErrNoHistory)
+}
@@ -0,0 +1,118 @@
+// Package tiny is a state machine for devices too constrained for package
+// fsm, and for TinyGo. It uses no reflection, starts no goroutines and
+// doesn't allocate: states are small integers, and a Table of transitions
+// is compiled into a fixed-size bitset that can be declared as a package
+// variable.
+//
+// It is a separate API, not a build of package fsm: it shares no types
+// with it and has none of its stores, hooks or registries, so code written
+// against one doesn't compile against the other. A program moving a
+// machine from fsm to tiny numbers its states and rewrites its rules as
+// a []Rule.
+//
+//	const (
+//		Idle tiny.State = iota
+//		Heating
+//		Fault
+//	)
+//
+//	var rules = []tiny.Rule{
+//		{From: Idle, To: Heating, Guard: doorClosed},
+//		{From: Heating, To: Idle},
+//		{From: Heating, To: Fault},
+//	}
+//
+//	var table = tiny.MustCompile(rules)
+//	var oven = tiny.Machine{Table: &table, State: Idle}
+package tiny
+
+import "errors"
+
+// MaxStates is the number of states a Table can hold.
+const MaxStates = 64
+
+var (
+	ErrInvalidTransition = errors.New("invalid transition")
+	ErrTooManyStates     = errors.New("state out of range")
+)
+
+// State identifies a state, from 0 to MaxStates-1.
+type State uint8
+
+// Guard decides whether the transition from from to to may happen.
+type Guard func(from, to State) bool
+
+// Rule permits the transition from From to To, when Guard, if any, agrees.
+type Rule struct {
+	From, To State
+	Guard    Guard
+}
+
+// Table is a compiled set of Rules.
+type Table struct {
+	exits [MaxStates]uint64 // bit To of exits[From] is set for every Rule
+	rules []Rule
+}
+
+// Compile returns the Table of rules. The rules are kept, not copied, for
+// their guards.
+func Compile(rules []Rule) (Table, error) {
+	t := Table{rules: rules}
+	for _, r := range rules {
+		if r.From >= MaxStates || r.To >= MaxStates {
+			return Table{}, ErrTooManyStates
+		}
+		t.exits[r.From] |= 1 << r.To
+	}
+
+	return t, nil
+}
+
+// MustCompile is like Compile but panics on error. It is intended for
+// package-level variables.
+func MustCompile(rules []Rule) Table {
+	t, err := Compile(rules)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// Permitted determines if the transition from from to to is allowed. A
+// transition with several Rules is allowed when all their guards agree.
+func (t *Table) Permitted(from, to State) bool {
+	if from >= MaxStates || to >= MaxStates || t.exits[from]&(1<<to) == 0 {
+		return false
+	}
+
+	for _, r := range t.rules {
+		if r.From == from && r.To == to && r.Guard != nil && !r.Guard(from, to) {
+			return false
+		}
+	}
+	return true
+}
+
+// Machine moves State around a Table.
+type Machine struct {
+	Table *Table
+	State State
+
+	// OnTransition, if set, is called after each transition.
+	OnTransition func(from, to State)
+}
+
+// Transition attempts to move the machine to the goal state.
+func (m *Machine) Transition(goal State) error {
+	if !m.Table.Permitted(m.State, goal) {
+		return ErrInvalidTransition
+	}
+
+	from := m.State
+	m.State = goal
+	if m.OnTransition != nil {
+		m.OnTransition(from, goal)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package tiny_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3/tiny"
+)
+
+const (
+	Idle tiny.State = iota
+	Heating
+	Fault
+)
+
+var doorClosed = true
+
+var table = tiny.MustCompile([]tiny.Rule{
+	{From: Idle, To: Heating, Guard: func(from, to tiny.State) bool { return doorClosed }},
+	{From: Heating, To: Idle},
+	{From: Heating, To: Fault},
+})
+
+func TestMachineTransition(t *testing.T) {
+	var moves []tiny.State
+	oven := tiny.Machine{Table: &table, State: Idle, OnTransition: func(from, to tiny.State) {
+		moves = append(moves, from, to)
+	}}
+
+	doorClosed = false
+	st.Expect(t, oven.Transition(Heating), tiny.ErrInvalidTransition)
+	doorClosed = true
+	st.Expect(t, oven.Transition(Heating), nil)
+	st.Expect(t, oven.Transition(Fault), nil)
+	st.Expect(t, oven.Transition(Idle), tiny.ErrInvalidTransition)
+	st.Expect(t, oven.Transition(tiny.MaxStates), tiny.ErrInvalidTransition)
+	st.Expect(t, oven.State, Fault)
+	st.Expect(t, moves, []tiny.State{Idle, Heating, Heating, Fault})
+
+	_, err := tiny.Compile([]tiny.Rule{{From: Idle, To: tiny.MaxStates}})
+	st.Expect(t, err, tiny.ErrTooManyStates)
+}
+
+func TestMachineDoesNotAllocate(t *testing.T) {
+	oven := tiny.Machine{Table: &table, State: Idle}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		oven.Transition(Heating)
+		oven.Transition(Idle)
+		oven.Transition(Fault)
+	})
+	st.Expect(t, allocs, float64(0))
+}
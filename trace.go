@@ -0,0 +1,171 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Tracer records the timing of transitions, their phases and each of their
+// guards, those of a Ruleset or CompiledRuleset and those passed to
+// WithGuardsCtx, for viewing on a timeline. WriteChromeTrace exports
+// what it has recorded in the Chrome trace event format, which
+// chrome://tracing, Perfetto and Speedscope all open.
+type Tracer struct {
+	mu    sync.Mutex
+	epoch time.Time
+	lanes map[string]int
+	spans []traceSpan // a ring once Limit is reached
+	next  int         // where the ring's next span goes
+	Limit int         // most recent spans to keep; 0 keeps them all
+}
+
+type traceSpan struct {
+	lane       int
+	name, cat  string
+	start, dur time.Duration // since epoch
+	args       map[string]string
+}
+
+// NewTracer returns an empty Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{lanes: map[string]int{}}
+}
+
+type machineTracer struct {
+	tracer *Tracer
+	lane   string
+}
+
+// WithTracer is intended to be passed to New to record the machine's
+// transitions in t. Each machine gets its own lane, named lane, on the
+// timeline; machines sharing a lane are drawn on the same track.
+func WithTracer(t *Tracer, lane string) func(*Machine) {
+	return func(m *Machine) {
+		m.tracer = &machineTracer{t, lane}
+	}
+}
+
+// phase runs one phase of a transition, returning how long it took.
+func (m Machine) phase(tc TransitionContext, phase string, fn func()) time.Duration {
+	start := time.Now()
+	m.profile.do(tc, phase, fn)
+	d := time.Since(start)
+
+	m.tracer.record(phase, "phase", start, d, nil)
+	return d
+}
+
+// guard records the index-th guard of tc, which refused it unless err is
+// nil.
+func (t *machineTracer) guard(tc TransitionContext, index int, start time.Time, err error) {
+	if t == nil {
+		return
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = err.Error()
+	}
+	args := map[string]string{
+		"origin":  string(tc.Origin),
+		"goal":    string(tc.Goal),
+		"outcome": outcome,
+	}
+	t.record(fmt.Sprintf("guard %d", index+1), "guard", start, time.Since(start), args)
+}
+
+// transition records a whole transition, which ended with err.
+func (t *machineTracer) transition(tc TransitionContext, start time.Time, err *error) {
+	if t == nil {
+		return
+	}
+	outcome := "ok"
+	if *err != nil {
+		outcome = (*err).Error()
+	}
+	args := map[string]string{
+		"origin":  string(tc.Origin),
+		"goal":    string(tc.Goal),
+		"outcome": outcome,
+	}
+	for name, value := range tc.Labels {
+		args["label."+name] = value
+	}
+	t.record(string(tc.Origin)+" -> "+string(tc.Goal), "transition", start, time.Since(start), args)
+}
+
+func (t *machineTracer) record(name, cat string, start time.Time, d time.Duration, args map[string]string) {
+	if t == nil {
+		return
+	}
+	tr := t.tracer
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if tr.epoch.IsZero() {
+		tr.epoch = start
+	}
+	lane, ok := tr.lanes[t.lane]
+	if !ok {
+		lane = len(tr.lanes) + 1
+		tr.lanes[t.lane] = lane
+	}
+
+	span := traceSpan{lane, name, cat, start.Sub(tr.epoch), d, args}
+	switch {
+	case tr.Limit <= 0 || len(tr.spans) < tr.Limit:
+		tr.spans = append(tr.spans, span)
+	case len(tr.spans) > tr.Limit:
+		// Limit was lowered
+		spans := tr.ordered()
+		tr.spans, tr.next = append(spans[len(spans)-tr.Limit+1:], span), 0
+	default:
+		tr.spans[tr.next] = span
+		tr.next = (tr.next + 1) % tr.Limit
+	}
+}
+
+// ordered returns the spans kept, oldest first. t must be locked.
+func (t *Tracer) ordered() []traceSpan {
+	return append(append([]traceSpan(nil), t.spans[t.next:]...), t.spans[:t.next]...)
+}
+
+type chromeEvent struct {
+	Name string            `json:"name"`
+	Cat  string            `json:"cat,omitempty"`
+	Ph   string            `json:"ph"`
+	Ts   float64           `json:"ts"` // microseconds
+	Dur  float64           `json:"dur,omitempty"`
+	Pid  int               `json:"pid"`
+	Tid  int               `json:"tid"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the recorded spans to w as a Chrome trace event
+// JSON object.
+func (t *Tracer) WriteChromeTrace(w io.Writer) error {
+	t.mu.Lock()
+	events := make([]chromeEvent, 0, len(t.lanes)+len(t.spans))
+	for name, lane := range t.lanes {
+		events = append(events, chromeEvent{
+			Name: "thread_name", Ph: "M", Pid: 1, Tid: lane,
+			Args: map[string]string{"name": name},
+		})
+	}
+	for _, s := range t.ordered() {
+		events = append(events, chromeEvent{
+			Name: s.name, Cat: s.cat, Ph: "X", Pid: 1, Tid: s.lane,
+			Ts:   float64(s.start) / float64(time.Microsecond),
+			Dur:  float64(s.dur) / float64(time.Microsecond),
+			Args: s.args,
+		})
+	}
+	t.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []chromeEvent `json:"traceEvents"`
+		DisplayTimeUnit string        `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
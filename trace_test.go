@@ -0,0 +1,127 @@
+package fsm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := fsm.NewTracer()
+	rules := fsm.CreateRuleset(fsm.T{"pending", "started"})
+
+	for _, lane := range []string{"order-1", "order-2"} {
+		the_machine := fsm.New(
+			fsm.WithRules(rules),
+			fsm.WithSubject(&Thing{State: "pending"}),
+			fsm.WithTracer(tracer, lane),
+		)
+		st.Expect(t, the_machine.Transition("started"), nil)
+		st.Expect(t, the_machine.Transition("finished"), fsm.ErrInvalidTransition)
+	}
+
+	var out bytes.Buffer
+	st.Expect(t, tracer.WriteChromeTrace(&out), nil)
+
+	var trace struct {
+		TraceEvents []struct {
+			Name string            `json:"name"`
+			Cat  string            `json:"cat"`
+			Ph   string            `json:"ph"`
+			Tid  int               `json:"tid"`
+			Args map[string]string `json:"args"`
+		} `json:"traceEvents"`
+	}
+	st.Expect(t, json.Unmarshal(out.Bytes(), &trace), nil)
+
+	lanes := map[string]int{}
+	outcomes := map[string]int{}
+	phases := map[string]int{}
+	for _, e := range trace.TraceEvents {
+		switch {
+		case e.Ph == "M":
+			lanes[e.Args["name"]] = e.Tid
+		case e.Cat == "transition":
+			outcomes[e.Args["outcome"]]++
+		case e.Cat == "phase":
+			phases[e.Name]++
+		}
+	}
+	st.Expect(t, len(lanes), 2)
+	st.Expect(t, outcomes, map[string]int{"ok": 2, "invalid transition": 2})
+	st.Expect(t, phases, map[string]int{"guards": 4, "actions": 2, "hooks": 2})
+}
+
+// chromeTrace decodes the events written by tracer.
+func chromeTrace(t *testing.T, tracer *fsm.Tracer) []map[string]interface{} {
+	var out bytes.Buffer
+	st.Expect(t, tracer.WriteChromeTrace(&out), nil)
+	var trace struct {
+		TraceEvents []map[string]interface{} `json:"traceEvents"`
+	}
+	st.Expect(t, json.Unmarshal(out.Bytes(), &trace), nil)
+	return trace.TraceEvents
+}
+
+func TestTracerGuards(t *testing.T) {
+	tracer := fsm.NewTracer()
+	pass := func(fsm.Stater, fsm.State) bool { return true }
+	fail := func(fsm.Stater, fsm.State) bool { return false }
+	rules := fsm.Ruleset{}
+	rules.AddRule(fsm.T{O: "pending", E: "started"}, pass, pass)
+	rules.AddRule(fsm.T{O: "pending", E: "cancelled"}, pass, fail, pass)
+
+	the_machine := fsm.New(
+		fsm.WithRules(rules),
+		fsm.WithSubject(&Thing{State: "pending"}),
+		fsm.WithTracer(tracer, "order-1"),
+		fsm.WithRejectionErrors(),
+		fsm.WithGuardsCtx(fsm.T{O: "pending", E: "started"}, fsm.GuardWithCtx(pass)),
+	)
+	var guardErr *fsm.GuardError
+	st.Expect(t, errors.As(the_machine.Transition("cancelled"), &guardErr), true)
+	st.Expect(t, guardErr.Index, 1)
+	st.Expect(t, the_machine.Transition("started"), nil)
+
+	var guards []string
+	for _, e := range chromeTrace(t, tracer) {
+		if e["cat"] == "guard" {
+			args := e["args"].(map[string]interface{})
+			guards = append(guards, fmt.Sprint(args["goal"], " ", e["name"], " ", args["outcome"] == "ok"))
+		}
+	}
+	st.Expect(t, guards, []string{
+		"cancelled guard 1 true",
+		"cancelled guard 2 false",
+		"started guard 1 true",
+		"started guard 2 true",
+		"started guard 1 true", // the GuardCtx
+	})
+}
+
+func TestTracerLimit(t *testing.T) {
+	tracer := fsm.NewTracer()
+	tracer.Limit = 4
+	the_machine := fsm.New(
+		fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "a", E: "b"}, fsm.T{O: "b", E: "a"})),
+		fsm.WithSubject(&Thing{State: "a"}),
+		fsm.WithTracer(tracer, "order-1"),
+	)
+	for i := 0; i < 5; i++ {
+		st.Expect(t, the_machine.Transition([]fsm.State{"b", "a"}[i%2]), nil, i)
+	}
+
+	// the last transition's guards, actions, hooks and the transition
+	var names []string
+	for _, e := range chromeTrace(t, tracer) {
+		if e["ph"] == "X" {
+			names = append(names, e["name"].(string))
+		}
+	}
+	st.Expect(t, names, []string{"guards", "actions", "hooks", "a -> b"})
+}
@@ -0,0 +1,89 @@
+// Package fsm provides the API of version 1 of github.com/ryanfaerman/fsm
+// on top of the current one, so that code written against it can move to
+// the current module by changing its import path alone, and then upgrade
+// one machine at a time:
+//
+//	import fsm "github.com/ryanfaerman/fsm/v3/v1"
+//
+// States, transitions, guards and rulesets are those of the current
+// package, so they can be shared between old and new code. Only Machine
+// differs: it has just the Rules and Subject it had in version 1, and
+// Upgrade turns it into a current one.
+package fsm
+
+import "github.com/ryanfaerman/fsm/v3"
+
+type (
+	State      = fsm.State
+	Guard      = fsm.Guard
+	Transition = fsm.Transition
+	T          = fsm.T
+	Ruleset    = fsm.Ruleset
+	Stater     = fsm.Stater
+)
+
+var (
+	ErrInvalidTransition = fsm.ErrInvalidTransition
+
+	// InvalidTransition is the name version 1 gave ErrInvalidTransition.
+	InvalidTransition = fsm.ErrInvalidTransition
+)
+
+// CreateRuleset will establish a ruleset with the provided transitions.
+// This eases initialization when storing within another structure.
+func CreateRuleset(transitions ...Transition) Ruleset {
+	return fsm.CreateRuleset(transitions...)
+}
+
+// Machine is a pairing of Rules and a Subject.
+// The subject or rules may be changed at any time within
+// the machine's lifecycle.
+type Machine struct {
+	Rules   *Ruleset
+	Subject Stater
+}
+
+// Transition attempts to move the Subject to the Goal state.
+func (m Machine) Transition(goal State) error {
+	if m.Rules == nil {
+		return fsm.ErrNoRules
+	}
+
+	return fsm.Machine{Rules: m.Rules, Subject: m.Subject}.Transition(goal)
+}
+
+// Upgrade returns a machine of the current package with the same rules and
+// subject, configured further by opts.
+func (m Machine) Upgrade(opts ...func(*fsm.Machine)) fsm.Machine {
+	var rules Ruleset
+	if m.Rules != nil {
+		rules = *m.Rules
+	}
+
+	return fsm.New(append([]func(*fsm.Machine){fsm.WithRules(rules), fsm.WithSubject(m.Subject)}, opts...)...)
+}
+
+// New initializes a machine
+func New(opts ...func(*Machine)) Machine {
+	var m Machine
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return m
+}
+
+// WithSubject is intended to be passed to New to set the Subject
+func WithSubject(s Stater) func(*Machine) {
+	return func(m *Machine) {
+		m.Subject = s
+	}
+}
+
+// WithRules is intended to be passed to New to set the Rules
+func WithRules(r Ruleset) func(*Machine) {
+	return func(m *Machine) {
+		m.Rules = &r
+	}
+}
@@ -0,0 +1,57 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	fsm "github.com/ryanfaerman/fsm/v3/v1"
+)
+
+type Thing struct {
+	State fsm.State
+
+	machine *fsm.Machine
+}
+
+func (t *Thing) CurrentState() fsm.State { return t.State }
+func (t *Thing) SetState(s fsm.State)    { t.State = s }
+
+func (t *Thing) Apply(r *fsm.Ruleset) *fsm.Machine {
+	if t.machine == nil {
+		t.machine = &fsm.Machine{Subject: t}
+	}
+
+	t.machine.Rules = r
+	return t.machine
+}
+
+func TestMachineTransition(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+
+	rules := fsm.Ruleset{}
+	rules.AddTransition(fsm.T{O: "pending", E: "started"})
+	rules.AddRule(fsm.T{O: "started", E: "finished"}, func(subject fsm.Stater, goal fsm.State) bool {
+		return false
+	})
+
+	st.Expect(t, some_thing.Apply(&rules).Transition("started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	err := some_thing.Apply(&rules).Transition("finished")
+	st.Expect(t, err, fsm.InvalidTransition)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+
+	// The rules are read through the pointer, so they can be changed later.
+	rules.AddTransition(fsm.T{O: "started", E: "pending"})
+	st.Expect(t, some_thing.machine.Transition("pending"), nil)
+}
+
+func TestMachineUpgrade(t *testing.T) {
+	some_thing := Thing{State: "pending"}
+	old := fsm.New(fsm.WithSubject(&some_thing), fsm.WithRules(fsm.CreateRuleset(fsm.T{O: "pending", E: "started"})))
+
+	the_machine := old.Upgrade()
+	st.Expect(t, the_machine.Transition("started"), nil)
+	st.Expect(t, some_thing.State, fsm.State("started"))
+	st.Expect(t, the_machine.Stats().Transitions, uint64(1))
+}
@@ -0,0 +1,29 @@
+// Types for fsm.js. Definitions are the JSON documented by fsm.Definition.
+
+export type State = string;
+
+/** Decides whether a subject in state may move to goal. */
+export type Guard = (state: State, goal: State) => boolean;
+
+export interface Rules {
+  /** Reports whether a subject in state may move to goal. */
+  permitted(state: State, goal: State): boolean;
+  /** Returns the goal of every transition out of state, sorted. */
+  exits(state: State): State[];
+  /** Returns every state the ruleset mentions, sorted. */
+  states(): State[];
+}
+
+export interface FSM {
+  /** Registers guard under name for rulesets loaded after it. */
+  registerGuard(name: string, guard: Guard): void;
+  /**
+   * Builds the ruleset of a JSON definition, substituting params, and
+   * throws an Error listing every problem with it.
+   */
+  load(definition: string, params?: Record<string, string>): Rules;
+}
+
+export function init(
+  source: Response | Promise<Response> | BufferSource,
+): Promise<FSM>;
@@ -0,0 +1,37 @@
+// Thin bindings for fsm.wasm. Go's wasm_exec.js, from
+// $(go env GOROOT)/lib/wasm, must have been loaded first so that the Go
+// class is defined.
+//
+//	import { init } from "./fsm.js";
+//
+//	const fsm = await init(fetch("fsm.wasm"));
+//	fsm.registerGuard("role:manager", (state, goal) => user.isManager);
+//	const rules = fsm.load(definition, { approver: "manager" });
+//	rules.permitted("pending", "approved");
+
+/**
+ * Starts fsm.wasm and resolves to its API once it is ready.
+ *
+ * @param {Response | Promise<Response> | BufferSource} source
+ */
+export async function init(source) {
+  const go = new Go();
+  const { instance } =
+    source instanceof ArrayBuffer || ArrayBuffer.isView(source)
+      ? await WebAssembly.instantiate(source, go.importObject)
+      : await WebAssembly.instantiateStreaming(source, go.importObject);
+
+  go.run(instance);
+  const { registerGuard, load } = globalThis.fsm;
+
+  return {
+    registerGuard,
+    load(definition, params) {
+      const rules = load(definition, params);
+      if (rules.error !== undefined) {
+        throw new Error(rules.error);
+      }
+      return rules;
+    },
+  };
+}
@@ -0,0 +1,88 @@
+//go:build js && wasm
+
+// Command wasm exposes rulesets to JavaScript, so that a browser can check
+// transitions against the very Definition, and the same guard names, as the
+// backend that enforces them. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o fsm.wasm ./wasm
+//
+// and load it with fsm.js, next to Go's wasm_exec.js.
+package main
+
+import (
+	"strings"
+	"syscall/js"
+
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+// guards are the JavaScript guards registered so far, by name.
+var guards = map[string]js.Value{}
+
+func main() {
+	js.Global().Set("fsm", js.ValueOf(map[string]any{
+		"registerGuard": js.FuncOf(registerGuard),
+		"load":          js.FuncOf(load),
+	}))
+
+	select {}
+}
+
+// registerGuard(name, fn) registers fn(state, goal) under name. It is
+// consulted by rulesets loaded after it.
+func registerGuard(this js.Value, args []js.Value) any {
+	guards[args[0].String()] = args[1]
+	return nil
+}
+
+// load(definition, params) builds the ruleset of the JSON definition and
+// returns an object to query it, or {error} describing everything that is
+// wrong with it. Go can't throw, so fsm.js does.
+func load(this js.Value, args []js.Value) any {
+	params := map[string]string{}
+	if len(args) > 1 && args[1].Truthy() {
+		keys := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < keys.Length(); i++ {
+			k := keys.Index(i).String()
+			params[k] = args[1].Get(k).String()
+		}
+	}
+
+	b := fsm.NewBuilder()
+	for name, fn := range guards {
+		b.Guard(name, func(subject fsm.Stater, goal fsm.State) bool {
+			return fn.Invoke(string(subject.CurrentState()), string(goal)).Truthy()
+		})
+	}
+	rules, err := b.LoadJSONWithParams(strings.NewReader(args[0].String()), params).Build()
+	if err != nil {
+		return js.ValueOf(map[string]any{"error": err.Error()})
+	}
+
+	return js.ValueOf(map[string]any{
+		"permitted": js.FuncOf(func(this js.Value, args []js.Value) any {
+			subject := &state{fsm.State(args[0].String())}
+			return rules.Permitted(subject, fsm.State(args[1].String()))
+		}),
+		"exits": js.FuncOf(func(this js.Value, args []js.Value) any {
+			return stateNames(rules.ExitsFrom(fsm.State(args[0].String())))
+		}),
+		"states": js.FuncOf(func(this js.Value, args []js.Value) any {
+			return stateNames(rules.States())
+		}),
+	})
+}
+
+// state is the subject of a check: nothing but the state it is in.
+type state struct{ s fsm.State }
+
+func (s *state) CurrentState() fsm.State { return s.s }
+func (s *state) SetState(g fsm.State)    { s.s = g }
+
+func stateNames(states []fsm.State) []any {
+	s := make([]any, len(states))
+	for i, state := range states {
+		s[i] = string(state)
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package fsm
+
+import "time"
+
+// Window is a recurring weekly span of time, such as a maintenance window
+// from 02:00 to 06:00 on Saturdays and Sundays in Europe/Berlin.
+type Window struct {
+	// Days the window opens on; every day when empty.
+	Days []time.Weekday
+
+	// Start and End are offsets from midnight. When End isn't after Start
+	// the window runs past midnight and closes on the following day.
+	Start, End time.Duration
+
+	// Location the window is defined in; UTC when nil.
+	Location *time.Location
+}
+
+// Contains reports whether t falls within the window.
+func (w Window) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	// wall clock time, so windows keep their hours across DST changes
+	offset := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+
+	if w.End > w.Start {
+		return w.opensOn(t.Weekday()) && offset >= w.Start && offset < w.End
+	}
+
+	yesterday := (t.Weekday() + 6) % 7
+	return w.opensOn(t.Weekday()) && offset >= w.Start ||
+		w.opensOn(yesterday) && offset < w.End
+}
+
+func (w Window) opensOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// WithinWindows creates a Guard that only permits its transition while the
+// time told by c falls within one of windows.
+func WithinWindows(c Clock, windows ...Window) Guard {
+	return func(subject Stater, goal State) bool {
+		now := c.Now()
+		for _, w := range windows {
+			if w.Contains(now) {
+				return true
+			}
+		}
+		return false
+	}
+}
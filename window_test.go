@@ -0,0 +1,57 @@
+package fsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"github.com/ryanfaerman/fsm/v3"
+)
+
+func TestWindowContains(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+
+	// Fridays 22:00 until 04:00 the next morning, Berlin time
+	window := fsm.Window{
+		Days:     []time.Weekday{time.Friday},
+		Start:    22 * time.Hour,
+		End:      4 * time.Hour,
+		Location: berlin,
+	}
+
+	examples := []struct {
+		at      time.Time
+		outcome bool
+	}{
+		{time.Date(2024, 3, 1, 21, 59, 0, 0, berlin), false}, // Friday
+		{time.Date(2024, 3, 1, 22, 0, 0, 0, berlin), true},
+		{time.Date(2024, 3, 2, 3, 59, 0, 0, berlin), true}, // Saturday
+		{time.Date(2024, 3, 2, 4, 0, 0, 0, berlin), false},
+		{time.Date(2024, 3, 1, 21, 30, 0, 0, time.UTC), true}, // 22:30 in Berlin
+		{time.Date(2024, 3, 7, 23, 0, 0, 0, berlin), false},   // Thursday
+	}
+
+	for i, ex := range examples {
+		st.Expect(t, window.Contains(ex.at), ex.outcome, i)
+	}
+}
+
+func TestWithinWindows(t *testing.T) {
+	now := time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC) // Saturday
+	clock := fsm.ClockFunc(func() time.Time { return now })
+
+	rules := fsm.CreateRuleset(fsm.T{"running", "destroyed"})
+	rules.AddRule(fsm.T{"running", "destroyed"}, fsm.WithinWindows(clock, fsm.Window{
+		Days:  []time.Weekday{time.Saturday, time.Sunday},
+		Start: 2 * time.Hour,
+		End:   6 * time.Hour,
+	}))
+
+	st.Expect(t, rules.Permitted(&Thing{State: "running"}, "destroyed"), true)
+
+	now = now.Add(4 * time.Hour)
+	st.Expect(t, rules.Permitted(&Thing{State: "running"}, "destroyed"), false)
+}